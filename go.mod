@@ -5,11 +5,11 @@ go 1.18
 require (
 	github.com/stretchr/testify v1.7.1
 	github.com/xingshuo/console v0.0.0-20190501085718-a1c5edeb5c47
+	golang.org/x/exp v0.0.0-20220428152302-39d4317da171
 )
 
 require (
 	github.com/davecgh/go-spew v1.1.0 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
-	golang.org/x/exp v0.0.0-20220428152302-39d4317da171 // indirect
 	gopkg.in/yaml.v3 v3.0.0-20200313102051-9f266ea9e77c // indirect
 )
@@ -1,79 +1,270 @@
 package main
 
 import (
+	"bytes"
+	"errors"
 	"flag"
 	"fmt"
 	"io/ioutil"
+	"monkey/my_compiler"
 	"monkey/my_engine"
+	"monkey/my_module"
+	"monkey/my_object"
+	"monkey/my_parser"
 	repl "monkey/my_repl"
 	"os"
 	"os/user"
+	"path/filepath"
+	"strings"
 )
 
-var (
-	engineFlag = flag.String(
-		"engine",
-		"vm",
-		"engine to execute code; possible options: vm, eval; default to vm",
-	)
-	vmEngine   = my_engine.NewVMEngine()
-	evalEngine = my_engine.NewEvalEngine()
+// exit codes telling which stage fails
+const (
+	exitOK           = 0
+	exitFailure      = 1 // bad usage or io errors
+	exitParseError   = 2
+	exitCompileError = 3
+	exitRuntimeError = 4
 )
 
+const usage = `Usage:
+  monkey [-engine vm|eval]                      start an interactive repl
+  monkey [-engine vm|eval] <file>               run a .monkey or .mkc file, .mkc files need -engine vm
+  monkey build [-o <output>] <file>             compile a .monkey file into a .mkc bytecode file
+  monkey run [-engine vm|eval] <file>           run a .monkey or .mkc file, detected by its header;
+                                                .mkc files need -engine vm
+  monkey eval [-engine vm|eval] -e '<code>'     run code given on the command line
+  monkey disasm <file>                          disassemble a .monkey or .mkc file
+
+Imported modules are looked up in the directory of the file, or the working directory for code
+not in a file, then in directories given by -path, which every command accepts.
+`
+
+var engineFlag = flag.String(
+	"engine",
+	"vm",
+	"engine to execute code; possible options: vm, eval; default to vm",
+)
+
+var pathFlag = flag.String(
+	"path",
+	"",
+	pathFlagUsage,
+)
+
+const pathFlagUsage = "directories to look imported modules up in, separated by " + string(os.PathListSeparator)
+
 func main() {
+	flag.Usage = func() { fmt.Fprint(os.Stderr, usage) }
 	flag.Parse()
 
 	args := flag.Args()
-	switch len(args) {
-	case 0:
-		// interactive repl
-		user, err := user.Current()
-		if err != nil {
-			panic(err)
-		}
-		fmt.Printf("Hello %s! This is the Monkey programming language!\n",
-			user.Username)
-		fmt.Printf("Feel free to type in commands\n")
-		switch *engineFlag {
-		case "eval":
-			repl.Start(os.Stdout, evalEngine)
-		case "vm":
-			fallthrough
-		default:
-			repl.Start(os.Stdout, vmEngine)
+	if len(args) == 0 {
+		startRepl(*engineFlag)
+		return
+	}
+	switch args[0] {
+	case "build":
+		os.Exit(buildCmd(args[1:]))
+	case "run":
+		os.Exit(runCmd(args[1:]))
+	case "eval":
+		os.Exit(evalCmd(args[1:]))
+	case "disasm":
+		os.Exit(disasmCmd(args[1:]))
+	default:
+		// monkey <file> is short for monkey run <file>
+		if len(args) > 1 {
+			fmt.Fprintf(os.Stderr, "Sorry, Monkey runs one file at a time, import other files as modules instead\n")
+			os.Exit(exitFailure)
 		}
-	case 1:
-		// output file result
-		code, err := ioutil.ReadFile(args[0])
+		os.Exit(runFile(*engineFlag, args[0]))
+	}
+}
+
+func startRepl(engineName string) {
+	user, err := user.Current()
+	if err != nil {
+		panic(err)
+	}
+	fmt.Printf("Hello %s! This is the Monkey programming language!\n",
+		user.Username)
+	fmt.Printf("Feel free to type in commands\n")
+	repl.Start(os.Stdout, newEngine(engineName, newLoader(".")))
+}
+
+func buildCmd(args []string) int {
+	fs := flag.NewFlagSet("build", flag.ContinueOnError)
+	output := fs.String("o", "", "output file; default to the input file with "+my_compiler.FileExtension+" extension")
+	fs.StringVar(pathFlag, "path", *pathFlag, pathFlagUsage)
+	if err := fs.Parse(args); err != nil || fs.NArg() != 1 {
+		fmt.Fprint(os.Stderr, usage)
+		return exitFailure
+	}
+	input := fs.Arg(0)
+	code, err := ioutil.ReadFile(input)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Sorry, Monkey doesn't know how to compile %s: %s\n", input, err)
+		return exitFailure
+	}
+	byteCode, err := my_engine.CompileWithLoader(string(code), newLoader(filepath.Dir(input)))
+	if err != nil {
+		repl.PrintErrors(os.Stderr, string(code), err)
+		return exitCode(err)
+	}
+	if *output == "" {
+		*output = strings.TrimSuffix(input, filepath.Ext(input)) + my_compiler.FileExtension
+	}
+	if err := writeByteCode(*output, byteCode); err != nil {
+		fmt.Fprintf(os.Stderr, "Sorry, Monkey cannot write to %s: %s\n", *output, err)
+		return exitFailure
+	}
+	return exitOK
+}
+
+// writeByteCode: encode byteCode into a temporary file next to output, renamed to output once complete,
+// so that a failed build leaves no truncated file behind
+func writeByteCode(output string, byteCode *my_compiler.ByteCode) error {
+	f, err := os.CreateTemp(filepath.Dir(output), filepath.Base(output)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	// temporary files are private, bytecode files are readable like those os.Create makes
+	err = f.Chmod(0o644)
+	if err == nil {
+		err = byteCode.Encode(f)
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), output)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
+
+func runCmd(args []string) int {
+	fs := flag.NewFlagSet("run", flag.ContinueOnError)
+	engineName := fs.String("engine", *engineFlag, "engine to execute source code; possible options: vm, eval")
+	fs.StringVar(pathFlag, "path", *pathFlag, pathFlagUsage)
+	if err := fs.Parse(args); err != nil || fs.NArg() != 1 {
+		fmt.Fprint(os.Stderr, usage)
+		return exitFailure
+	}
+	return runFile(*engineName, fs.Arg(0))
+}
+
+func evalCmd(args []string) int {
+	fs := flag.NewFlagSet("eval", flag.ContinueOnError)
+	engineName := fs.String("engine", *engineFlag, "engine to execute code; possible options: vm, eval")
+	code := fs.String("e", "", "code to run")
+	fs.StringVar(pathFlag, "path", *pathFlag, pathFlagUsage)
+	if err := fs.Parse(args); err != nil || fs.NArg() != 0 || *code == "" {
+		fmt.Fprint(os.Stderr, usage)
+		return exitFailure
+	}
+	res, err := newEngine(*engineName, newLoader(".")).Evaluate(*code)
+	return printResult(*code, res, err)
+}
+
+func disasmCmd(args []string) int {
+	fs := flag.NewFlagSet("disasm", flag.ContinueOnError)
+	fs.StringVar(pathFlag, "path", *pathFlag, pathFlagUsage)
+	if err := fs.Parse(args); err != nil || fs.NArg() != 1 {
+		fmt.Fprint(os.Stderr, usage)
+		return exitFailure
+	}
+	path := fs.Arg(0)
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Sorry, Monkey cannot read %s: %s\n", path, err)
+		return exitFailure
+	}
+	var byteCode *my_compiler.ByteCode
+	if my_compiler.IsByteCode(data) {
+		byteCode, err = my_compiler.Decode(bytes.NewReader(data))
 		if err != nil {
-			fmt.Printf(
-				"Sorry, Monkey doesn't know how to compile %s: %s",
-				args[0],
-				err.Error(),
-			)
-			os.Exit(1)
+			fmt.Fprintf(os.Stderr, "Sorry, Monkey cannot load %s: %s\n", path, err)
+			return exitFailure
 		}
-		switch *engineFlag {
-		case "eval":
-			res, err := evalEngine.Evaluate(string(code))
-			if err != nil {
-				repl.PrintErrors(os.Stderr, err)
-				os.Exit(1)
-			}
-			fmt.Print(res.String())
-		case "vm":
-			fallthrough
-		default:
-			res, err := vmEngine.Evaluate(string(code))
-			if err != nil {
-				repl.PrintErrors(os.Stderr, err)
-				os.Exit(1)
-			}
-			fmt.Print(res.String())
+	} else {
+		byteCode, err = my_engine.CompileWithLoader(string(data), newLoader(filepath.Dir(path)))
+		if err != nil {
+			repl.PrintErrors(os.Stderr, string(data), err)
+			return exitCode(err)
 		}
+	}
+	fmt.Print(byteCode.Disassemble())
+	return exitOK
+}
+
+// runFile: run bytecode files with the vm, source files with the given engine;
+// bytecode cannot be evaluated, so asking the eval engine to run it is an error
+func runFile(engineName, path string) int {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Sorry, Monkey cannot read %s: %s\n", path, err)
+		return exitFailure
+	}
+	if !my_compiler.IsByteCode(data) {
+		res, err := newEngine(engineName, newLoader(filepath.Dir(path))).Evaluate(string(data))
+		return printResult(string(data), res, err)
+	}
+	if engineName == "eval" {
+		fmt.Fprintf(os.Stderr, "Sorry, Monkey can only run the bytecode file %s with -engine vm\n", path)
+		return exitFailure
+	}
+	byteCode, err := my_compiler.Decode(bytes.NewReader(data))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Sorry, Monkey cannot load %s: %s\n", path, err)
+		return exitFailure
+	}
+	res, err := my_engine.RunByteCode(byteCode)
+	return printResult("", res, err)
+}
+
+func printResult(code string, res my_object.Object, err error) int {
+	if err != nil {
+		repl.PrintErrors(os.Stderr, code, err)
+		return exitCode(err)
+	}
+	if res != nil {
+		fmt.Print(res.String())
+	}
+	return exitOK
+}
+
+func exitCode(err error) int {
+	switch {
+	case errors.Is(err, my_parser.ErrParseError):
+		return exitParseError
+	case errors.Is(err, my_engine.ErrCompile):
+		return exitCompileError
+	case errors.Is(err, my_engine.ErrRuntime):
+		return exitRuntimeError
 	default:
-		// error
-		fmt.Printf("Sorry, Monkey only knows how to compile one file at a time for now\n")
-		os.Exit(1)
+		return exitFailure
 	}
 }
+
+func newEngine(name string, loader *my_module.Loader) my_engine.Engine {
+	var engine my_engine.Engine
+	switch name {
+	case "eval":
+		engine = my_engine.NewEvalEngine()
+	case "vm":
+		fallthrough
+	default:
+		engine = my_engine.NewVMEngine()
+	}
+	engine.SetLoader(loader)
+	return engine
+}
+
+// newLoader: look modules up in dir, then in directories given by -path
+func newLoader(dir string) *my_module.Loader {
+	return my_module.NewLoader(append([]string{dir}, filepath.SplitList(*pathFlag)...)...)
+}
@@ -18,8 +18,20 @@ type Node interface {
 	// each line will end with semicolon;
 	// each token has a space in between;
 	String() string
+	// Span: range of source code the node is parsed from;
+	// zero value if the node is not created by the parser
+	Span() token.Span
 }
 
+// NodeSpan is embedded in every node to record its span in source code
+type NodeSpan struct {
+	SourceSpan token.Span
+}
+
+func (n *NodeSpan) Span() token.Span { return n.SourceSpan }
+
+func (n *NodeSpan) SetSpan(span token.Span) { n.SourceSpan = span }
+
 const (
 	NodeStringNewLine    = "\n"
 	NodeStringSemiColon  = ";"
@@ -39,6 +51,7 @@ type Statement interface {
 // root node
 
 type Program struct {
+	NodeSpan
 	Statements []Statement
 }
 
@@ -63,6 +76,7 @@ func (p *Program) String() string {
 // statements
 
 type LetStatement struct {
+	NodeSpan
 	Ident *Identifier
 	Value Expression
 }
@@ -87,6 +101,7 @@ func (l *LetStatement) String() string {
 }
 
 type ReturnStatement struct {
+	NodeSpan
 	Value Expression
 }
 
@@ -106,6 +121,7 @@ func (r *ReturnStatement) String() string {
 }
 
 type ExpressionStatement struct {
+	NodeSpan
 	Expression Expression
 }
 
@@ -128,6 +144,7 @@ func (e *ExpressionStatement) String() string {
 // expressions
 
 type Identifier struct {
+	NodeSpan
 	Value string
 }
 
@@ -142,6 +159,7 @@ func (i *Identifier) String() string {
 }
 
 type Integer struct {
+	NodeSpan
 	Value uint64
 }
 
@@ -156,6 +174,7 @@ func (i *Integer) String() string {
 }
 
 type Float struct {
+	NodeSpan
 	Value float64
 }
 
@@ -174,9 +193,11 @@ type PrefixOperator string
 const (
 	PREOP_MINUS PrefixOperator = token.MINUS
 	PREOP_BANG  PrefixOperator = token.BANG
+	PREOP_TILDE PrefixOperator = token.TILDE
 )
 
 type PrefixExpression struct {
+	NodeSpan
 	Operator PrefixOperator
 	Right    Expression
 }
@@ -197,23 +218,34 @@ func (p *PrefixExpression) String() string {
 type InfixOperator string
 
 const (
-	INOP_MINUS      InfixOperator = token.MINUS
-	INOP_PLUS       InfixOperator = token.PLUS
-	INOP_ASTERISK   InfixOperator = token.ASTERISK
-	INOP_SLASH      InfixOperator = token.SLASH
-	INOP_LT         InfixOperator = token.LT
-	INOP_GT         InfixOperator = token.GT
-	INOP_EQ         InfixOperator = token.EQ
-	INOP_NOT_EQ     InfixOperator = token.NOT_EQ
-	INOP_CALL       InfixOperator = token.LPAREN
-	INOP_INDEX      InfixOperator = token.LBRACKET
-	INOP_INDEXCOLON InfixOperator = token.COLON
-	INOP_GTE        InfixOperator = token.GTE
-	INOP_LTE        InfixOperator = token.LTE
-	INOP_REASSIGN   InfixOperator = token.REASSIGN
+	INOP_MINUS       InfixOperator = token.MINUS
+	INOP_PLUS        InfixOperator = token.PLUS
+	INOP_ASTERISK    InfixOperator = token.ASTERISK
+	INOP_SLASH       InfixOperator = token.SLASH
+	INOP_LT          InfixOperator = token.LT
+	INOP_GT          InfixOperator = token.GT
+	INOP_EQ          InfixOperator = token.EQ
+	INOP_NOT_EQ      InfixOperator = token.NOT_EQ
+	INOP_CALL        InfixOperator = token.LPAREN
+	INOP_INDEX       InfixOperator = token.LBRACKET
+	INOP_INDEXCOLON  InfixOperator = token.COLON
+	INOP_GTE         InfixOperator = token.GTE
+	INOP_LTE         InfixOperator = token.LTE
+	INOP_REASSIGN    InfixOperator = token.REASSIGN
+	INOP_AND         InfixOperator = token.AND
+	INOP_OR          InfixOperator = token.OR
+	INOP_PERCENT     InfixOperator = token.PERCENT
+	INOP_POWER       InfixOperator = token.POWER
+	INOP_FLOOR_SLASH InfixOperator = token.FLOOR_SLASH
+	INOP_AMPERSAND   InfixOperator = token.AMPERSAND
+	INOP_PIPE        InfixOperator = token.PIPE
+	INOP_CARET       InfixOperator = token.CARET
+	INOP_SHL         InfixOperator = token.SHL
+	INOP_SHR         InfixOperator = token.SHR
 )
 
 type InfixExpression struct {
+	NodeSpan
 	Operator InfixOperator
 	Left     Expression
 	Right    Expression
@@ -236,6 +268,7 @@ func (i *InfixExpression) String() string {
 }
 
 type Boolean struct {
+	NodeSpan
 	Value bool
 }
 
@@ -250,6 +283,7 @@ func (b *Boolean) String() string {
 }
 
 type BlockStatement struct {
+	NodeSpan
 	Statements []Statement
 }
 
@@ -270,6 +304,7 @@ func (b *BlockStatement) String() string {
 }
 
 type IfExpression struct {
+	NodeSpan
 	Condition   Expression
 	Consequence *BlockStatement
 	Alternative *BlockStatement
@@ -296,8 +331,11 @@ func (i *IfExpression) String() string {
 }
 
 type Function struct {
+	NodeSpan
 	Parameters []*Identifier
 	Body       *BlockStatement
+	// Name: set when the function is bound by a let statement so that it can refer to itself
+	Name string
 }
 
 func (f *Function) expressionNode() {}
@@ -325,6 +363,7 @@ func (f *Function) String() string {
 }
 
 type CallExpression struct {
+	NodeSpan
 	Function  Expression // Identifier or Function
 	Arguments []Expression
 }
@@ -350,6 +389,7 @@ func (c *CallExpression) String() string {
 }
 
 type StringExpression struct {
+	NodeSpan
 	Value string
 }
 
@@ -360,6 +400,7 @@ func (sl *StringExpression) String() string { return sl.Value }
 func (sl *StringExpression) expressionNode() {}
 
 type ArrayExpression struct {
+	NodeSpan
 	Elements []Expression
 }
 
@@ -378,6 +419,7 @@ func (ae *ArrayExpression) String() string {
 func (ae *ArrayExpression) expressionNode() {}
 
 type IndexExpression struct {
+	NodeSpan
 	Left            Expression
 	StartIndex      Expression // value specified by user for start index
 	IsSetStartIndex bool       // if start index is set when 1. user specified a colon : 2. user specified a value explicitly
@@ -416,6 +458,7 @@ func (aie *IndexExpression) String() string {
 func (aie *IndexExpression) expressionNode() {}
 
 type HashExpression struct {
+	NodeSpan
 	Pairs map[Expression]Expression
 	Keys  []Expression
 }
@@ -433,6 +476,7 @@ func (he *HashExpression) String() string {
 func (he *HashExpression) expressionNode() {}
 
 type ForExpression struct {
+	NodeSpan
 	InitStmt   Statement
 	TestExpr   Expression
 	UpdateStmt Statement
@@ -474,6 +518,7 @@ func (fe *ForExpression) String() string {
 func (fe *ForExpression) expressionNode() {}
 
 type DoWhileExpression struct {
+	NodeSpan
 	TestExpr Expression
 	Body     *BlockStatement
 }
@@ -500,6 +545,7 @@ func (dw *DoWhileExpression) String() string {
 func (dw *DoWhileExpression) expressionNode() {}
 
 type WhileExpression struct {
+	NodeSpan
 	TestExpr Expression
 	Body     *BlockStatement
 }
@@ -526,7 +572,9 @@ func (w *WhileExpression) String() string {
 
 func (w *WhileExpression) expressionNode() {}
 
-type BreakStatement struct{}
+type BreakStatement struct {
+	NodeSpan
+}
 
 func (b *BreakStatement) DebugString() string { return b.String() }
 
@@ -534,7 +582,9 @@ func (b *BreakStatement) String() string { return "break;" }
 
 func (b *BreakStatement) statementNode() {}
 
-type ContinueStatement struct{}
+type ContinueStatement struct {
+	NodeSpan
+}
 
 func (c *ContinueStatement) DebugString() string { return c.String() }
 
@@ -542,7 +592,37 @@ func (c *ContinueStatement) String() string { return "continue;" }
 
 func (c *ContinueStatement) statementNode() {}
 
-type Null struct{}
+// ImportStatement: import "path/to/lib.monkey" or import lib, optionally followed by `as name`;
+// Path is the module as written, Ident the binding of its namespace
+type ImportStatement struct {
+	NodeSpan
+	Path  string
+	Ident *Identifier
+}
+
+func (i *ImportStatement) DebugString() string { return i.Path }
+
+func (i *ImportStatement) String() string {
+	sb := strings.Builder{}
+	sb.WriteString(token.LookupKeywords(token.IMPORT))
+	sb.WriteString(NodeStringTokenSpace)
+	sb.WriteString(strconv.Quote(i.Path))
+	sb.WriteString(NodeStringTokenSpace)
+	sb.WriteString(ImportAs)
+	sb.WriteString(NodeStringTokenSpace)
+	sb.WriteString(i.Ident.Value)
+	sb.WriteString(NodeStringSemiColon)
+	return sb.String()
+}
+
+func (i *ImportStatement) statementNode() {}
+
+// ImportAs: word naming the binding of an import, not a keyword elsewhere
+const ImportAs = "as"
+
+type Null struct {
+	NodeSpan
+}
 
 var NULL = &Null{}
 
@@ -551,3 +631,87 @@ func (n *Null) DebugString() string { return n.String() }
 func (n *Null) String() string { return "null" }
 
 func (n *Null) expressionNode() {}
+
+// TryExpression: try { ... } catch (e) { ... } finally { ... };
+// either the catch or the finally block may be left out, and so may the parameter of catch
+type TryExpression struct {
+	NodeSpan
+	Body       *BlockStatement
+	CatchParam *Identifier
+	Catch      *BlockStatement
+	Finally    *BlockStatement
+}
+
+func (t *TryExpression) DebugString() string { return t.String() }
+
+func (t *TryExpression) String() string {
+	sb := &strings.Builder{}
+	sb.WriteString(token.LookupKeywords(token.TRY))
+	sb.WriteString(t.Body.String())
+	if t.Catch != nil {
+		sb.WriteString(token.LookupKeywords(token.CATCH))
+		if t.CatchParam != nil {
+			sb.WriteRune('(')
+			sb.WriteString(t.CatchParam.Value)
+			sb.WriteRune(')')
+		}
+		sb.WriteString(t.Catch.String())
+	}
+	if t.Finally != nil {
+		sb.WriteString(token.LookupKeywords(token.FINALLY))
+		sb.WriteString(t.Finally.String())
+	}
+	return sb.String()
+}
+
+func (t *TryExpression) expressionNode() {}
+
+// ThrowStatement: throw <EXPR>, raising any value as an exception
+type ThrowStatement struct {
+	NodeSpan
+	Value Expression
+}
+
+func (t *ThrowStatement) DebugString() string { return t.Value.DebugString() }
+
+func (t *ThrowStatement) String() string {
+	sb := strings.Builder{}
+	sb.WriteString(token.LookupKeywords(token.THROW))
+	sb.WriteString(NodeStringTokenSpace)
+	sb.WriteString(t.Value.String())
+	sb.WriteString(NodeStringSemiColon)
+	return sb.String()
+}
+
+func (t *ThrowStatement) statementNode() {}
+
+// AssignExpression: compound assignment like a += 1 or a[i] *= 2, applying Operator to the target and Value;
+// a++ and a-- as statements are a += 1 and a -= 1
+type AssignExpression struct {
+	NodeSpan
+	Target   Expression // Target: an identifier, or an index expression with a single index
+	Operator InfixOperator
+	Value    Expression
+}
+
+func (a *AssignExpression) expressionNode() {}
+
+func (a *AssignExpression) DebugString() string {
+	return string(a.Operator) + string(token.REASSIGN)
+}
+
+func (a *AssignExpression) String() string {
+	sb := strings.Builder{}
+	sb.WriteRune('(')
+	sb.WriteString(a.Target.String())
+	sb.WriteString(string(a.Operator))
+	sb.WriteString(string(token.REASSIGN))
+	sb.WriteString(a.Value.String())
+	sb.WriteRune(')')
+	return sb.String()
+}
+
+// IsElement: whether the index expression refers to a single element like a[i], rather than a slice
+func (aie *IndexExpression) IsElement() bool {
+	return aie.StartIndex != nil && !aie.IsSetEndIndex && !aie.IsSetStride
+}
@@ -31,6 +31,45 @@ const (
 	OpNull
 	OpGetGlobal
 	OpSetGlobal
+	OpCall        // call the function below its arguments on the stack
+	OpReturnValue // return from function with the top of the stack
+	OpReturn      // return from function with null
+	OpGetLocal
+	OpSetLocal
+	OpClosure        // wrap a compiled function constant with its free variables into a closure
+	OpGetFree        // push a free variable captured by the current closure
+	OpCurrentClosure // push the closure being executed, for self-referencing functions
+	OpArray          // build an array out of elements on the stack
+	OpHash           // build a hash out of keys and values on the stack
+	OpIndex          // index an array, string or hash with a single index
+	OpSlice          // python-like slicing with optional start, end and stride
+	OpSetFree        // reassign a free variable captured by the current closure
+	OpGetBuiltin     // push a builtin function from my_object.Builtins
+	OpImport         // push the namespace of a module, running the module the first time
+	OpSetupTry       // set up an exception handler for the try block that follows
+	OpPopTry         // remove the exception handler set up last, leaving its try block
+	OpThrow          // raise the top of the stack as an exception
+	OpMod            // modulo rounding towards negative infinity, taking the sign of the divisor
+	OpPow            // power, right-associative
+	OpFloorDiv       // division rounding towards negative infinity
+	OpBitAnd         // bitwise and of integers
+	OpBitOr          // bitwise or of integers
+	OpBitXor         // bitwise exclusive or of integers
+	OpShiftLeft      // shift an integer left
+	OpShiftRight     // shift an integer right, keeping its sign
+	OpBitNot         // prefix tilde, bitwise not of an integer
+	OpDupPair        // push the two values on top of the stack again, an array or hash and its index
+	OpSetIndex       // set an element of an array or hash, leaving the value assigned
+	OpCaptureLocal   // push the cell of a local binding for OpClosure, moving the binding into a new cell first
+	OpCaptureFree    // push the cell of a free variable for OpClosure, as captured by the current closure
+)
+
+// Flags as the operand of OpSlice, telling which values are on the stack and whether it yields a slice
+const (
+	SliceHasStart  = 1 << iota // start index is on the stack
+	SliceHasEnd                // end index is on the stack
+	SliceHasStride             // stride is on the stack
+	SliceIsRange               // colon specified: yields a slice instead of one element
 )
 
 func (ins Instructions) String() string {
@@ -55,6 +94,8 @@ func ReadOperands(def *Definition, ins Instructions) (operands []int, offset int
 		switch byteWidth {
 		case 2:
 			operands[idx] = int(ReadUint16(ins[offset:]))
+		case 1:
+			operands[idx] = int(ReadUint8(ins[offset:]))
 		}
 		offset += byteWidth
 	}
@@ -65,6 +106,10 @@ func ReadUint16(ins Instructions) uint16 {
 	return binary.BigEndian.Uint16(ins)
 }
 
+func ReadUint8(ins Instructions) uint8 {
+	return uint8(ins[0])
+}
+
 func Concat(instructions []Instructions) Instructions {
 	concatted := Instructions{}
 	for _, ins := range instructions {
@@ -83,6 +128,8 @@ func fmtIns(def *Definition, operands []int) string {
 		return def.Name
 	case 1:
 		return fmt.Sprintf("%s %d", def.Name, operands[0])
+	case 2:
+		return fmt.Sprintf("%s %d %d", def.Name, operands[0], operands[1])
 	}
 	return fmt.Sprintf("ERROR: unhandled operandCount for %s", def.Name)
 }
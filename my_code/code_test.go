@@ -2,6 +2,7 @@ package my_code
 
 import (
 	"math"
+	token "monkey/my_token"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -13,15 +14,55 @@ func TestDisassembleString(t *testing.T) {
 		Make(OpConstant, 2),
 		Make(OpConstant, math.MaxUint16),
 		Make(OpAdd),
+		Make(OpCall, 2),
+		Make(OpReturnValue),
+		Make(OpClosure, 65535, 255),
 	}
 	expected := `0000 OpConstant 1
 0003 OpConstant 2
 0006 OpConstant 65535
 0009 OpAdd
+0010 OpCall 2
+0012 OpReturnValue
+0013 OpClosure 65535 255
 `
 	assert.EqualValues(t, expected, Concat(instructions).String())
 }
 
+func TestFormat(t *testing.T) {
+	instructions := Concat([]Instructions{
+		Make(OpTrue),
+		Make(OpJumpNotTruthy, 10),
+		Make(OpConstant, 0),
+		Make(OpJump, 11),
+		Make(OpNull),
+		Make(OpPop),
+		Make(OpJump, 0),
+		{255},
+		{byte(OpConstant), 0},
+	})
+	annotate := func(op Opcode, operands []int) string {
+		if op == OpConstant {
+			return "constant"
+		}
+		return ""
+	}
+	expected := `L0:
+0000 OpTrue
+0001 OpJumpNotTruthy L1
+0004 OpConstant 0             ; constant
+0007 OpJump L2
+L1:
+0010 OpNull
+L2:
+0011 OpPop
+0012 OpJump L0
+0015 ERROR: opcode 255 undefined
+0016 ERROR: operands of OpConstant truncated
+`
+	assert.Equal(t, expected, instructions.Format(annotate))
+}
+
 func TestReadOperands(t *testing.T) {
 	tests := []struct {
 		op        Opcode
@@ -29,6 +70,8 @@ func TestReadOperands(t *testing.T) {
 		bytesRead int
 	}{
 		{OpConstant, []int{math.MaxUint16}, 2},
+		{OpCall, []int{math.MaxUint8}, 1},
+		{OpClosure, []int{math.MaxUint16, math.MaxUint8}, 3},
 	}
 	for _, tt := range tests {
 		instruction := Make(tt.op, tt.operands...)
@@ -39,3 +82,26 @@ func TestReadOperands(t *testing.T) {
 		assert.EqualValues(t, tt.operands, operandsRead)
 	}
 }
+
+func TestLineTable(t *testing.T) {
+	pos := func(line, column int) token.Position {
+		return token.Position{Line: line, Column: column}
+	}
+	lines := LineTable{}
+	lines.Add(0, pos(1, 1))
+	lines.Add(3, pos(1, 1))
+	lines.Add(4, token.Position{})
+	lines.Add(6, pos(2, 5))
+	lines.Add(6, pos(2, 3))
+	lines.Add(9, pos(3, 1))
+	assert.Equal(t, LineTable{{0, pos(1, 1)}, {6, pos(2, 3)}, {9, pos(3, 1)}}, lines)
+
+	assert.Equal(t, pos(1, 1), lines.Lookup(0))
+	assert.Equal(t, pos(1, 1), lines.Lookup(5))
+	assert.Equal(t, pos(2, 3), lines.Lookup(6))
+	assert.Equal(t, pos(3, 1), lines.Lookup(100))
+	assert.False(t, LineTable{}.Lookup(0).IsValid())
+
+	lines.Truncate(6)
+	assert.Equal(t, LineTable{{0, pos(1, 1)}}, lines)
+}
@@ -33,6 +33,50 @@ var definitions = map[Opcode]*Definition{
 	OpNull:      {"OpNull", []int{}},
 	OpGetGlobal: {"OpGetGlobal", []int{2}},
 	OpSetGlobal: {"OpSetGlobal", []int{2}},
+	// OpCall: 1 operand with 1 byte meaning number of arguments
+	OpCall:        {"OpCall", []int{1}},
+	OpReturnValue: {"OpReturnValue", []int{}},
+	OpReturn:      {"OpReturn", []int{}},
+	// OpGetLocal, OpSetLocal: 1 operand with 1 byte meaning 256 local bindings max per function
+	OpGetLocal: {"OpGetLocal", []int{1}},
+	OpSetLocal: {"OpSetLocal", []int{1}},
+	// OpClosure: 2 operands, the first with 2 bytes as constant index of the compiled function,
+	// the second with 1 byte as number of free variables sitting on the stack
+	OpClosure:        {"OpClosure", []int{2, 1}},
+	OpGetFree:        {"OpGetFree", []int{1}},
+	OpCurrentClosure: {"OpCurrentClosure", []int{}},
+	// OpArray: 1 operand with 2 bytes meaning number of elements
+	OpArray: {"OpArray", []int{2}},
+	// OpHash: 1 operand with 2 bytes meaning number of keys and values added up
+	OpHash:  {"OpHash", []int{2}},
+	OpIndex: {"OpIndex", []int{}},
+	// OpSlice: 1 operand with 1 byte of Slice* flags
+	OpSlice:   {"OpSlice", []int{1}},
+	OpSetFree: {"OpSetFree", []int{1}},
+	// OpGetBuiltin: 1 operand with 1 byte as index of the builtin in the registry
+	OpGetBuiltin: {"OpGetBuiltin", []int{1}},
+	// OpImport: 2 operands with 2 bytes each, the global keeping the namespace of the module once imported
+	// and the constant index of the compiled function running the module and returning its namespace
+	OpImport: {"OpImport", []int{2, 2}},
+	// OpSetupTry: 1 operand with 2 bytes as the position of the handler,
+	// which starts with the exception pushed onto the stack as it is when the handler is set up
+	OpSetupTry:   {"OpSetupTry", []int{2}},
+	OpPopTry:     {"OpPopTry", []int{}},
+	OpThrow:      {"OpThrow", []int{}},
+	OpMod:        {"OpMod", []int{}},
+	OpPow:        {"OpPow", []int{}},
+	OpFloorDiv:   {"OpFloorDiv", []int{}},
+	OpBitAnd:     {"OpBitAnd", []int{}},
+	OpBitOr:      {"OpBitOr", []int{}},
+	OpBitXor:     {"OpBitXor", []int{}},
+	OpShiftLeft:  {"OpShiftLeft", []int{}},
+	OpShiftRight: {"OpShiftRight", []int{}},
+	OpBitNot:     {"OpBitNot", []int{}},
+	OpDupPair:    {"OpDupPair", []int{}},
+	OpSetIndex:   {"OpSetIndex", []int{}},
+	// OpCaptureLocal, OpCaptureFree: 1 operand with 1 byte, the same as OpGetLocal and OpGetFree
+	OpCaptureLocal: {"OpCaptureLocal", []int{1}},
+	OpCaptureFree:  {"OpCaptureFree", []int{1}},
 }
 
 func Lookup(op byte) (*Definition, error) {
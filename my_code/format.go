@@ -0,0 +1,90 @@
+package my_code
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Annotate: comment resolving operands of an instruction, empty for no comment
+type Annotate func(op Opcode, operands []int) string
+
+// Format: disassemble instructions like String, but jump targets are turned into labels,
+// and each instruction is commented by annotate if not nil;
+// unlike String, malformed instructions are reported instead of panicking
+func (ins Instructions) Format(annotate Annotate) string {
+	labels := ins.jumpLabels()
+	sb := &strings.Builder{}
+	for i := 0; i < len(ins); {
+		if label, ok := labels[i]; ok {
+			sb.WriteString(fmt.Sprintf("%s:\n", label))
+		}
+		def, err := Lookup(ins[i])
+		if err != nil {
+			sb.WriteString(fmt.Sprintf("%04d ERROR: %s\n", i, err))
+			i++
+			continue
+		}
+		if i+1+def.OperandsWidth() > len(ins) {
+			sb.WriteString(fmt.Sprintf("%04d ERROR: operands of %s truncated\n", i, def.Name))
+			break
+		}
+		operands, bytesRead := ReadOperands(def, ins[i+1:])
+		line := fmtIns(def, operands)
+		if IsJump(Opcode(ins[i])) {
+			line = fmt.Sprintf("%s %s", def.Name, labels[operands[0]])
+		}
+		if annotate != nil {
+			if comment := annotate(Opcode(ins[i]), operands); comment != "" {
+				line = fmt.Sprintf("%-24s ; %s", line, comment)
+			}
+		}
+		sb.WriteString(fmt.Sprintf("%04d %s\n", i, line))
+		i += 1 + bytesRead
+	}
+	// jumping right past the last instruction
+	if label, ok := labels[len(ins)]; ok {
+		sb.WriteString(fmt.Sprintf("%s:\n", label))
+	}
+	return sb.String()
+}
+
+// IsJump: whether the only operand of op is the position to jump to,
+// including OpSetupTry jumping to its handler once an exception is raised
+func IsJump(op Opcode) bool {
+	return op == OpJump || op == OpJumpNotTruthy || op == OpSetupTry
+}
+
+// jumpLabels: labels named after the order of jump targets
+func (ins Instructions) jumpLabels() map[int]string {
+	targets := []int{}
+	seen := map[int]bool{}
+	for i := 0; i < len(ins); {
+		def, err := Lookup(ins[i])
+		if err != nil || i+1+def.OperandsWidth() > len(ins) {
+			i++
+			continue
+		}
+		operands, bytesRead := ReadOperands(def, ins[i+1:])
+		if IsJump(Opcode(ins[i])) && !seen[operands[0]] {
+			seen[operands[0]] = true
+			targets = append(targets, operands[0])
+		}
+		i += 1 + bytesRead
+	}
+	sort.Ints(targets)
+	labels := make(map[int]string, len(targets))
+	for idx, target := range targets {
+		labels[target] = fmt.Sprintf("L%d", idx)
+	}
+	return labels
+}
+
+// OperandsWidth: number of bytes taken by all operands
+func (def *Definition) OperandsWidth() int {
+	width := 0
+	for _, w := range def.OperandWidths {
+		width += w
+	}
+	return width
+}
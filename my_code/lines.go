@@ -0,0 +1,48 @@
+package my_code
+
+import (
+	"sort"
+
+	token "monkey/my_token"
+)
+
+// LineTable: source positions of instructions sorted by offset,
+// an entry covers instructions from its offset up to the next entry
+type LineTable []LineEntry
+
+type LineEntry struct {
+	Offset int
+	Pos    token.Position
+}
+
+// Add: record pos for the instruction at offset,
+// skipping it if the position is unknown or the same as the last one
+func (lt *LineTable) Add(offset int, pos token.Position) {
+	if !pos.IsValid() {
+		return
+	}
+	n := len(*lt)
+	if n > 0 && (*lt)[n-1].Pos == pos {
+		return
+	}
+	if n > 0 && (*lt)[n-1].Offset == offset {
+		(*lt)[n-1].Pos = pos
+		return
+	}
+	*lt = append(*lt, LineEntry{Offset: offset, Pos: pos})
+}
+
+// Truncate: drop entries of instructions removed from offset on
+func (lt *LineTable) Truncate(offset int) {
+	idx := sort.Search(len(*lt), func(i int) bool { return (*lt)[i].Offset >= offset })
+	*lt = (*lt)[:idx]
+}
+
+// Lookup: position of the instruction at offset, invalid if unknown
+func (lt LineTable) Lookup(offset int) token.Position {
+	idx := sort.Search(len(lt), func(i int) bool { return lt[i].Offset > offset })
+	if idx == 0 {
+		return token.Position{}
+	}
+	return lt[idx-1].Pos
+}
@@ -15,6 +15,8 @@ func TestMake(t *testing.T) {
 	}{
 		{OpConstant, []int{math.MaxUint16 - 1}, []byte{byte(OpConstant), 255, 254}},
 		{OpAdd, []int{}, []byte{byte(OpAdd)}},
+		{OpCall, []int{255}, []byte{byte(OpCall), 255}},
+		{OpClosure, []int{65534, 255}, []byte{byte(OpClosure), 255, 254, 255}},
 	}
 	for _, test := range tests {
 		instruction := Make(test.op, test.operands...)
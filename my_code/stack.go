@@ -0,0 +1,50 @@
+package my_code
+
+// StackEffect: number of values an instruction pops from and then pushes onto the stack of its frame;
+// returning instructions pop the returned value and leave the frame
+func StackEffect(op Opcode, operands []int) (pops, pushes int) {
+	switch op {
+	case OpConstant, OpTrue, OpFalse, OpNull,
+		OpGetGlobal, OpGetLocal, OpGetFree, OpGetBuiltin, OpCurrentClosure, OpImport,
+		OpCaptureLocal, OpCaptureFree:
+		return 0, 1
+	case OpPop, OpSetGlobal, OpSetLocal, OpSetFree, OpJumpNotTruthy, OpThrow:
+		return 1, 0
+	case OpAdd, OpSub, OpMul, OpDiv, OpEqual, OpNotEqual, OpGT, OpGTE, OpIndex,
+		OpMod, OpPow, OpFloorDiv, OpBitAnd, OpBitOr, OpBitXor, OpShiftLeft, OpShiftRight:
+		return 2, 1
+	case OpMinus, OpBang, OpBitNot:
+		return 1, 1
+	case OpDupPair:
+		return 2, 4
+	case OpSetIndex:
+		// the array or hash, the index and the value
+		return 3, 1
+	case OpJump, OpReturn, OpSetupTry, OpPopTry:
+		return 0, 0
+	case OpReturnValue:
+		return 1, 0
+	case OpCall:
+		// the function and its arguments
+		return operands[0] + 1, 1
+	case OpClosure:
+		// free variables
+		return operands[1], 1
+	case OpArray, OpHash:
+		return operands[0], 1
+	case OpSlice:
+		pops = 1
+		for _, flag := range []int{SliceHasStart, SliceHasEnd, SliceHasStride} {
+			if operands[0]&flag != 0 {
+				pops++
+			}
+		}
+		return pops, 1
+	}
+	return 0, 0
+}
+
+// IsTerminator: whether execution never continues to the next instruction
+func IsTerminator(op Opcode) bool {
+	return op == OpJump || op == OpReturnValue || op == OpReturn || op == OpThrow
+}
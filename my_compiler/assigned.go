@@ -0,0 +1,82 @@
+package my_compiler
+
+import "monkey/my_ast"
+
+// assignsTo: whether node assigns to the identifier name anywhere, nested functions included;
+// a function assigning to its own name refers to the binding holding it instead of itself,
+// so that reads in its body see what is assigned like in my_evaluator
+func assignsTo(node my_ast.Node, name string) bool {
+	switch node := node.(type) {
+	case *my_ast.Program:
+		return statementsAssignTo(node.Statements, name)
+	case *my_ast.BlockStatement:
+		return node != nil && statementsAssignTo(node.Statements, name)
+	case *my_ast.LetStatement:
+		return assignsTo(node.Value, name)
+	case *my_ast.ReturnStatement:
+		return assignsTo(node.Value, name)
+	case *my_ast.ExpressionStatement:
+		return assignsTo(node.Expression, name)
+	case *my_ast.ThrowStatement:
+		return assignsTo(node.Value, name)
+	case *my_ast.PrefixExpression:
+		return assignsTo(node.Right, name)
+	case *my_ast.InfixExpression:
+		if node.Operator == my_ast.INOP_REASSIGN && isIdentifier(node.Left, name) {
+			return true
+		}
+		return assignsTo(node.Left, name) || assignsTo(node.Right, name)
+	case *my_ast.AssignExpression:
+		return isIdentifier(node.Target, name) || assignsTo(node.Target, name) || assignsTo(node.Value, name)
+	case *my_ast.IfExpression:
+		return assignsTo(node.Condition, name) || assignsTo(node.Consequence, name) || assignsTo(node.Alternative, name)
+	case *my_ast.Function:
+		return assignsTo(node.Body, name)
+	case *my_ast.CallExpression:
+		return assignsTo(node.Function, name) || expressionsAssignTo(node.Arguments, name)
+	case *my_ast.ArrayExpression:
+		return expressionsAssignTo(node.Elements, name)
+	case *my_ast.IndexExpression:
+		return assignsTo(node.Left, name) || assignsTo(node.StartIndex, name) ||
+			assignsTo(node.EndIndex, name) || assignsTo(node.Stride, name)
+	case *my_ast.HashExpression:
+		for _, key := range node.Keys {
+			if assignsTo(key, name) || assignsTo(node.Pairs[key], name) {
+				return true
+			}
+		}
+	case *my_ast.ForExpression:
+		return assignsTo(node.InitStmt, name) || assignsTo(node.TestExpr, name) ||
+			assignsTo(node.UpdateStmt, name) || assignsTo(node.Body, name)
+	case *my_ast.WhileExpression:
+		return assignsTo(node.TestExpr, name) || assignsTo(node.Body, name)
+	case *my_ast.DoWhileExpression:
+		return assignsTo(node.TestExpr, name) || assignsTo(node.Body, name)
+	case *my_ast.TryExpression:
+		return assignsTo(node.Body, name) || assignsTo(node.Catch, name) || assignsTo(node.Finally, name)
+	}
+	return false
+}
+
+func statementsAssignTo(statements []my_ast.Statement, name string) bool {
+	for _, stmt := range statements {
+		if assignsTo(stmt, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func expressionsAssignTo(expressions []my_ast.Expression, name string) bool {
+	for _, expr := range expressions {
+		if assignsTo(expr, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func isIdentifier(expr my_ast.Expression, name string) bool {
+	ident, ok := expr.(*my_ast.Identifier)
+	return ok && ident.Value == name
+}
@@ -0,0 +1,319 @@
+package my_compiler
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"monkey/my_code"
+	"monkey/my_object"
+	token "monkey/my_token"
+)
+
+// Layout of a bytecode file, integers are big endian like operands in my_code:
+//
+//	magic        4 bytes "MKC\x00"
+//	version      uint16
+//	flags        uint16, see flagDebugInfo
+//	instructions uvarint length + bytes
+//	constants    uvarint count + constants, each a tag byte followed by its payload
+//	debug info   only if flagDebugInfo is set: global names and the line table of main
+//
+// with flagDebugInfo set, compiled functions in the constant pool also carry their names and line tables.
+//
+// builtins are referred to by their indices in my_object.Builtins,
+// so a file only runs with the same builtins registered in the same order.
+
+// FileExtension: extension of precompiled bytecode files
+const FileExtension = ".mkc"
+
+// FileVersion: bumped whenever the layout or the instruction set changes
+const FileVersion uint16 = 3
+
+var fileMagic = [4]byte{'M', 'K', 'C', 0}
+
+const (
+	// flagDebugInfo: debug info follows the constants and is attached to compiled functions
+	flagDebugInfo uint16 = 1 << iota
+)
+
+// tags of typed constants in the constant pool
+const (
+	constInteger byte = iota + 1
+	constFloat
+	constString
+	constCompiledFunction
+)
+
+var (
+	ErrBadMagic           = errors.New("not a monkey bytecode file")
+	ErrUnsupportedVersion = errors.New("unsupported bytecode version")
+	ErrCorruptedByteCode  = errors.New("corrupted bytecode")
+)
+
+// DebugInfo: information not needed to run the bytecode, but to inspect it
+type DebugInfo struct {
+	// Globals: names of global bindings indexed by their slots;
+	// empty if the binding is defined in a block and no longer visible
+	Globals []string
+	// Lines: line table of main, compiled functions carry their own
+	Lines my_code.LineTable
+}
+
+// IsByteCode: whether data starts with the header of a bytecode file
+func IsByteCode(data []byte) bool {
+	return bytes.HasPrefix(data, fileMagic[:])
+}
+
+// Encode: write the bytecode in the binary file format
+func (bc *ByteCode) Encode(w io.Writer) error {
+	buf := &bytes.Buffer{}
+	flags := uint16(0)
+	if bc.Debug != nil {
+		flags |= flagDebugInfo
+	}
+	buf.Write(fileMagic[:])
+	binary.Write(buf, binary.BigEndian, FileVersion)
+	binary.Write(buf, binary.BigEndian, flags)
+	writeBytes(buf, bc.Instructions)
+	writeUvarint(buf, uint64(len(bc.Constants)))
+	for idx, constant := range bc.Constants {
+		if err := writeConstant(buf, constant, bc.Debug != nil); err != nil {
+			return fmt.Errorf("constant %d: %w", idx, err)
+		}
+	}
+	if bc.Debug != nil {
+		writeUvarint(buf, uint64(len(bc.Debug.Globals)))
+		for _, name := range bc.Debug.Globals {
+			writeBytes(buf, []byte(name))
+		}
+		writeLineTable(buf, bc.Debug.Lines)
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// Decode: read the bytecode written by Encode
+func Decode(r io.Reader) (*ByteCode, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	rd := bytes.NewReader(data)
+	var magic [4]byte
+	if _, err := io.ReadFull(rd, magic[:]); err != nil || magic != fileMagic {
+		return nil, ErrBadMagic
+	}
+	var version, flags uint16
+	if err := binary.Read(rd, binary.BigEndian, &version); err != nil {
+		return nil, ErrCorruptedByteCode
+	}
+	if version != FileVersion {
+		return nil, fmt.Errorf("%w: got=%d, want=%d", ErrUnsupportedVersion, version, FileVersion)
+	}
+	if err := binary.Read(rd, binary.BigEndian, &flags); err != nil {
+		return nil, ErrCorruptedByteCode
+	}
+
+	bc := &ByteCode{}
+	if bc.Instructions, err = readBytes(rd); err != nil {
+		return nil, err
+	}
+	numConstants, err := readLength(rd)
+	if err != nil {
+		return nil, err
+	}
+	bc.Constants = make([]my_object.Object, 0, numConstants)
+	for i := 0; i < numConstants; i++ {
+		constant, err := readConstant(rd, flags&flagDebugInfo != 0)
+		if err != nil {
+			return nil, fmt.Errorf("constant %d: %w", i, err)
+		}
+		bc.Constants = append(bc.Constants, constant)
+	}
+	if flags&flagDebugInfo != 0 {
+		numGlobals, err := readLength(rd)
+		if err != nil {
+			return nil, err
+		}
+		bc.Debug = &DebugInfo{Globals: make([]string, 0, numGlobals)}
+		for i := 0; i < numGlobals; i++ {
+			name, err := readBytes(rd)
+			if err != nil {
+				return nil, err
+			}
+			bc.Debug.Globals = append(bc.Debug.Globals, string(name))
+		}
+		if bc.Debug.Lines, err = readLineTable(rd); err != nil {
+			return nil, err
+		}
+	}
+	if rd.Len() != 0 {
+		return nil, fmt.Errorf("%w: %d trailing bytes", ErrCorruptedByteCode, rd.Len())
+	}
+	return bc, nil
+}
+
+func writeConstant(buf *bytes.Buffer, constant my_object.Object, debug bool) error {
+	switch constant := constant.(type) {
+	case *my_object.Integer:
+		buf.WriteByte(constInteger)
+		varint := make([]byte, binary.MaxVarintLen64)
+		buf.Write(varint[:binary.PutVarint(varint, constant.Value)])
+	case *my_object.Float:
+		buf.WriteByte(constFloat)
+		binary.Write(buf, binary.BigEndian, math.Float64bits(constant.Value))
+	case *my_object.String:
+		buf.WriteByte(constString)
+		writeBytes(buf, []byte(constant.Value))
+	case *my_object.CompiledFunction:
+		buf.WriteByte(constCompiledFunction)
+		writeUvarint(buf, uint64(constant.NumLocals))
+		writeUvarint(buf, uint64(constant.NumParameters))
+		writeBytes(buf, constant.Instructions)
+		if debug {
+			writeBytes(buf, []byte(constant.Name))
+			writeLineTable(buf, constant.Lines)
+			writeBytes(buf, []byte(constant.Module))
+		}
+	default:
+		return fmt.Errorf("cannot encode constant of type %s", constant.Type())
+	}
+	return nil
+}
+
+func readConstant(rd *bytes.Reader, debug bool) (my_object.Object, error) {
+	tag, err := rd.ReadByte()
+	if err != nil {
+		return nil, ErrCorruptedByteCode
+	}
+	switch tag {
+	case constInteger:
+		value, err := binary.ReadVarint(rd)
+		if err != nil {
+			return nil, ErrCorruptedByteCode
+		}
+		return &my_object.Integer{Value: value}, nil
+	case constFloat:
+		var bits uint64
+		if err := binary.Read(rd, binary.BigEndian, &bits); err != nil {
+			return nil, ErrCorruptedByteCode
+		}
+		return &my_object.Float{Value: math.Float64frombits(bits)}, nil
+	case constString:
+		value, err := readBytes(rd)
+		if err != nil {
+			return nil, err
+		}
+		return &my_object.String{Value: string(value)}, nil
+	case constCompiledFunction:
+		numLocals, err := readInt(rd)
+		if err != nil {
+			return nil, err
+		}
+		numParameters, err := readInt(rd)
+		if err != nil {
+			return nil, err
+		}
+		instructions, err := readBytes(rd)
+		if err != nil {
+			return nil, err
+		}
+		fn := &my_object.CompiledFunction{
+			Instructions:  my_code.Instructions(instructions),
+			NumLocals:     numLocals,
+			NumParameters: numParameters,
+		}
+		if debug {
+			name, err := readBytes(rd)
+			if err != nil {
+				return nil, err
+			}
+			fn.Name = string(name)
+			if fn.Lines, err = readLineTable(rd); err != nil {
+				return nil, err
+			}
+			module, err := readBytes(rd)
+			if err != nil {
+				return nil, err
+			}
+			fn.Module = string(module)
+		}
+		return fn, nil
+	default:
+		return nil, fmt.Errorf("%w: unknown constant tag %d", ErrCorruptedByteCode, tag)
+	}
+}
+
+// writeLineTable: entries as instruction offset, source offset, line and column
+func writeLineTable(buf *bytes.Buffer, lines my_code.LineTable) {
+	writeUvarint(buf, uint64(len(lines)))
+	for _, entry := range lines {
+		writeUvarint(buf, uint64(entry.Offset))
+		writeUvarint(buf, uint64(entry.Pos.Offset))
+		writeUvarint(buf, uint64(entry.Pos.Line))
+		writeUvarint(buf, uint64(entry.Pos.Column))
+	}
+}
+
+func readLineTable(rd *bytes.Reader) (my_code.LineTable, error) {
+	numEntries, err := readLength(rd)
+	if err != nil {
+		return nil, err
+	}
+	var lines my_code.LineTable
+	for i := 0; i < numEntries; i++ {
+		var fields [4]int
+		for j := range fields {
+			if fields[j], err = readInt(rd); err != nil {
+				return nil, err
+			}
+		}
+		lines = append(lines, my_code.LineEntry{
+			Offset: fields[0],
+			Pos:    token.Position{Offset: fields[1], Line: fields[2], Column: fields[3]},
+		})
+	}
+	return lines, nil
+}
+
+func writeUvarint(buf *bytes.Buffer, value uint64) {
+	uvarint := make([]byte, binary.MaxVarintLen64)
+	buf.Write(uvarint[:binary.PutUvarint(uvarint, value)])
+}
+
+func writeBytes(buf *bytes.Buffer, value []byte) {
+	writeUvarint(buf, uint64(len(value)))
+	buf.Write(value)
+}
+
+func readInt(rd *bytes.Reader) (int, error) {
+	value, err := binary.ReadUvarint(rd)
+	if err != nil || value > math.MaxInt32 {
+		return 0, ErrCorruptedByteCode
+	}
+	return int(value), nil
+}
+
+// readLength: read a count or size, which never exceeds the bytes left
+func readLength(rd *bytes.Reader) (int, error) {
+	value, err := binary.ReadUvarint(rd)
+	if err != nil || value > uint64(rd.Len()) {
+		return 0, ErrCorruptedByteCode
+	}
+	return int(value), nil
+}
+
+func readBytes(rd *bytes.Reader) ([]byte, error) {
+	size, err := readLength(rd)
+	if err != nil {
+		return nil, err
+	}
+	value := make([]byte, size)
+	if _, err := io.ReadFull(rd, value); err != nil {
+		return nil, ErrCorruptedByteCode
+	}
+	return value, nil
+}
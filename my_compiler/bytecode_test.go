@@ -0,0 +1,73 @@
+package my_compiler
+
+import (
+	"bytes"
+	"monkey/my_object"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestByteCodeEncodeDecode(t *testing.T) {
+	input := `
+	let a = 1;
+	let b = -2.5;
+	let s = "monkey";
+	let add = fn(x) { fn(y) { x + y + a } };
+	if (true) { let hidden = 1; }
+	add(2)(3);
+	`
+	comp := New()
+	assert.NoError(t, comp.Compile(parse(input)))
+	bc := comp.ByteCode()
+	assert.Equal(t, []string{"a", "b", "s", "add", ""}, bc.Debug.Globals)
+
+	buf := &bytes.Buffer{}
+	assert.NoError(t, bc.Encode(buf))
+	assert.Equal(t, []byte("MKC\x00"), buf.Bytes()[:4])
+	decoded, err := Decode(bytes.NewReader(buf.Bytes()))
+	assert.NoError(t, err)
+	assert.Equal(t, bc, decoded)
+
+	// without debug info, names, line tables and modules of functions are dropped as well
+	bc.Debug = nil
+	for _, constant := range bc.Constants {
+		if fn, ok := constant.(*my_object.CompiledFunction); ok {
+			fn.Name, fn.Lines, fn.Module = "", nil, ""
+		}
+	}
+	buf.Reset()
+	assert.NoError(t, bc.Encode(buf))
+	decoded, err = Decode(buf)
+	assert.NoError(t, err)
+	assert.Equal(t, bc, decoded)
+}
+
+func TestByteCodeDecodeErrors(t *testing.T) {
+	comp := New()
+	assert.NoError(t, comp.Compile(parse(`let f = fn(a) { a * 2 }; f(21)`)))
+	buf := &bytes.Buffer{}
+	assert.NoError(t, comp.ByteCode().Encode(buf))
+	encoded := buf.Bytes()
+
+	_, err := Decode(bytes.NewReader([]byte("let a = 1;")))
+	assert.ErrorIs(t, err, ErrBadMagic)
+
+	wrongVersion := append([]byte{}, encoded...)
+	wrongVersion[5] = byte(FileVersion + 1)
+	_, err = Decode(bytes.NewReader(wrongVersion))
+	assert.ErrorIs(t, err, ErrUnsupportedVersion)
+
+	for size := 4; size < len(encoded); size++ {
+		_, err = Decode(bytes.NewReader(encoded[:size]))
+		assert.ErrorIs(t, err, ErrCorruptedByteCode, "truncated to %d bytes", size)
+	}
+	_, err = Decode(bytes.NewReader(append(encoded, 0)))
+	assert.ErrorIs(t, err, ErrCorruptedByteCode)
+}
+
+func TestByteCodeEncodeUnsupportedConstant(t *testing.T) {
+	bc := &ByteCode{Constants: []my_object.Object{&my_object.Boolean{Value: true}}}
+	err := bc.Encode(&bytes.Buffer{})
+	assert.EqualError(t, err, "constant 0: cannot encode constant of type BOOLEAN")
+}
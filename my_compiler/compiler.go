@@ -4,46 +4,98 @@ import (
 	"fmt"
 	"monkey/my_ast"
 	"monkey/my_code"
+	"monkey/my_module"
 	"monkey/my_object"
+	token "monkey/my_token"
 )
 
 type Compiler struct {
+	constants   []my_object.Object
+	symbolTable *SymbolTable
+	// scopes: one compilation scope per function body being compiled, the outermost being main
+	scopes     []CompilationScope
+	scopeIndex int
+	// position: start of the innermost node being compiled, recorded into line tables on emit
+	position token.Position
+	// loader: nil if code cannot import modules
+	loader *my_module.Loader
+	// module: path of the module being compiled, empty for the main program
+	module string
+}
+
+type CompilationScope struct {
 	instructions my_code.Instructions
-	constants    []my_object.Object
 	// trackedInstructions: tracking the last and before the last instructions emitted
 	trackedInstructions [2]*EmittedInstruction
-	symbolTable         *SymbolTable
+	// loops: enclosing loops of the code being compiled, the innermost being the last
+	loops []*LoopContext
+	// tries: try blocks whose handlers are set up at the code being compiled, the innermost being the last
+	tries []*TryContext
+	// stackDepth: number of values on the stack of the frame when the emitted instructions finish,
+	// so that jumps out of an expression can leave the stack balanced
+	stackDepth int
+	lines      my_code.LineTable
+}
+
+// LoopContext: positions of jumps emitted by break and continue,
+// to be back-patched once the loop is fully compiled
+type LoopContext struct {
+	breakJumps    []int
+	continueJumps []int
+	// stackDepth: stack depth at the start and the end of the loop body
+	stackDepth int
+	// tries: number of try blocks of the function set up outside the loop
+	tries int
 }
 
+// loopResultName: hidden binding holding the value of the last completed loop iteration;
+// not a legal identifier so it never clashes with user code
+const loopResultName = "@loop"
+
 type ByteCode struct {
 	Instructions my_code.Instructions
 	Constants    []my_object.Object
+	// Debug: optional, nil if stripped
+	Debug *DebugInfo
 }
 
 type EmittedInstruction struct {
 	Position int
 	OpCode   my_code.Opcode
+	// stackDepth: stack depth before the instruction
+	stackDepth int
 }
 
 func New() *Compiler {
-	return &Compiler{
-		instructions:        my_code.Instructions{},
-		constants:           []my_object.Object{},
-		trackedInstructions: [2]*EmittedInstruction{nil, nil},
-		symbolTable:         NewSymbolTable(),
-	}
+	return NewWithState([]my_object.Object{}, NewSymbolTable())
 }
 
 func NewWithState(constants []my_object.Object, symbolTable *SymbolTable) *Compiler {
 	return &Compiler{
+		constants:   constants,
+		symbolTable: symbolTable,
+		scopes:      []CompilationScope{newCompilationScope()},
+		scopeIndex:  0,
+	}
+}
+
+func newCompilationScope() CompilationScope {
+	return CompilationScope{
 		instructions:        my_code.Instructions{},
-		constants:           constants,
 		trackedInstructions: [2]*EmittedInstruction{nil, nil},
-		symbolTable:         symbolTable,
 	}
 }
 
 func (c *Compiler) Compile(node my_ast.Node) error {
+	if node == nil {
+		return nil
+	}
+	// synthesized nodes without a span take the position of the enclosing node
+	if pos := node.Span().Start; pos.IsValid() {
+		outer := c.position
+		c.position = pos
+		defer func() { c.position = outer }()
+	}
 	switch node := node.(type) {
 	// statements
 	case *my_ast.Program:
@@ -67,13 +119,61 @@ func (c *Compiler) Compile(node my_ast.Node) error {
 			}
 		}
 	case *my_ast.LetStatement:
+		// decide what number to set to the identifier from the symbol table;
+		// functions get their binding before the body is compiled so that they can reassign it,
+		// other values are compiled first so that `let a = a + 1` refers to the outer `a`
+		var sym Symbol
+		if _, isFn := node.Value.(*my_ast.Function); isFn {
+			sym = c.symbolTable.Define(node.Ident.Value)
+		}
 		err := c.Compile(node.Value)
 		if err != nil {
 			return err
 		}
-		// decide what number to set to the identifier from the symbol table
-		sym := c.symbolTable.Define(node.Ident.Value)
-		c.emit(my_code.OpSetGlobal, sym.Index)
+		if sym.Name == "" {
+			sym = c.symbolTable.Define(node.Ident.Value)
+		}
+		c.storeSymbol(sym)
+	case *my_ast.ImportStatement:
+		return c.compileImport(node)
+	case *my_ast.BreakStatement:
+		loop := c.currentLoop()
+		if loop == nil {
+			return fmt.Errorf("break outside loop")
+		}
+		err := c.leaveTries(loop.tries)
+		if err != nil {
+			return err
+		}
+		c.popTo(loop.stackDepth)
+		loop.breakJumps = append(loop.breakJumps, c.emit(my_code.OpJump, 0))
+	case *my_ast.ContinueStatement:
+		loop := c.currentLoop()
+		if loop == nil {
+			return fmt.Errorf("continue outside loop")
+		}
+		err := c.leaveTries(loop.tries)
+		if err != nil {
+			return err
+		}
+		c.popTo(loop.stackDepth)
+		loop.continueJumps = append(loop.continueJumps, c.emit(my_code.OpJump, 0))
+	case *my_ast.ReturnStatement:
+		err := c.Compile(node.Value)
+		if err != nil {
+			return err
+		}
+		err = c.leaveTries(0)
+		if err != nil {
+			return err
+		}
+		c.emit(my_code.OpReturnValue)
+	case *my_ast.ThrowStatement:
+		err := c.Compile(node.Value)
+		if err != nil {
+			return err
+		}
+		c.emit(my_code.OpThrow)
 	// expressions
 	case *my_ast.IfExpression:
 		err := c.Compile(node.Condition)
@@ -81,31 +181,35 @@ func (c *Compiler) Compile(node my_ast.Node) error {
 			return err
 		}
 		jumpNotTruthyPos := c.emit(my_code.OpJumpNotTruthy, 0)
-		err = c.Compile(node.Consequence)
+		branchDepth := c.stackDepth()
+		err = c.compileBlockExpression(node.Consequence)
 		if err != nil {
 			return err
 		}
-		// remove last OpPop because block statement emitted one and if is an expression,
-		// which means if should return one and only one expression value
-		if c.isLastInstruction(my_code.OpPop) {
-			c.removeLastInstruction()
-		}
 		jumpPos := c.emit(my_code.OpJump, 0)
+		c.setStackDepth(branchDepth)
 		// change OpJumpNotTruthy operands after we knew where consequence ins ends
-		c.replaceOperands(jumpNotTruthyPos, len(c.instructions))
+		c.replaceOperands(jumpNotTruthyPos, len(c.currentInstructions()))
 		if node.Alternative == nil {
 			c.emit(my_code.OpNull)
 		} else {
-			err = c.Compile(node.Alternative)
+			err = c.compileBlockExpression(node.Alternative)
 			if err != nil {
 				return err
 			}
-			if c.isLastInstruction(my_code.OpPop) {
-				c.removeLastInstruction()
-			}
 		}
 		// change OpJump operands after we knew where alternative ins ends
-		c.replaceOperands(jumpPos, len(c.instructions))
+		c.replaceOperands(jumpPos, len(c.currentInstructions()))
+		// either branch leaves one value, even if it ends with break or continue
+		c.setStackDepth(branchDepth + 1)
+	case *my_ast.ForExpression:
+		return c.compileForLoop(node)
+	case *my_ast.WhileExpression:
+		return c.compileWhileLoop(node)
+	case *my_ast.DoWhileExpression:
+		return c.compileDoWhileLoop(node)
+	case *my_ast.TryExpression:
+		return c.compileTry(node)
 	case *my_ast.PrefixExpression:
 		err := c.Compile(node.Right)
 		if err != nil {
@@ -116,10 +220,20 @@ func (c *Compiler) Compile(node my_ast.Node) error {
 			c.emit(my_code.OpMinus)
 		case "!":
 			c.emit(my_code.OpBang)
+		case "~":
+			c.emit(my_code.OpBitNot)
 		default:
 			return fmt.Errorf("unknown prefix operator: %s", node.Operator)
 		}
+	case *my_ast.AssignExpression:
+		return c.compileAssign(node)
 	case *my_ast.InfixExpression:
+		switch node.Operator {
+		case my_ast.INOP_REASSIGN:
+			return c.compileReassign(node)
+		case my_ast.INOP_AND, my_ast.INOP_OR:
+			return c.compileLogical(node)
+		}
 		var first my_ast.Expression
 		var second my_ast.Expression
 		if isNodeReversed(node.Operator) {
@@ -147,6 +261,22 @@ func (c *Compiler) Compile(node my_ast.Node) error {
 			c.emit(my_code.OpMul)
 		case "/":
 			c.emit(my_code.OpDiv)
+		case "%":
+			c.emit(my_code.OpMod)
+		case "**":
+			c.emit(my_code.OpPow)
+		case "//":
+			c.emit(my_code.OpFloorDiv)
+		case "&":
+			c.emit(my_code.OpBitAnd)
+		case "|":
+			c.emit(my_code.OpBitOr)
+		case "^":
+			c.emit(my_code.OpBitXor)
+		case "<<":
+			c.emit(my_code.OpShiftLeft)
+		case ">>":
+			c.emit(my_code.OpShiftRight)
 		case "<":
 			fallthrough
 		case ">":
@@ -188,15 +318,287 @@ func (c *Compiler) Compile(node my_ast.Node) error {
 		if !ok {
 			return fmt.Errorf("undefined variable: %s", node.String())
 		}
-		c.emit(my_code.OpGetGlobal, sym.Index)
+		c.loadSymbol(sym)
 	case *my_ast.Null:
 		c.emit(my_code.OpNull)
+	case *my_ast.ArrayExpression:
+		for _, el := range node.Elements {
+			err := c.Compile(el)
+			if err != nil {
+				return err
+			}
+		}
+		c.emit(my_code.OpArray, len(node.Elements))
+	case *my_ast.HashExpression:
+		for _, key := range node.Keys {
+			// identifiers as keys are taken as strings, the same as my_evaluator/eval_hash.go
+			var keyNode my_ast.Node = key
+			if ident, ok := key.(*my_ast.Identifier); ok {
+				keyNode = &my_ast.StringExpression{Value: ident.Value}
+			}
+			err := c.Compile(keyNode)
+			if err != nil {
+				return err
+			}
+			err = c.Compile(node.Pairs[key])
+			if err != nil {
+				return err
+			}
+		}
+		c.emit(my_code.OpHash, len(node.Keys)*2)
+	case *my_ast.IndexExpression:
+		return c.compileIndexExpression(node)
+	case *my_ast.Function:
+		c.enterScope()
+		if node.Name != "" && !assignsTo(node.Body, node.Name) {
+			c.symbolTable.DefineFunctionName(node.Name)
+		}
+		for _, param := range node.Parameters {
+			c.symbolTable.Define(param.Value)
+		}
+		err := c.Compile(node.Body)
+		if err != nil {
+			c.leaveScope()
+			return err
+		}
+		// implicit return of the last expression value, or null if there is none
+		if c.isLastInstruction(my_code.OpPop) {
+			c.replaceLastInstruction(my_code.OpReturnValue)
+		}
+		if !c.isLastInstruction(my_code.OpReturnValue) {
+			c.emit(my_code.OpReturn)
+		}
+		freeSymbols := c.symbolTable.FreeSymbols
+		numLocals := c.symbolTable.NumDefinitions()
+		lines := c.scopes[c.scopeIndex].lines
+		instructions := c.leaveScope()
+		// push cells of captured bindings in the enclosing scope for OpClosure to collect
+		for _, sym := range freeSymbols {
+			c.captureSymbol(sym)
+		}
+		compiledFn := &my_object.CompiledFunction{
+			Instructions:  instructions,
+			NumLocals:     numLocals,
+			NumParameters: len(node.Parameters),
+			Name:          node.Name,
+			Lines:         lines,
+			Module:        c.module,
+		}
+		c.emit(my_code.OpClosure, c.addConstant(compiledFn), len(freeSymbols))
+	case *my_ast.CallExpression:
+		err := c.Compile(node.Function)
+		if err != nil {
+			return err
+		}
+		for _, arg := range node.Arguments {
+			err = c.Compile(arg)
+			if err != nil {
+				return err
+			}
+		}
+		c.emit(my_code.OpCall, len(node.Arguments))
+	}
+	return nil
+}
+
+// compileReassign: set the value to the existing binding,
+// leaving the value on the stack as the result of the expression
+func (c *Compiler) compileReassign(node *my_ast.InfixExpression) error {
+	ident, ok := node.Left.(*my_ast.Identifier)
+	if !ok {
+		return fmt.Errorf("cannot assign to values other than identifier: got=%s", node.Left.String())
+	}
+	sym, ok := c.symbolTable.ResolveBinding(ident.Value)
+	if !ok || sym.Scope == BuiltinScope {
+		return fmt.Errorf("cannot assign to undefined identifier")
+	}
+	err := c.Compile(node.Right)
+	if err != nil {
+		return err
+	}
+	c.storeSymbol(sym)
+	c.loadSymbol(sym)
+	return nil
+}
+
+// compileAssign: compound assignment like a += 1, reading the target before storing to it
+// without evaluating it twice, and leaving the value assigned on the stack
+//
+//	a += b:    <load a> <b> OpAdd <store a> <load a>
+//	a[i] += b: <a> <i> OpDupPair OpIndex <b> OpAdd OpSetIndex
+func (c *Compiler) compileAssign(node *my_ast.AssignExpression) error {
+	op, ok := assignOpcodes[node.Operator]
+	if !ok {
+		return fmt.Errorf("unknown operator %s", node.Operator)
+	}
+	switch target := node.Target.(type) {
+	case *my_ast.Identifier:
+		sym, ok := c.symbolTable.ResolveBinding(target.Value)
+		if !ok || sym.Scope == BuiltinScope {
+			return fmt.Errorf("cannot assign to undefined identifier")
+		}
+		c.loadSymbol(sym)
+		if err := c.Compile(node.Value); err != nil {
+			return err
+		}
+		c.emit(op)
+		c.storeSymbol(sym)
+		c.loadSymbol(sym)
+	case *my_ast.IndexExpression:
+		if err := c.Compile(target.Left); err != nil {
+			return err
+		}
+		if err := c.Compile(target.StartIndex); err != nil {
+			return err
+		}
+		c.emit(my_code.OpDupPair)
+		c.emit(my_code.OpIndex)
+		if err := c.Compile(node.Value); err != nil {
+			return err
+		}
+		c.emit(op)
+		c.emit(my_code.OpSetIndex)
+	default:
+		return fmt.Errorf("cannot assign to %s", node.Target.String())
+	}
+	return nil
+}
+
+// assignOpcodes: operators of compound assignments
+var assignOpcodes = map[my_ast.InfixOperator]my_code.Opcode{
+	my_ast.INOP_PLUS:     my_code.OpAdd,
+	my_ast.INOP_MINUS:    my_code.OpSub,
+	my_ast.INOP_ASTERISK: my_code.OpMul,
+	my_ast.INOP_SLASH:    my_code.OpDiv,
+	my_ast.INOP_PERCENT:  my_code.OpMod,
+}
+
+// compileLogical: && and || yield booleans like my_evaluator, skipping the right side once the left side decides
+//
+//	a && b: <a> OpJumpNotTruthy false <b> OpBang OpBang OpJump end
+//	        false: OpFalse
+//	        end:
+//
+//	a || b: <a> OpJumpNotTruthy right OpTrue OpJump end
+//	        right: <b> OpBang OpBang
+//	        end:
+func (c *Compiler) compileLogical(node *my_ast.InfixExpression) error {
+	err := c.Compile(node.Left)
+	if err != nil {
+		return err
+	}
+	jumpNotTruthyPos := c.emit(my_code.OpJumpNotTruthy, 0)
+	depth := c.stackDepth()
+	if node.Operator == my_ast.INOP_OR {
+		c.emit(my_code.OpTrue)
+	} else if err := c.compileBoolean(node.Right); err != nil {
+		return err
+	}
+	jumpPos := c.emit(my_code.OpJump, 0)
+	c.setStackDepth(depth)
+	c.replaceOperands(jumpNotTruthyPos, len(c.currentInstructions()))
+	if node.Operator == my_ast.INOP_OR {
+		if err := c.compileBoolean(node.Right); err != nil {
+			return err
+		}
+	} else {
+		c.emit(my_code.OpFalse)
 	}
+	c.replaceOperands(jumpPos, len(c.currentInstructions()))
+	return nil
+}
+
+// compileBoolean: turn the value of node into a boolean by its truthiness, negating it twice
+func (c *Compiler) compileBoolean(node my_ast.Expression) error {
+	err := c.Compile(node)
+	if err != nil {
+		return err
+	}
+	c.emit(my_code.OpBang)
+	c.emit(my_code.OpBang)
+	return nil
+}
+
+// compileIndexExpression: plain indexing like a[1] goes with OpIndex,
+// others like a[1:], a[::-1] or a[] go with OpSlice
+func (c *Compiler) compileIndexExpression(node *my_ast.IndexExpression) error {
+	err := c.Compile(node.Left)
+	if err != nil {
+		return err
+	}
+	isRange := node.IsSetEndIndex || node.IsSetStride
+	if node.StartIndex != nil && !isRange {
+		err = c.Compile(node.StartIndex)
+		if err != nil {
+			return err
+		}
+		c.emit(my_code.OpIndex)
+		return nil
+	}
+	flags := 0
+	if isRange {
+		flags |= my_code.SliceIsRange
+	}
+	for _, part := range []struct {
+		expr my_ast.Expression
+		flag int
+	}{
+		{node.StartIndex, my_code.SliceHasStart},
+		{node.EndIndex, my_code.SliceHasEnd},
+		{node.Stride, my_code.SliceHasStride},
+	} {
+		if part.expr == nil {
+			continue
+		}
+		err = c.Compile(part.expr)
+		if err != nil {
+			return err
+		}
+		flags |= part.flag
+	}
+	c.emit(my_code.OpSlice, flags)
 	return nil
 }
 
 func (c *Compiler) ByteCode() *ByteCode {
-	return &ByteCode{Instructions: c.instructions, Constants: c.constants}
+	return &ByteCode{
+		Instructions: c.currentInstructions(),
+		Constants:    c.constants,
+		Debug: &DebugInfo{
+			Globals: c.symbolTable.DefinitionNames(),
+			Lines:   c.scopes[c.scopeIndex].lines,
+		},
+	}
+}
+
+// compileBlockExpression: compile a block in its own scope,
+// leaving the value of its last statement on the stack
+func (c *Compiler) compileBlockExpression(block *my_ast.BlockStatement) error {
+	c.enterBlockScope()
+	defer c.leaveBlockScope()
+	return c.compileBlockValue(block)
+}
+
+// compileBlockValue: compile a block in the current scope whose last statement is the value of an expression,
+// leaving exactly one value on the stack
+func (c *Compiler) compileBlockValue(block *my_ast.BlockStatement) error {
+	if block == nil {
+		c.emit(my_code.OpNull)
+		return nil
+	}
+	err := c.Compile(block)
+	if err != nil {
+		return err
+	}
+	// remove last OpPop because block statement emitted one and the block is used as an expression,
+	// which means it should leave one and only one expression value
+	if c.isLastInstruction(my_code.OpPop) {
+		c.removeLastInstruction()
+	} else if !c.isLastInstruction(my_code.OpReturnValue) {
+		// empty block or block ending with a let statement produces nothing
+		c.emit(my_code.OpNull)
+	}
+	return nil
 }
 
 // addConstant: add to the constant pool and return index of the newly added item as identifier
@@ -206,13 +608,100 @@ func (c *Compiler) addConstant(obj my_object.Object) int {
 }
 
 func (c *Compiler) emit(op my_code.Opcode, operands ...int) (posNewIns int) {
-	posNewIns = len(c.instructions)
+	posNewIns = len(c.currentInstructions())
 	ins := my_code.Make(op, operands...)
-	c.instructions = append(c.instructions, ins...)
+	scope := &c.scopes[c.scopeIndex]
+	scope.instructions = append(scope.instructions, ins...)
+	scope.lines.Add(posNewIns, c.position)
 	c.setLastInstruction(posNewIns, op)
+	pops, pushes := my_code.StackEffect(op, operands)
+	scope.stackDepth += pushes - pops
 	return posNewIns
 }
 
+func (c *Compiler) stackDepth() int {
+	return c.scopes[c.scopeIndex].stackDepth
+}
+
+// setStackDepth: reset stack depth where branches of control flow meet
+func (c *Compiler) setStackDepth(depth int) {
+	c.scopes[c.scopeIndex].stackDepth = depth
+}
+
+// popTo: pop values until the stack depth is back to depth
+func (c *Compiler) popTo(depth int) {
+	for c.stackDepth() > depth {
+		c.emit(my_code.OpPop)
+	}
+}
+
+func (c *Compiler) currentInstructions() my_code.Instructions {
+	return c.scopes[c.scopeIndex].instructions
+}
+
+func (c *Compiler) enterScope() {
+	c.scopes = append(c.scopes, newCompilationScope())
+	c.scopeIndex++
+	c.symbolTable = NewEnclosedSymbolTable(c.symbolTable)
+}
+
+func (c *Compiler) leaveScope() my_code.Instructions {
+	instructions := c.currentInstructions()
+	c.scopes = c.scopes[:len(c.scopes)-1]
+	c.scopeIndex--
+	c.symbolTable = c.symbolTable.Outer
+	return instructions
+}
+
+// enterBlockScope: confine bindings declared in if or loop bodies the same way
+// my_object.NewEnclosedEnvironment does for the evaluator
+func (c *Compiler) enterBlockScope() {
+	c.symbolTable = NewBlockSymbolTable(c.symbolTable)
+}
+
+func (c *Compiler) leaveBlockScope() {
+	c.symbolTable = c.symbolTable.Outer
+}
+
+func (c *Compiler) loadSymbol(sym Symbol) {
+	switch sym.Scope {
+	case GlobalScope:
+		c.emit(my_code.OpGetGlobal, sym.Index)
+	case LocalScope:
+		c.emit(my_code.OpGetLocal, sym.Index)
+	case FreeScope:
+		c.emit(my_code.OpGetFree, sym.Index)
+	case FunctionScope:
+		c.emit(my_code.OpCurrentClosure)
+	case BuiltinScope:
+		c.emit(my_code.OpGetBuiltin, sym.Index)
+	}
+}
+
+func (c *Compiler) storeSymbol(sym Symbol) {
+	switch sym.Scope {
+	case GlobalScope:
+		c.emit(my_code.OpSetGlobal, sym.Index)
+	case LocalScope:
+		c.emit(my_code.OpSetLocal, sym.Index)
+	case FreeScope:
+		c.emit(my_code.OpSetFree, sym.Index)
+	}
+}
+
+// captureSymbol: push what a new closure keeps for sym, the cell shared with the enclosing frame or closure
+// for bindings that can be assigned, or the enclosing closure itself when captured by its name
+func (c *Compiler) captureSymbol(sym Symbol) {
+	switch sym.Scope {
+	case LocalScope:
+		c.emit(my_code.OpCaptureLocal, sym.Index)
+	case FreeScope:
+		c.emit(my_code.OpCaptureFree, sym.Index)
+	default:
+		c.loadSymbol(sym)
+	}
+}
+
 func isNodeReversed(operator my_ast.InfixOperator) bool {
 	switch operator {
 	case "<=":
@@ -225,32 +714,45 @@ func isNodeReversed(operator my_ast.InfixOperator) bool {
 }
 
 func (c *Compiler) setLastInstruction(position int, op my_code.Opcode) {
-	c.trackedInstructions[0] = c.trackedInstructions[1]
-	c.trackedInstructions[1] = &EmittedInstruction{Position: position, OpCode: op}
+	tracked := &c.scopes[c.scopeIndex].trackedInstructions
+	tracked[0] = tracked[1]
+	tracked[1] = &EmittedInstruction{Position: position, OpCode: op, stackDepth: c.stackDepth()}
 }
 
 func (c *Compiler) isLastInstruction(op my_code.Opcode) bool {
-	if c.trackedInstructions[1] == nil {
+	last := c.scopes[c.scopeIndex].trackedInstructions[1]
+	if last == nil {
 		return false
 	}
-	return c.trackedInstructions[1].OpCode == op
+	return last.OpCode == op
 }
 
 func (c *Compiler) removeLastInstruction() {
 	// should panic if illegal removing happens in compiler
-	c.instructions = c.instructions[:c.trackedInstructions[1].Position]
-	c.trackedInstructions[1] = c.trackedInstructions[0]
-	c.trackedInstructions[0] = nil
+	scope := &c.scopes[c.scopeIndex]
+	scope.instructions = scope.instructions[:scope.trackedInstructions[1].Position]
+	scope.lines.Truncate(scope.trackedInstructions[1].Position)
+	scope.stackDepth = scope.trackedInstructions[1].stackDepth
+	scope.trackedInstructions[1] = scope.trackedInstructions[0]
+	scope.trackedInstructions[0] = nil
+}
+
+// replaceLastInstruction: replace the last instruction with an op code having the same operand widths
+func (c *Compiler) replaceLastInstruction(op my_code.Opcode, operands ...int) {
+	last := c.scopes[c.scopeIndex].trackedInstructions[1]
+	c.replaceInstruction(last.Position, op, operands...)
+	last.OpCode = op
 }
 
 func (c *Compiler) replaceOperands(pos int, operands ...int) {
-	op := my_code.Opcode(c.instructions[pos])
+	op := my_code.Opcode(c.currentInstructions()[pos])
 	c.replaceInstruction(pos, op, operands...)
 }
 
 func (c *Compiler) replaceInstruction(pos int, op my_code.Opcode, operands ...int) {
 	ins := my_code.Make(op, operands...)
+	instructions := c.currentInstructions()
 	for i := 0; i < len(ins); i++ {
-		c.instructions[pos+i] = ins[i]
+		instructions[pos+i] = ins[i]
 	}
 }
@@ -0,0 +1,101 @@
+package my_compiler
+
+import (
+	"errors"
+	"fmt"
+	"monkey/my_ast"
+	"monkey/my_code"
+	"monkey/my_module"
+	"monkey/my_object"
+	"sort"
+	"strings"
+)
+
+// SetLoader: let import statements load modules with loader
+func (c *Compiler) SetLoader(loader *my_module.Loader) {
+	c.loader = loader
+}
+
+// compileImport: compile the module the first time it is imported into a function run by OpImport,
+// then bind the namespace it returns to the name of the import
+func (c *Compiler) compileImport(node *my_ast.ImportStatement) error {
+	if c.loader == nil {
+		return fmt.Errorf("cannot import %q: no module loader", node.Path)
+	}
+	path, err := c.loader.Resolve(node.Path)
+	if err != nil {
+		return err
+	}
+	module, ok := c.symbolTable.ResolveModule(path)
+	if !ok {
+		module, err = c.compileModule(path)
+		if err != nil {
+			return err
+		}
+	}
+	c.emit(my_code.OpImport, module.Index, module.Constant)
+	// keep the namespace so that later imports skip running the module
+	c.emit(my_code.OpSetGlobal, module.Index)
+	c.emit(my_code.OpGetGlobal, module.Index)
+	c.storeSymbol(c.symbolTable.Define(node.Ident.Value))
+	return nil
+}
+
+// compileModule: compile the top level of a module into a function
+// setting its bindings in globals of its own and returning them as a hash
+func (c *Compiler) compileModule(path string) (ModuleSymbol, error) {
+	program, err := c.loader.Enter(path)
+	if err != nil {
+		return ModuleSymbol{}, err
+	}
+	defer c.loader.Leave()
+
+	importing, importingModule := c.symbolTable, c.module
+	c.scopes = append(c.scopes, newCompilationScope())
+	c.scopeIndex++
+	c.symbolTable = NewModuleSymbolTable(importing)
+	c.module = path
+	defer func() {
+		c.scopes = c.scopes[:len(c.scopes)-1]
+		c.scopeIndex--
+		c.symbolTable = importing
+		c.module = importingModule
+	}()
+
+	if err := c.Compile(program); err != nil {
+		// errors of modules imported by this one are located already
+		var moduleErr *my_module.Error
+		if errors.As(err, &moduleErr) {
+			return ModuleSymbol{}, err
+		}
+		return ModuleSymbol{}, &my_module.Error{Path: path, Err: err}
+	}
+	names := c.symbolTable.exportedNames()
+	for _, name := range names {
+		c.emit(my_code.OpConstant, c.addConstant(&my_object.String{Value: name}))
+		sym, _ := c.symbolTable.Resolve(name)
+		c.loadSymbol(sym)
+	}
+	c.emit(my_code.OpHash, 2*len(names))
+	c.emit(my_code.OpReturnValue)
+
+	compiledFn := &my_object.CompiledFunction{
+		Instructions: c.currentInstructions(),
+		Name:         path,
+		Lines:        c.scopes[c.scopeIndex].lines,
+		Module:       path,
+	}
+	return c.symbolTable.DefineModule(path, c.addConstant(compiledFn)), nil
+}
+
+// exportedNames: sorted names defined at the top level of a module, hidden names excluded
+func (s *SymbolTable) exportedNames() []string {
+	names := []string{}
+	for name := range s.store {
+		if !strings.HasPrefix(name, "@") {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
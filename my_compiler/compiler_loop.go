@@ -0,0 +1,166 @@
+package my_compiler
+
+import (
+	"monkey/my_ast"
+	"monkey/my_code"
+)
+
+// Loops are expressions yielding the value of the last iteration completed without break or continue,
+// or null if there is none, the same as my_evaluator/eval_loop.go.
+// Declarations in loops are confined in one block scope shared by init, test, update and body.
+
+// compileForLoop:
+//
+//	<init>
+//	start: <test> OpJumpNotTruthy end
+//	       <body> set result
+//	continue: <update> OpJump start
+//	end: get result
+func (c *Compiler) compileForLoop(node *my_ast.ForExpression) error {
+	c.enterBlockScope()
+	defer c.leaveBlockScope()
+	result := c.initLoopResult()
+	if node.InitStmt != nil {
+		err := c.Compile(node.InitStmt)
+		if err != nil {
+			return err
+		}
+	}
+	startPos := len(c.currentInstructions())
+	jumpNotTruthyPos, err := c.compileLoopTest(node.TestExpr)
+	if err != nil {
+		return err
+	}
+	loop, err := c.compileLoopBody(node.Body, result)
+	if err != nil {
+		return err
+	}
+	continuePos := len(c.currentInstructions())
+	if node.UpdateStmt != nil {
+		err = c.Compile(node.UpdateStmt)
+		if err != nil {
+			return err
+		}
+	}
+	c.emit(my_code.OpJump, startPos)
+	c.finishLoop(loop, jumpNotTruthyPos, continuePos, result)
+	return nil
+}
+
+// compileWhileLoop:
+//
+//	start, continue: <test> OpJumpNotTruthy end
+//	                 <body> set result OpJump start
+//	end: get result
+func (c *Compiler) compileWhileLoop(node *my_ast.WhileExpression) error {
+	c.enterBlockScope()
+	defer c.leaveBlockScope()
+	result := c.initLoopResult()
+	startPos := len(c.currentInstructions())
+	jumpNotTruthyPos, err := c.compileLoopTest(node.TestExpr)
+	if err != nil {
+		return err
+	}
+	loop, err := c.compileLoopBody(node.Body, result)
+	if err != nil {
+		return err
+	}
+	c.emit(my_code.OpJump, startPos)
+	c.finishLoop(loop, jumpNotTruthyPos, startPos, result)
+	return nil
+}
+
+// compileDoWhileLoop:
+//
+//	start: <body> set result
+//	continue: <test> OpJumpNotTruthy end
+//	          OpJump start
+//	end: get result
+func (c *Compiler) compileDoWhileLoop(node *my_ast.DoWhileExpression) error {
+	c.enterBlockScope()
+	defer c.leaveBlockScope()
+	result := c.initLoopResult()
+	startPos := len(c.currentInstructions())
+	loop, err := c.compileLoopBody(node.Body, result)
+	if err != nil {
+		return err
+	}
+	continuePos := len(c.currentInstructions())
+	jumpNotTruthyPos, err := c.compileLoopTest(node.TestExpr)
+	if err != nil {
+		return err
+	}
+	c.emit(my_code.OpJump, startPos)
+	c.finishLoop(loop, jumpNotTruthyPos, continuePos, result)
+	return nil
+}
+
+// initLoopResult: define the hidden loop result in the loop scope and initialize it with null
+func (c *Compiler) initLoopResult() Symbol {
+	result := c.symbolTable.Define(loopResultName)
+	c.emit(my_code.OpNull)
+	c.storeSymbol(result)
+	return result
+}
+
+// compileLoopTest: return position of OpJumpNotTruthy to be back-patched,
+// or -1 if there is no test which means looping forever
+func (c *Compiler) compileLoopTest(test my_ast.Expression) (int, error) {
+	if test == nil {
+		return -1, nil
+	}
+	err := c.Compile(test)
+	if err != nil {
+		return -1, err
+	}
+	return c.emit(my_code.OpJumpNotTruthy, 0), nil
+}
+
+func (c *Compiler) compileLoopBody(body *my_ast.BlockStatement, result Symbol) (*LoopContext, error) {
+	loop := c.enterLoop()
+	err := c.compileBlockValue(body)
+	c.leaveLoop()
+	if err != nil {
+		return nil, err
+	}
+	c.storeSymbol(result)
+	return loop, nil
+}
+
+// finishLoop: back-patch jumps to the end of the loop and to where continue should go,
+// then leave the loop result on the stack
+func (c *Compiler) finishLoop(loop *LoopContext, jumpNotTruthyPos, continuePos int, result Symbol) {
+	endPos := len(c.currentInstructions())
+	if jumpNotTruthyPos >= 0 {
+		c.replaceOperands(jumpNotTruthyPos, endPos)
+	}
+	for _, pos := range loop.breakJumps {
+		c.replaceOperands(pos, endPos)
+	}
+	for _, pos := range loop.continueJumps {
+		c.replaceOperands(pos, continuePos)
+	}
+	c.setStackDepth(loop.stackDepth)
+	c.loadSymbol(result)
+}
+
+func (c *Compiler) enterLoop() *LoopContext {
+	scope := &c.scopes[c.scopeIndex]
+	loop := &LoopContext{breakJumps: []int{}, continueJumps: []int{}, stackDepth: c.stackDepth(), tries: len(scope.tries)}
+	scope.loops = append(scope.loops, loop)
+	return loop
+}
+
+func (c *Compiler) leaveLoop() {
+	scope := &c.scopes[c.scopeIndex]
+	scope.loops = scope.loops[:len(scope.loops)-1]
+}
+
+// currentLoop: innermost loop in the function being compiled, nil if not in a loop
+func (c *Compiler) currentLoop() *LoopContext {
+	loops := c.scopes[c.scopeIndex].loops
+	if len(loops) == 0 {
+		return nil
+	}
+	return loops[len(loops)-1]
+}
@@ -73,6 +73,44 @@ func TestIntegerArithmetic(t *testing.T) {
 	runCompilerTests(t, tests)
 }
 
+func TestArithmeticBitwiseExpressions(t *testing.T) {
+	tests := []*compilerTestCase{}
+	for _, tt := range []struct {
+		input string
+		op    my_code.Opcode
+	}{
+		{"1%2", my_code.OpMod},
+		{"1**2", my_code.OpPow},
+		{"1//2", my_code.OpFloorDiv},
+		{"1&2", my_code.OpBitAnd},
+		{"1|2", my_code.OpBitOr},
+		{"1^2", my_code.OpBitXor},
+		{"1<<2", my_code.OpShiftLeft},
+		{"1>>2", my_code.OpShiftRight},
+	} {
+		tests = append(tests, &compilerTestCase{
+			tt.input,
+			[]any{1, 2},
+			[]my_code.Instructions{
+				my_code.Make(my_code.OpConstant, 0),
+				my_code.Make(my_code.OpConstant, 1),
+				my_code.Make(tt.op),
+				my_code.Make(my_code.OpPop),
+			},
+		})
+	}
+	tests = append(tests, &compilerTestCase{
+		"~2",
+		[]any{2},
+		[]my_code.Instructions{
+			my_code.Make(my_code.OpConstant, 0),
+			my_code.Make(my_code.OpBitNot),
+			my_code.Make(my_code.OpPop),
+		},
+	})
+	runCompilerTests(t, tests)
+}
+
 func TestFloatStringType(t *testing.T) {
 	tests := []*compilerTestCase{
 		{
@@ -126,6 +164,56 @@ func TestBooleanExpressions(t *testing.T) {
 	runCompilerTests(t, tests)
 }
 
+func TestLogicalExpressions(t *testing.T) {
+	tests := []*compilerTestCase{
+		{
+			"true && false",
+			[]any{},
+			[]my_code.Instructions{
+				// 0000
+				my_code.Make(my_code.OpTrue),
+				// 0001
+				my_code.Make(my_code.OpJumpNotTruthy, 10),
+				// 0004
+				my_code.Make(my_code.OpFalse),
+				// 0005
+				my_code.Make(my_code.OpBang),
+				// 0006
+				my_code.Make(my_code.OpBang),
+				// 0007
+				my_code.Make(my_code.OpJump, 11),
+				// 0010
+				my_code.Make(my_code.OpFalse),
+				// 0011
+				my_code.Make(my_code.OpPop),
+			},
+		},
+		{
+			"true || false",
+			[]any{},
+			[]my_code.Instructions{
+				// 0000
+				my_code.Make(my_code.OpTrue),
+				// 0001
+				my_code.Make(my_code.OpJumpNotTruthy, 8),
+				// 0004
+				my_code.Make(my_code.OpTrue),
+				// 0005
+				my_code.Make(my_code.OpJump, 11),
+				// 0008
+				my_code.Make(my_code.OpFalse),
+				// 0009
+				my_code.Make(my_code.OpBang),
+				// 0010
+				my_code.Make(my_code.OpBang),
+				// 0011
+				my_code.Make(my_code.OpPop),
+			},
+		},
+	}
+	runCompilerTests(t, tests)
+}
+
 func TestComparisonBooleanExpressions(t *testing.T) {
 	tests := []*compilerTestCase{
 		{
@@ -330,6 +418,757 @@ func TestGlobalLetStatements(t *testing.T) {
 	runCompilerTests(t, tests)
 }
 
+func TestFunctions(t *testing.T) {
+	tests := []*compilerTestCase{
+		{
+			input: `fn() { return 5 + 10 }`,
+			expectedConstants: []any{
+				5,
+				10,
+				[]my_code.Instructions{
+					my_code.Make(my_code.OpConstant, 0),
+					my_code.Make(my_code.OpConstant, 1),
+					my_code.Make(my_code.OpAdd),
+					my_code.Make(my_code.OpReturnValue),
+				},
+			},
+			expectedInstructions: []my_code.Instructions{
+				my_code.Make(my_code.OpClosure, 2, 0),
+				my_code.Make(my_code.OpPop),
+			},
+		},
+		{
+			input: `fn() { 5 + 10 }`,
+			expectedConstants: []any{
+				5,
+				10,
+				[]my_code.Instructions{
+					my_code.Make(my_code.OpConstant, 0),
+					my_code.Make(my_code.OpConstant, 1),
+					my_code.Make(my_code.OpAdd),
+					my_code.Make(my_code.OpReturnValue),
+				},
+			},
+			expectedInstructions: []my_code.Instructions{
+				my_code.Make(my_code.OpClosure, 2, 0),
+				my_code.Make(my_code.OpPop),
+			},
+		},
+		{
+			input: `fn() { }`,
+			expectedConstants: []any{
+				[]my_code.Instructions{
+					my_code.Make(my_code.OpReturn),
+				},
+			},
+			expectedInstructions: []my_code.Instructions{
+				my_code.Make(my_code.OpClosure, 0, 0),
+				my_code.Make(my_code.OpPop),
+			},
+		},
+	}
+	runCompilerTests(t, tests)
+}
+
+func TestFunctionCalls(t *testing.T) {
+	tests := []*compilerTestCase{
+		{
+			input: `fn() { 24 }();`,
+			expectedConstants: []any{
+				24,
+				[]my_code.Instructions{
+					my_code.Make(my_code.OpConstant, 0),
+					my_code.Make(my_code.OpReturnValue),
+				},
+			},
+			expectedInstructions: []my_code.Instructions{
+				my_code.Make(my_code.OpClosure, 1, 0),
+				my_code.Make(my_code.OpCall, 0),
+				my_code.Make(my_code.OpPop),
+			},
+		},
+		{
+			input: `
+			let noArg = fn() { 24 };
+			noArg();
+			`,
+			expectedConstants: []any{
+				24,
+				[]my_code.Instructions{
+					my_code.Make(my_code.OpConstant, 0),
+					my_code.Make(my_code.OpReturnValue),
+				},
+			},
+			expectedInstructions: []my_code.Instructions{
+				my_code.Make(my_code.OpClosure, 1, 0),
+				my_code.Make(my_code.OpSetGlobal, 0),
+				my_code.Make(my_code.OpGetGlobal, 0),
+				my_code.Make(my_code.OpCall, 0),
+				my_code.Make(my_code.OpPop),
+			},
+		},
+	}
+	runCompilerTests(t, tests)
+}
+
+func TestLetStatementScopes(t *testing.T) {
+	tests := []*compilerTestCase{
+		{
+			input: `
+			let num = 55;
+			fn() { num }
+			`,
+			expectedConstants: []any{
+				55,
+				[]my_code.Instructions{
+					my_code.Make(my_code.OpGetGlobal, 0),
+					my_code.Make(my_code.OpReturnValue),
+				},
+			},
+			expectedInstructions: []my_code.Instructions{
+				my_code.Make(my_code.OpConstant, 0),
+				my_code.Make(my_code.OpSetGlobal, 0),
+				my_code.Make(my_code.OpClosure, 1, 0),
+				my_code.Make(my_code.OpPop),
+			},
+		},
+		{
+			input: `
+			fn() {
+				let num = 55;
+				num
+			}
+			`,
+			expectedConstants: []any{
+				55,
+				[]my_code.Instructions{
+					my_code.Make(my_code.OpConstant, 0),
+					my_code.Make(my_code.OpSetLocal, 0),
+					my_code.Make(my_code.OpGetLocal, 0),
+					my_code.Make(my_code.OpReturnValue),
+				},
+			},
+			expectedInstructions: []my_code.Instructions{
+				my_code.Make(my_code.OpClosure, 1, 0),
+				my_code.Make(my_code.OpPop),
+			},
+		},
+		{
+			input: `
+			let a = 1;
+			if (true) { let a = 2; a };
+			a
+			`,
+			expectedConstants: []any{1, 2},
+			expectedInstructions: []my_code.Instructions{
+				// 0000
+				my_code.Make(my_code.OpConstant, 0),
+				// 0003
+				my_code.Make(my_code.OpSetGlobal, 0),
+				// 0006
+				my_code.Make(my_code.OpTrue),
+				// 0007
+				my_code.Make(my_code.OpJumpNotTruthy, 22),
+				// 0010
+				my_code.Make(my_code.OpConstant, 1),
+				// 0013
+				my_code.Make(my_code.OpSetGlobal, 1),
+				// 0016
+				my_code.Make(my_code.OpGetGlobal, 1),
+				// 0019
+				my_code.Make(my_code.OpJump, 23),
+				// 0022
+				my_code.Make(my_code.OpNull),
+				// 0023
+				my_code.Make(my_code.OpPop),
+				// 0024
+				my_code.Make(my_code.OpGetGlobal, 0),
+				// 0027
+				my_code.Make(my_code.OpPop),
+			},
+		},
+	}
+	runCompilerTests(t, tests)
+}
+
+func TestFunctionArguments(t *testing.T) {
+	tests := []*compilerTestCase{
+		{
+			input: `
+			let manyArg = fn(a, b, c) { a; b; c };
+			manyArg(24, 25, 26);
+			`,
+			expectedConstants: []any{
+				[]my_code.Instructions{
+					my_code.Make(my_code.OpGetLocal, 0),
+					my_code.Make(my_code.OpPop),
+					my_code.Make(my_code.OpGetLocal, 1),
+					my_code.Make(my_code.OpPop),
+					my_code.Make(my_code.OpGetLocal, 2),
+					my_code.Make(my_code.OpReturnValue),
+				},
+				24,
+				25,
+				26,
+			},
+			expectedInstructions: []my_code.Instructions{
+				my_code.Make(my_code.OpClosure, 0, 0),
+				my_code.Make(my_code.OpSetGlobal, 0),
+				my_code.Make(my_code.OpGetGlobal, 0),
+				my_code.Make(my_code.OpConstant, 1),
+				my_code.Make(my_code.OpConstant, 2),
+				my_code.Make(my_code.OpConstant, 3),
+				my_code.Make(my_code.OpCall, 3),
+				my_code.Make(my_code.OpPop),
+			},
+		},
+	}
+	runCompilerTests(t, tests)
+}
+
+func TestClosures(t *testing.T) {
+	tests := []*compilerTestCase{
+		{
+			input: `fn(a) { fn(b) { a + b } }`,
+			expectedConstants: []any{
+				[]my_code.Instructions{
+					my_code.Make(my_code.OpGetFree, 0),
+					my_code.Make(my_code.OpGetLocal, 0),
+					my_code.Make(my_code.OpAdd),
+					my_code.Make(my_code.OpReturnValue),
+				},
+				[]my_code.Instructions{
+					my_code.Make(my_code.OpCaptureLocal, 0),
+					my_code.Make(my_code.OpClosure, 0, 1),
+					my_code.Make(my_code.OpReturnValue),
+				},
+			},
+			expectedInstructions: []my_code.Instructions{
+				my_code.Make(my_code.OpClosure, 1, 0),
+				my_code.Make(my_code.OpPop),
+			},
+		},
+		{
+			input: `fn(a) { fn(b) { fn(c) { a + b + c } } }`,
+			expectedConstants: []any{
+				[]my_code.Instructions{
+					my_code.Make(my_code.OpGetFree, 0),
+					my_code.Make(my_code.OpGetFree, 1),
+					my_code.Make(my_code.OpAdd),
+					my_code.Make(my_code.OpGetLocal, 0),
+					my_code.Make(my_code.OpAdd),
+					my_code.Make(my_code.OpReturnValue),
+				},
+				[]my_code.Instructions{
+					my_code.Make(my_code.OpCaptureFree, 0),
+					my_code.Make(my_code.OpCaptureLocal, 0),
+					my_code.Make(my_code.OpClosure, 0, 2),
+					my_code.Make(my_code.OpReturnValue),
+				},
+				[]my_code.Instructions{
+					my_code.Make(my_code.OpCaptureLocal, 0),
+					my_code.Make(my_code.OpClosure, 1, 1),
+					my_code.Make(my_code.OpReturnValue),
+				},
+			},
+			expectedInstructions: []my_code.Instructions{
+				my_code.Make(my_code.OpClosure, 2, 0),
+				my_code.Make(my_code.OpPop),
+			},
+		},
+	}
+	runCompilerTests(t, tests)
+}
+
+func TestRecursiveFunctions(t *testing.T) {
+	tests := []*compilerTestCase{
+		{
+			input: `
+			let countDown = fn(x) { countDown(x - 1); };
+			countDown(1);
+			`,
+			expectedConstants: []any{
+				1,
+				[]my_code.Instructions{
+					my_code.Make(my_code.OpCurrentClosure),
+					my_code.Make(my_code.OpGetLocal, 0),
+					my_code.Make(my_code.OpConstant, 0),
+					my_code.Make(my_code.OpSub),
+					my_code.Make(my_code.OpCall, 1),
+					my_code.Make(my_code.OpReturnValue),
+				},
+				1,
+			},
+			expectedInstructions: []my_code.Instructions{
+				my_code.Make(my_code.OpClosure, 1, 0),
+				my_code.Make(my_code.OpSetGlobal, 0),
+				my_code.Make(my_code.OpGetGlobal, 0),
+				my_code.Make(my_code.OpConstant, 2),
+				my_code.Make(my_code.OpCall, 1),
+				my_code.Make(my_code.OpPop),
+			},
+		},
+	}
+	runCompilerTests(t, tests)
+}
+
+func TestLoops(t *testing.T) {
+	tests := []*compilerTestCase{
+		{
+			input:             `while (true) { break; }`,
+			expectedConstants: []any{},
+			expectedInstructions: []my_code.Instructions{
+				// 0000
+				my_code.Make(my_code.OpNull),
+				// 0001
+				my_code.Make(my_code.OpSetGlobal, 0),
+				// 0004
+				my_code.Make(my_code.OpTrue),
+				// 0005
+				my_code.Make(my_code.OpJumpNotTruthy, 18),
+				// 0008, break
+				my_code.Make(my_code.OpJump, 18),
+				// 0011
+				my_code.Make(my_code.OpNull),
+				// 0012
+				my_code.Make(my_code.OpSetGlobal, 0),
+				// 0015
+				my_code.Make(my_code.OpJump, 4),
+				// 0018
+				my_code.Make(my_code.OpGetGlobal, 0),
+				// 0021
+				my_code.Make(my_code.OpPop),
+			},
+		},
+		{
+			input:             `do { continue; } while (false)`,
+			expectedConstants: []any{},
+			expectedInstructions: []my_code.Instructions{
+				// 0000
+				my_code.Make(my_code.OpNull),
+				// 0001
+				my_code.Make(my_code.OpSetGlobal, 0),
+				// 0004, continue
+				my_code.Make(my_code.OpJump, 11),
+				// 0007
+				my_code.Make(my_code.OpNull),
+				// 0008
+				my_code.Make(my_code.OpSetGlobal, 0),
+				// 0011
+				my_code.Make(my_code.OpFalse),
+				// 0012
+				my_code.Make(my_code.OpJumpNotTruthy, 18),
+				// 0015
+				my_code.Make(my_code.OpJump, 4),
+				// 0018
+				my_code.Make(my_code.OpGetGlobal, 0),
+				// 0021
+				my_code.Make(my_code.OpPop),
+			},
+		},
+		{
+			input: `fn() { for (let i = 1; ; 2) { i } }`,
+			expectedConstants: []any{
+				1,
+				2,
+				[]my_code.Instructions{
+					// 0000
+					my_code.Make(my_code.OpNull),
+					// 0001
+					my_code.Make(my_code.OpSetLocal, 0),
+					// 0003
+					my_code.Make(my_code.OpConstant, 0),
+					// 0006
+					my_code.Make(my_code.OpSetLocal, 1),
+					// 0008
+					my_code.Make(my_code.OpGetLocal, 1),
+					// 0010
+					my_code.Make(my_code.OpSetLocal, 0),
+					// 0012, update
+					my_code.Make(my_code.OpConstant, 1),
+					// 0015
+					my_code.Make(my_code.OpPop),
+					// 0016
+					my_code.Make(my_code.OpJump, 8),
+					// 0019
+					my_code.Make(my_code.OpGetLocal, 0),
+					// 0021
+					my_code.Make(my_code.OpReturnValue),
+				},
+			},
+			expectedInstructions: []my_code.Instructions{
+				my_code.Make(my_code.OpClosure, 2, 0),
+				my_code.Make(my_code.OpPop),
+			},
+		},
+	}
+	runCompilerTests(t, tests)
+}
+
+func TestTryExpressions(t *testing.T) {
+	tests := []*compilerTestCase{
+		{
+			input:             "try { 1 } catch (e) { e }",
+			expectedConstants: []any{1},
+			expectedInstructions: []my_code.Instructions{
+				// 0000
+				my_code.Make(my_code.OpSetupTry, 10),
+				// 0003
+				my_code.Make(my_code.OpConstant, 0),
+				// 0006
+				my_code.Make(my_code.OpPopTry),
+				// 0007
+				my_code.Make(my_code.OpJump, 16),
+				// 0010
+				my_code.Make(my_code.OpSetGlobal, 0),
+				// 0013
+				my_code.Make(my_code.OpGetGlobal, 0),
+				// 0016
+				my_code.Make(my_code.OpPop),
+			},
+		},
+		{
+			input:             "while (true) { try { break; } finally { 1 } }",
+			expectedConstants: []any{1, 1, 1},
+			expectedInstructions: []my_code.Instructions{
+				// 0000
+				my_code.Make(my_code.OpNull),
+				// 0001
+				my_code.Make(my_code.OpSetGlobal, 0),
+				// 0004
+				my_code.Make(my_code.OpTrue),
+				// 0005
+				my_code.Make(my_code.OpJumpNotTruthy, 39),
+				// 0008
+				my_code.Make(my_code.OpSetupTry, 28),
+				// break leaves the try block and runs finally first
+				// 0011
+				my_code.Make(my_code.OpPopTry),
+				// 0012
+				my_code.Make(my_code.OpConstant, 0),
+				// 0015
+				my_code.Make(my_code.OpPop),
+				// 0016
+				my_code.Make(my_code.OpJump, 39),
+				// 0019
+				my_code.Make(my_code.OpNull),
+				// 0020
+				my_code.Make(my_code.OpPopTry),
+				// 0021
+				my_code.Make(my_code.OpConstant, 1),
+				// 0024
+				my_code.Make(my_code.OpPop),
+				// 0025
+				my_code.Make(my_code.OpJump, 33),
+				// the handler runs finally and raises the exception again
+				// 0028
+				my_code.Make(my_code.OpConstant, 2),
+				// 0031
+				my_code.Make(my_code.OpPop),
+				// 0032
+				my_code.Make(my_code.OpThrow),
+				// 0033
+				my_code.Make(my_code.OpSetGlobal, 0),
+				// 0036
+				my_code.Make(my_code.OpJump, 4),
+				// 0039
+				my_code.Make(my_code.OpGetGlobal, 0),
+				// 0042
+				my_code.Make(my_code.OpPop),
+			},
+		},
+	}
+	runCompilerTests(t, tests)
+}
+
+func TestLoopControlOutsideLoop(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"break", "break outside loop"},
+		{"continue", "continue outside loop"},
+		{"if (true) { break; }", "break outside loop"},
+		{"while (true) { fn() { continue; } }", "continue outside loop"},
+	}
+	for _, tt := range tests {
+		compiler := New()
+		err := compiler.Compile(parse(tt.input))
+		assert.EqualError(t, err, tt.expected, "input=%s", tt.input)
+	}
+}
+
+func TestArrayLiterals(t *testing.T) {
+	tests := []*compilerTestCase{
+		{
+			"[]",
+			[]any{},
+			[]my_code.Instructions{
+				my_code.Make(my_code.OpArray, 0),
+				my_code.Make(my_code.OpPop),
+			},
+		},
+		{
+			"[1, 2 + 3]",
+			[]any{1, 2, 3},
+			[]my_code.Instructions{
+				my_code.Make(my_code.OpConstant, 0),
+				my_code.Make(my_code.OpConstant, 1),
+				my_code.Make(my_code.OpConstant, 2),
+				my_code.Make(my_code.OpAdd),
+				my_code.Make(my_code.OpArray, 2),
+				my_code.Make(my_code.OpPop),
+			},
+		},
+	}
+	runCompilerTests(t, tests)
+}
+
+func TestHashLiterals(t *testing.T) {
+	tests := []*compilerTestCase{
+		{
+			"{}",
+			[]any{},
+			[]my_code.Instructions{
+				my_code.Make(my_code.OpHash, 0),
+				my_code.Make(my_code.OpPop),
+			},
+		},
+		{
+			"{foo: 1, 2: 3 * 4}",
+			[]any{"foo", 1, 2, 3, 4},
+			[]my_code.Instructions{
+				my_code.Make(my_code.OpConstant, 0),
+				my_code.Make(my_code.OpConstant, 1),
+				my_code.Make(my_code.OpConstant, 2),
+				my_code.Make(my_code.OpConstant, 3),
+				my_code.Make(my_code.OpConstant, 4),
+				my_code.Make(my_code.OpMul),
+				my_code.Make(my_code.OpHash, 4),
+				my_code.Make(my_code.OpPop),
+			},
+		},
+	}
+	runCompilerTests(t, tests)
+}
+
+func TestIndexExpressions(t *testing.T) {
+	tests := []*compilerTestCase{
+		{
+			"[1, 2][1]",
+			[]any{1, 2, 1},
+			[]my_code.Instructions{
+				my_code.Make(my_code.OpConstant, 0),
+				my_code.Make(my_code.OpConstant, 1),
+				my_code.Make(my_code.OpArray, 2),
+				my_code.Make(my_code.OpConstant, 2),
+				my_code.Make(my_code.OpIndex),
+				my_code.Make(my_code.OpPop),
+			},
+		},
+		{
+			"[][]",
+			[]any{},
+			[]my_code.Instructions{
+				my_code.Make(my_code.OpArray, 0),
+				my_code.Make(my_code.OpSlice, 0),
+				my_code.Make(my_code.OpPop),
+			},
+		},
+		{
+			"[][1:]",
+			[]any{1},
+			[]my_code.Instructions{
+				my_code.Make(my_code.OpArray, 0),
+				my_code.Make(my_code.OpConstant, 0),
+				my_code.Make(my_code.OpSlice, my_code.SliceIsRange|my_code.SliceHasStart),
+				my_code.Make(my_code.OpPop),
+			},
+		},
+		{
+			"[][:2:-1]",
+			[]any{2, 1},
+			[]my_code.Instructions{
+				my_code.Make(my_code.OpArray, 0),
+				my_code.Make(my_code.OpConstant, 0),
+				my_code.Make(my_code.OpConstant, 1),
+				my_code.Make(my_code.OpMinus),
+				my_code.Make(my_code.OpSlice, my_code.SliceIsRange|my_code.SliceHasEnd|my_code.SliceHasStride),
+				my_code.Make(my_code.OpPop),
+			},
+		},
+	}
+	runCompilerTests(t, tests)
+}
+
+func TestReassignExpressions(t *testing.T) {
+	tests := []*compilerTestCase{
+		{
+			input:             `let a = 1; a = 2;`,
+			expectedConstants: []any{1, 2},
+			expectedInstructions: []my_code.Instructions{
+				my_code.Make(my_code.OpConstant, 0),
+				my_code.Make(my_code.OpSetGlobal, 0),
+				my_code.Make(my_code.OpConstant, 1),
+				my_code.Make(my_code.OpSetGlobal, 0),
+				my_code.Make(my_code.OpGetGlobal, 0),
+				my_code.Make(my_code.OpPop),
+			},
+		},
+		{
+			input: `fn(a) { fn() { a = 2 } }`,
+			expectedConstants: []any{
+				2,
+				[]my_code.Instructions{
+					my_code.Make(my_code.OpConstant, 0),
+					my_code.Make(my_code.OpSetFree, 0),
+					my_code.Make(my_code.OpGetFree, 0),
+					my_code.Make(my_code.OpReturnValue),
+				},
+				[]my_code.Instructions{
+					my_code.Make(my_code.OpCaptureLocal, 0),
+					my_code.Make(my_code.OpClosure, 1, 1),
+					my_code.Make(my_code.OpReturnValue),
+				},
+			},
+			expectedInstructions: []my_code.Instructions{
+				my_code.Make(my_code.OpClosure, 2, 0),
+				my_code.Make(my_code.OpPop),
+			},
+		},
+		{
+			input: `let f = fn() { f = 1 }`,
+			expectedConstants: []any{
+				1,
+				[]my_code.Instructions{
+					my_code.Make(my_code.OpConstant, 0),
+					my_code.Make(my_code.OpSetGlobal, 0),
+					my_code.Make(my_code.OpGetGlobal, 0),
+					my_code.Make(my_code.OpReturnValue),
+				},
+			},
+			expectedInstructions: []my_code.Instructions{
+				my_code.Make(my_code.OpClosure, 1, 0),
+				my_code.Make(my_code.OpSetGlobal, 0),
+			},
+		},
+	}
+	runCompilerTests(t, tests)
+}
+
+func TestAssignExpressions(t *testing.T) {
+	tests := []*compilerTestCase{
+		{
+			input:             `let a = 1; a += 2;`,
+			expectedConstants: []any{1, 2},
+			expectedInstructions: []my_code.Instructions{
+				my_code.Make(my_code.OpConstant, 0),
+				my_code.Make(my_code.OpSetGlobal, 0),
+				my_code.Make(my_code.OpGetGlobal, 0),
+				my_code.Make(my_code.OpConstant, 1),
+				my_code.Make(my_code.OpAdd),
+				my_code.Make(my_code.OpSetGlobal, 0),
+				my_code.Make(my_code.OpGetGlobal, 0),
+				my_code.Make(my_code.OpPop),
+			},
+		},
+		{
+			input:             `let a = [1]; a[0]--;`,
+			expectedConstants: []any{1, 0, 1},
+			expectedInstructions: []my_code.Instructions{
+				my_code.Make(my_code.OpConstant, 0),
+				my_code.Make(my_code.OpArray, 1),
+				my_code.Make(my_code.OpSetGlobal, 0),
+				my_code.Make(my_code.OpGetGlobal, 0),
+				my_code.Make(my_code.OpConstant, 1),
+				my_code.Make(my_code.OpDupPair),
+				my_code.Make(my_code.OpIndex),
+				my_code.Make(my_code.OpConstant, 2),
+				my_code.Make(my_code.OpSub),
+				my_code.Make(my_code.OpSetIndex),
+				my_code.Make(my_code.OpPop),
+			},
+		},
+	}
+	runCompilerTests(t, tests)
+}
+
+func TestReassignErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"a = 2", "cannot assign to undefined identifier"},
+		{"fn() { let a = 1; }; a = 2", "cannot assign to undefined identifier"},
+		{"1 = 2", "cannot assign to values other than identifier: got=1"},
+		{"len = 2", "cannot assign to undefined identifier"},
+	}
+	for _, tt := range tests {
+		compiler := New()
+		err := compiler.Compile(parse(tt.input))
+		assert.EqualError(t, err, tt.expected, "input=%s", tt.input)
+	}
+}
+
+func TestBuiltins(t *testing.T) {
+	tests := []*compilerTestCase{
+		{
+			input:             `len([]); append([], 1);`,
+			expectedConstants: []any{1},
+			expectedInstructions: []my_code.Instructions{
+				my_code.Make(my_code.OpGetBuiltin, 0),
+				my_code.Make(my_code.OpArray, 0),
+				my_code.Make(my_code.OpCall, 1),
+				my_code.Make(my_code.OpPop),
+				my_code.Make(my_code.OpGetBuiltin, 1),
+				my_code.Make(my_code.OpArray, 0),
+				my_code.Make(my_code.OpConstant, 0),
+				my_code.Make(my_code.OpCall, 2),
+				my_code.Make(my_code.OpPop),
+			},
+		},
+		{
+			input: `fn() { len([]) }`,
+			expectedConstants: []any{
+				[]my_code.Instructions{
+					my_code.Make(my_code.OpGetBuiltin, 0),
+					my_code.Make(my_code.OpArray, 0),
+					my_code.Make(my_code.OpCall, 1),
+					my_code.Make(my_code.OpReturnValue),
+				},
+			},
+			expectedInstructions: []my_code.Instructions{
+				my_code.Make(my_code.OpClosure, 0, 0),
+				my_code.Make(my_code.OpPop),
+			},
+		},
+	}
+	runCompilerTests(t, tests)
+}
+
+func TestCompilerScopes(t *testing.T) {
+	compiler := New()
+	assert.Equal(t, 0, compiler.scopeIndex)
+	globalSymbolTable := compiler.symbolTable
+	compiler.emit(my_code.OpMul)
+
+	compiler.enterScope()
+	assert.Equal(t, 1, compiler.scopeIndex)
+	assert.Equal(t, globalSymbolTable, compiler.symbolTable.Outer)
+	compiler.emit(my_code.OpSub)
+	assert.Equal(t, 1, len(compiler.currentInstructions()))
+	assert.True(t, compiler.isLastInstruction(my_code.OpSub))
+
+	compiler.leaveScope()
+	assert.Equal(t, 0, compiler.scopeIndex)
+	assert.Equal(t, globalSymbolTable, compiler.symbolTable)
+	assert.True(t, compiler.isLastInstruction(my_code.OpMul))
+
+	compiler.emit(my_code.OpAdd)
+	assert.Equal(t, 2, len(compiler.currentInstructions()))
+	assert.True(t, compiler.isLastInstruction(my_code.OpAdd))
+}
+
 func runCompilerTests(t *testing.T, tests []*compilerTestCase) {
 	t.Helper()
 	for _, test := range tests {
@@ -393,6 +1232,10 @@ func testConstants(t *testing.T, expected []any, actual []my_object.Object) {
 			actualStringObj, ok := actual[idx].(*my_object.String)
 			assert.True(t, ok)
 			assert.EqualValues(t, exp, actualStringObj.Value)
+		case []my_code.Instructions:
+			actualFn, ok := actual[idx].(*my_object.CompiledFunction)
+			assert.True(t, ok, "expecting compiled function, got %s", actual[idx].Type())
+			testInstructions(t, exp, actualFn.Instructions, "constant %d", idx)
 		}
 	}
 }
@@ -0,0 +1,131 @@
+package my_compiler
+
+import (
+	"monkey/my_ast"
+	"monkey/my_code"
+)
+
+// Try expressions yield the value of the try block, or of the catch block once an exception is caught,
+// the same as my_evaluator/eval_try.go. Finally blocks are compiled once for each way out of the try block:
+// completing normally, raising an exception, or jumping out of it with break, continue and return.
+
+// TryContext: a try block whose handler is set up, left by jumping out of it
+type TryContext struct {
+	// finally: nil if the try expression has no finally block
+	finally *my_ast.BlockStatement
+}
+
+// compileTry:
+//
+//	   OpSetupTry handler
+//	   <body> OpPopTry <finally> OpJump end
+//	handler:
+//	   OpSetupTry rethrow                       ; with finally only
+//	   set param <catch> OpPopTry <finally> OpJump end
+//	rethrow:                                    ; with finally only
+//	   <finally> OpThrow
+//	end:
+//
+// without catch, the handler is the rethrow part alone
+func (c *Compiler) compileTry(node *my_ast.TryExpression) error {
+	depth := c.stackDepth()
+	endJumps := []int{}
+	handlerPos := c.emit(my_code.OpSetupTry, 0)
+	err := c.compileTryBlock(node.Finally, func() error {
+		return c.compileBlockExpression(node.Body)
+	})
+	if err != nil {
+		return err
+	}
+	c.setStackDepth(depth + 1)
+	c.emit(my_code.OpPopTry)
+	if err := c.compileFinally(node.Finally); err != nil {
+		return err
+	}
+	endJumps = append(endJumps, c.emit(my_code.OpJump, 0))
+
+	c.replaceOperands(handlerPos, len(c.currentInstructions()))
+	c.setStackDepth(depth + 1)
+	if node.Catch != nil {
+		compileCatch := func() error {
+			c.enterBlockScope()
+			defer c.leaveBlockScope()
+			if node.CatchParam != nil {
+				c.storeSymbol(c.symbolTable.Define(node.CatchParam.Value))
+			} else {
+				c.emit(my_code.OpPop)
+			}
+			return c.compileBlockValue(node.Catch)
+		}
+		if node.Finally == nil {
+			if err := compileCatch(); err != nil {
+				return err
+			}
+		} else {
+			rethrowPos := c.emit(my_code.OpSetupTry, 0)
+			if err := c.compileTryBlock(node.Finally, compileCatch); err != nil {
+				return err
+			}
+			c.setStackDepth(depth + 1)
+			c.emit(my_code.OpPopTry)
+			if err := c.compileFinally(node.Finally); err != nil {
+				return err
+			}
+			endJumps = append(endJumps, c.emit(my_code.OpJump, 0))
+			c.replaceOperands(rethrowPos, len(c.currentInstructions()))
+			c.setStackDepth(depth + 1)
+		}
+	}
+	if node.Finally != nil {
+		if err := c.compileFinally(node.Finally); err != nil {
+			return err
+		}
+		c.emit(my_code.OpThrow)
+	}
+
+	endPos := len(c.currentInstructions())
+	for _, pos := range endJumps {
+		c.replaceOperands(pos, endPos)
+	}
+	c.setStackDepth(depth + 1)
+	return nil
+}
+
+// compileTryBlock: compile code guarded by a handler, which jumps out of it have to leave first
+func (c *Compiler) compileTryBlock(finally *my_ast.BlockStatement, compile func() error) error {
+	scope := &c.scopes[c.scopeIndex]
+	scope.tries = append(scope.tries, &TryContext{finally: finally})
+	err := compile()
+	scope = &c.scopes[c.scopeIndex]
+	scope.tries = scope.tries[:len(scope.tries)-1]
+	return err
+}
+
+// compileFinally: run the finally block for its side effects, if any
+func (c *Compiler) compileFinally(finally *my_ast.BlockStatement) error {
+	if finally == nil {
+		return nil
+	}
+	err := c.compileBlockExpression(finally)
+	if err != nil {
+		return err
+	}
+	c.emit(my_code.OpPop)
+	return nil
+}
+
+// leaveTries: before break, continue or return jumps out of try blocks set up in the function,
+// remove their handlers and run their finally blocks, the innermost first, keeping the first n
+func (c *Compiler) leaveTries(n int) error {
+	tries := c.scopes[c.scopeIndex].tries
+	// finally blocks run outside the try blocks they belong to
+	defer func() { c.scopes[c.scopeIndex].tries = tries }()
+	for i := len(tries) - 1; i >= n; i-- {
+		c.scopes[c.scopeIndex].tries = tries[:i]
+		c.emit(my_code.OpPopTry)
+		if err := c.compileFinally(tries[i].finally); err != nil {
+			return err
+		}
+	}
+	return nil
+}
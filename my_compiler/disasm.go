@@ -0,0 +1,104 @@
+package my_compiler
+
+import (
+	"fmt"
+	"monkey/my_code"
+	"monkey/my_object"
+	"strconv"
+	"strings"
+)
+
+// Disassemble: dump instructions of main and then compiled functions in the order they are referenced,
+// resolving constants, globals, builtins and jump targets
+func (bc *ByteCode) Disassemble() string {
+	sb := &strings.Builder{}
+	sb.WriteString("== main ==\n")
+	sb.WriteString(bc.Instructions.Format(bc.annotate))
+
+	dumped := map[int]bool{}
+	var dumpFunctions func(ins my_code.Instructions)
+	dumpFunctions = func(ins my_code.Instructions) {
+		for _, idx := range referencedFunctions(ins) {
+			fn, ok := bc.constant(idx).(*my_object.CompiledFunction)
+			if !ok || dumped[idx] {
+				continue
+			}
+			dumped[idx] = true
+			sb.WriteString(fmt.Sprintf(
+				"\n== %s (parameters %d, locals %d) ==\n",
+				FunctionName(idx), fn.NumParameters, fn.NumLocals,
+			))
+			sb.WriteString(fn.Instructions.Format(bc.annotate))
+			dumpFunctions(fn.Instructions)
+		}
+	}
+	dumpFunctions(bc.Instructions)
+	return sb.String()
+}
+
+func (bc *ByteCode) annotate(op my_code.Opcode, operands []int) string {
+	switch op {
+	case my_code.OpConstant:
+		return describeConstant(operands[0], bc.constant(operands[0]))
+	case my_code.OpClosure:
+		return fmt.Sprintf("%s, %d free", FunctionName(operands[0]), operands[1])
+	case my_code.OpImport:
+		return FunctionName(operands[1])
+	case my_code.OpGetGlobal, my_code.OpSetGlobal:
+		if bc.Debug != nil && operands[0] < len(bc.Debug.Globals) {
+			return bc.Debug.Globals[operands[0]]
+		}
+	case my_code.OpGetBuiltin:
+		if operands[0] < len(my_object.Builtins) {
+			return my_object.Builtins[operands[0]].Name
+		}
+	}
+	return ""
+}
+
+// constant: nil if idx is out of the constant pool
+func (bc *ByteCode) constant(idx int) my_object.Object {
+	if idx < 0 || idx >= len(bc.Constants) {
+		return nil
+	}
+	return bc.Constants[idx]
+}
+
+func describeConstant(idx int, constant my_object.Object) string {
+	switch constant := constant.(type) {
+	case nil:
+		return "invalid constant"
+	case *my_object.String:
+		return strconv.Quote(constant.Value)
+	case *my_object.CompiledFunction:
+		return FunctionName(idx)
+	default:
+		return constant.String()
+	}
+}
+
+// FunctionName: compiled functions are named after their indices in the constant pool
+func FunctionName(constIdx int) string {
+	return fmt.Sprintf("fn#%d", constIdx)
+}
+
+// referencedFunctions: constant indices of functions referred to by OpClosure or OpImport
+func referencedFunctions(ins my_code.Instructions) []int {
+	indices := []int{}
+	for i := 0; i < len(ins); {
+		def, err := my_code.Lookup(ins[i])
+		if err != nil || i+1+def.OperandsWidth() > len(ins) {
+			i++
+			continue
+		}
+		operands, bytesRead := my_code.ReadOperands(def, ins[i+1:])
+		switch my_code.Opcode(ins[i]) {
+		case my_code.OpClosure:
+			indices = append(indices, operands[0])
+		case my_code.OpImport:
+			indices = append(indices, operands[1])
+		}
+		i += 1 + bytesRead
+	}
+	return indices
+}
@@ -0,0 +1,72 @@
+package my_compiler
+
+import (
+	"monkey/my_code"
+	"monkey/my_object"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDisassemble(t *testing.T) {
+	input := `
+	let greeting = "hi";
+	let adder = fn(x) { fn(y) { if (y > 0) { x + y } else { len(greeting) } } };
+	adder(1.5)(2);
+	`
+	comp := New()
+	assert.NoError(t, comp.Compile(parse(input)))
+	expected := `== main ==
+0000 OpConstant 0             ; "hi"
+0003 OpSetGlobal 0            ; greeting
+0006 OpClosure 3 0            ; fn#3, 0 free
+0010 OpSetGlobal 1            ; adder
+0013 OpGetGlobal 1            ; adder
+0016 OpConstant 4             ; 1.5
+0019 OpCall 1
+0021 OpConstant 5             ; 2
+0024 OpCall 1
+0026 OpPop
+
+== fn#3 (parameters 1, locals 1) ==
+0000 OpCaptureLocal 0
+0002 OpClosure 2 1            ; fn#2, 1 free
+0006 OpReturnValue
+
+== fn#2 (parameters 1, locals 1) ==
+0000 OpGetLocal 0
+0002 OpConstant 1             ; 0
+0005 OpGreaterThan
+0006 OpJumpNotTruthy L0
+0009 OpGetFree 0
+0011 OpGetLocal 0
+0013 OpAdd
+0014 OpJump L1
+L0:
+0017 OpGetBuiltin 0           ; len
+0019 OpGetGlobal 0            ; greeting
+0022 OpCall 1
+L1:
+0024 OpReturnValue
+`
+	assert.Equal(t, expected, comp.ByteCode().Disassemble())
+}
+
+func TestDisassembleWithoutDebugInfo(t *testing.T) {
+	bc := &ByteCode{
+		Instructions: my_code.Concat([]my_code.Instructions{
+			my_code.Make(my_code.OpConstant, 0),
+			my_code.Make(my_code.OpSetGlobal, 0),
+			my_code.Make(my_code.OpConstant, 1),
+			my_code.Make(my_code.OpClosure, 9, 0),
+		}),
+		Constants: []my_object.Object{&my_object.Integer{Value: 1}},
+	}
+	expected := `== main ==
+0000 OpConstant 0             ; 1
+0003 OpSetGlobal 0
+0006 OpConstant 1             ; invalid constant
+0009 OpClosure 9 0            ; fn#9, 0 free
+`
+	assert.Equal(t, expected, bc.Disassemble())
+}
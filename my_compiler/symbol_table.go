@@ -1,9 +1,15 @@
 package my_compiler
 
+import "monkey/my_object"
+
 type SymbolScope string
 
 const (
-	GlobalScope SymbolScope = "GLOBAL"
+	GlobalScope   SymbolScope = "GLOBAL"
+	LocalScope    SymbolScope = "LOCAL"
+	FreeScope     SymbolScope = "FREE"
+	FunctionScope SymbolScope = "FUNCTION"
+	BuiltinScope  SymbolScope = "BUILTIN"
 )
 
 type Symbol struct {
@@ -13,29 +19,226 @@ type Symbol struct {
 }
 
 type SymbolTable struct {
+	Outer          *SymbolTable
 	store          map[string]Symbol
 	numDefinitions int
+	// owner: the table allocating indices for definitions in this table;
+	// itself for global and function tables, the enclosing function or global table for blocks
+	owner *SymbolTable
+	// FreeSymbols: original symbols from enclosing functions captured by this function
+	FreeSymbols []Symbol
+	// modules: modules compiled so far by their paths, kept by the global table
+	modules map[string]ModuleSymbol
+	// hosted: globals defined by the host program, visible to modules as well; kept by the global table
+	hosted map[string]Symbol
+}
+
+// ModuleSymbol: a compiled module, with the constant index of the function running it
+// and a hidden global keeping its namespace once it runs
+type ModuleSymbol struct {
+	Symbol
+	Constant int
 }
 
 func NewSymbolTable() *SymbolTable {
-	return &SymbolTable{
+	s := &SymbolTable{
 		store:          make(map[string]Symbol),
 		numDefinitions: 0,
+		FreeSymbols:    []Symbol{},
 	}
+	s.owner = s
+	return s
+}
+
+// NewEnclosedSymbolTable: symbol table for a function body, whose definitions are local to its frame
+func NewEnclosedSymbolTable(outer *SymbolTable) *SymbolTable {
+	s := NewSymbolTable()
+	s.Outer = outer
+	return s
+}
+
+// NewBlockSymbolTable: symbol table for a block like an if or loop body;
+// definitions shadow outer ones but take up slots of the enclosing function or globals
+func NewBlockSymbolTable(outer *SymbolTable) *SymbolTable {
+	s := NewSymbolTable()
+	s.Outer = outer
+	s.owner = outer.owner
+	return s
+}
+
+// NewModuleSymbolTable: symbol table for the top level of a module;
+// definitions take up slots of globals but are hidden from the importing code and other modules
+func NewModuleSymbolTable(globals *SymbolTable) *SymbolTable {
+	s := NewSymbolTable()
+	s.owner = globals.owner
+	return s
 }
 
 func (s *SymbolTable) Define(name string) Symbol {
 	sym := Symbol{
 		Name:  name,
-		Scope: GlobalScope,
-		Index: s.numDefinitions,
+		Scope: s.owner.scope(),
+		Index: s.owner.numDefinitions,
+	}
+	s.store[name] = sym
+	s.owner.numDefinitions++
+	return sym
+}
+
+// DefineHosted: define a global on behalf of the host program, which modules can refer to as well
+func (s *SymbolTable) DefineHosted(name string) Symbol {
+	sym := s.Define(name)
+	if s.owner.hosted == nil {
+		s.owner.hosted = map[string]Symbol{}
 	}
+	s.owner.hosted[name] = sym
+	return sym
+}
+
+// DefineFunctionName: let a function refer to itself by the name it is bound to
+func (s *SymbolTable) DefineFunctionName(name string) Symbol {
+	sym := Symbol{Name: name, Scope: FunctionScope, Index: 0}
 	s.store[name] = sym
-	s.numDefinitions++
 	return sym
 }
 
 func (s *SymbolTable) Resolve(name string) (Symbol, bool) {
+	return s.resolve(name, false)
+}
+
+// ResolveBinding: resolve a symbol that can be assigned to,
+// skipping the name a function uses to refer to itself in favour of the binding holding it
+func (s *SymbolTable) ResolveBinding(name string) (Symbol, bool) {
+	return s.resolve(name, true)
+}
+
+func (s *SymbolTable) resolve(name string, skipFunctionName bool) (Symbol, bool) {
 	sym, ok := s.store[name]
-	return sym, ok
+	if ok && skipFunctionName && sym.Scope == FunctionScope {
+		ok = false
+	}
+	if ok {
+		return sym, ok
+	}
+	if s.Outer == nil {
+		if sym, ok := s.owner.hosted[name]; ok {
+			return sym, ok
+		}
+		return resolveBuiltin(name)
+	}
+	sym, ok = s.Outer.resolve(name, skipFunctionName)
+	if !ok {
+		return sym, ok
+	}
+	// blocks share the frame with their enclosing function, nothing to capture
+	if s.owner != s || sym.Scope == GlobalScope || sym.Scope == BuiltinScope {
+		return sym, ok
+	}
+	// symbols living in frames of enclosing functions are captured as free variables
+	return s.defineFree(sym), true
+}
+
+// DefineModule: record the module at path compiled into the function at constant index,
+// allocating its hidden global
+func (s *SymbolTable) DefineModule(path string, constant int) ModuleSymbol {
+	globals := s.owner
+	if globals.modules == nil {
+		globals.modules = map[string]ModuleSymbol{}
+	}
+	module := ModuleSymbol{
+		Symbol:   Symbol{Name: path, Scope: GlobalScope, Index: globals.numDefinitions},
+		Constant: constant,
+	}
+	globals.numDefinitions++
+	globals.modules[path] = module
+	return module
+}
+
+// ResolveModule: the module at path if compiled before
+func (s *SymbolTable) ResolveModule(path string) (ModuleSymbol, bool) {
+	module, ok := s.owner.modules[path]
+	return module, ok
+}
+
+// Snapshot: a copy of the definitions and modules of a global table,
+// for Restore to forget what was defined after it
+func (s *SymbolTable) Snapshot() *SymbolTable {
+	snapshot := NewSymbolTable()
+	snapshot.numDefinitions = s.numDefinitions
+	for name, sym := range s.store {
+		snapshot.store[name] = sym
+	}
+	if s.modules != nil {
+		snapshot.modules = make(map[string]ModuleSymbol, len(s.modules))
+		for path, module := range s.modules {
+			snapshot.modules[path] = module
+		}
+	}
+	return snapshot
+}
+
+// Restore: forget definitions and modules of a global table made since snapshot was taken
+func (s *SymbolTable) Restore(snapshot *SymbolTable) {
+	s.store = snapshot.store
+	s.modules = snapshot.modules
+	s.numDefinitions = snapshot.numDefinitions
+}
+
+// Revert: take back definitions made since snapshot was taken that keep rejects,
+// binding the names they shadowed again; their slots stay allocated
+func (s *SymbolTable) Revert(snapshot *SymbolTable, keep func(Symbol) bool) {
+	for name, sym := range s.store {
+		if sym.Index < snapshot.numDefinitions || keep(sym) {
+			continue
+		}
+		if shadowed, ok := snapshot.store[name]; ok {
+			s.store[name] = shadowed
+		} else {
+			delete(s.store, name)
+		}
+	}
+}
+
+// NumDefinitions: number of slots allocated by the owner of this table
+func (s *SymbolTable) NumDefinitions() int {
+	return s.owner.numDefinitions
+}
+
+// DefinitionNames: names of definitions allocated by the owner of this table indexed by their slots;
+// empty for slots whose names are shadowed or defined in blocks
+func (s *SymbolTable) DefinitionNames() []string {
+	names := make([]string, s.owner.numDefinitions)
+	for _, sym := range s.owner.store {
+		if sym.Scope == s.owner.scope() {
+			names[sym.Index] = sym.Name
+		}
+	}
+	return names
+}
+
+// resolveBuiltin: builtins are looked up from the shared registry when no binding shadows them,
+// so that builtins registered after the table is created are resolved as well
+func resolveBuiltin(name string) (Symbol, bool) {
+	idx, ok := my_object.LookupBuiltin(name)
+	if !ok {
+		return Symbol{}, false
+	}
+	return Symbol{Name: name, Scope: BuiltinScope, Index: idx}, true
+}
+
+func (s *SymbolTable) defineFree(original Symbol) Symbol {
+	s.FreeSymbols = append(s.FreeSymbols, original)
+	sym := Symbol{Name: original.Name, Scope: FreeScope, Index: len(s.FreeSymbols) - 1}
+	// keep the function name referring to the function itself
+	if existing, ok := s.store[original.Name]; !ok || existing.Scope != FunctionScope {
+		s.store[original.Name] = sym
+	}
+	return sym
+}
+
+func (s *SymbolTable) scope() SymbolScope {
+	if s.Outer == nil {
+		return GlobalScope
+	}
+	return LocalScope
 }
@@ -36,3 +36,214 @@ func TestResolveGlobal(t *testing.T) {
 		assert.Equal(t, sym, result)
 	}
 }
+
+func TestResolveLocal(t *testing.T) {
+	global := NewSymbolTable()
+	global.Define("a")
+	global.Define("b")
+
+	local := NewEnclosedSymbolTable(global)
+	local.Define("c")
+	local.Define("d")
+
+	expected := []Symbol{
+		{Name: "a", Scope: GlobalScope, Index: 0},
+		{Name: "b", Scope: GlobalScope, Index: 1},
+		{Name: "c", Scope: LocalScope, Index: 0},
+		{Name: "d", Scope: LocalScope, Index: 1},
+	}
+
+	for _, sym := range expected {
+		result, ok := local.Resolve(sym.Name)
+		assert.True(t, ok)
+		assert.Equal(t, sym, result)
+	}
+}
+
+func TestResolveNestedLocal(t *testing.T) {
+	global := NewSymbolTable()
+	global.Define("a")
+
+	firstLocal := NewEnclosedSymbolTable(global)
+	firstLocal.Define("b")
+
+	secondLocal := NewEnclosedSymbolTable(firstLocal)
+	secondLocal.Define("c")
+	secondLocal.Define("d")
+
+	tests := []struct {
+		table    *SymbolTable
+		expected []Symbol
+	}{
+		{
+			firstLocal,
+			[]Symbol{
+				{Name: "a", Scope: GlobalScope, Index: 0},
+				{Name: "b", Scope: LocalScope, Index: 0},
+			},
+		},
+		{
+			secondLocal,
+			[]Symbol{
+				{Name: "a", Scope: GlobalScope, Index: 0},
+				{Name: "c", Scope: LocalScope, Index: 0},
+				{Name: "d", Scope: LocalScope, Index: 1},
+			},
+		},
+	}
+	for _, tt := range tests {
+		for _, sym := range tt.expected {
+			result, ok := tt.table.Resolve(sym.Name)
+			assert.True(t, ok)
+			assert.Equal(t, sym, result)
+		}
+	}
+}
+
+func TestDefineBlockScope(t *testing.T) {
+	global := NewSymbolTable()
+	global.Define("a")
+
+	globalBlock := NewBlockSymbolTable(global)
+	shadowed := globalBlock.Define("a")
+	assert.Equal(t, Symbol{Name: "a", Scope: GlobalScope, Index: 1}, shadowed)
+	outer, ok := global.Resolve("a")
+	assert.True(t, ok)
+	assert.Equal(t, Symbol{Name: "a", Scope: GlobalScope, Index: 0}, outer)
+
+	local := NewEnclosedSymbolTable(global)
+	local.Define("b")
+	localBlock := NewBlockSymbolTable(local)
+	c := localBlock.Define("c")
+	assert.Equal(t, Symbol{Name: "c", Scope: LocalScope, Index: 1}, c)
+	assert.Equal(t, 2, local.NumDefinitions())
+	assert.Equal(t, 2, localBlock.NumDefinitions())
+	_, ok = local.Resolve("c")
+	assert.False(t, ok)
+}
+
+func TestResolveFree(t *testing.T) {
+	global := NewSymbolTable()
+	global.Define("a")
+
+	firstLocal := NewEnclosedSymbolTable(global)
+	firstLocal.Define("c")
+
+	secondLocal := NewEnclosedSymbolTable(firstLocal)
+	secondLocal.Define("e")
+
+	block := NewBlockSymbolTable(secondLocal)
+	block.Define("f")
+
+	tests := []struct {
+		table               *SymbolTable
+		expectedSymbols     []Symbol
+		expectedFreeSymbols []Symbol
+	}{
+		{
+			firstLocal,
+			[]Symbol{
+				{Name: "a", Scope: GlobalScope, Index: 0},
+				{Name: "c", Scope: LocalScope, Index: 0},
+			},
+			[]Symbol{},
+		},
+		{
+			block,
+			[]Symbol{
+				{Name: "a", Scope: GlobalScope, Index: 0},
+				{Name: "c", Scope: FreeScope, Index: 0},
+				{Name: "e", Scope: LocalScope, Index: 0},
+				{Name: "f", Scope: LocalScope, Index: 1},
+			},
+			[]Symbol{
+				{Name: "c", Scope: LocalScope, Index: 0},
+			},
+		},
+	}
+	for _, tt := range tests {
+		for _, sym := range tt.expectedSymbols {
+			result, ok := tt.table.Resolve(sym.Name)
+			assert.True(t, ok)
+			assert.Equal(t, sym, result)
+		}
+		assert.Equal(t, tt.expectedFreeSymbols, tt.table.owner.FreeSymbols)
+	}
+
+	_, ok := secondLocal.Resolve("b")
+	assert.False(t, ok)
+}
+
+func TestDefineAndResolveFunctionName(t *testing.T) {
+	global := NewSymbolTable()
+	global.DefineFunctionName("a")
+	result, ok := global.Resolve("a")
+	assert.True(t, ok)
+	assert.Equal(t, Symbol{Name: "a", Scope: FunctionScope, Index: 0}, result)
+
+	// shadowed by a later definition
+	global.Define("a")
+	result, ok = global.Resolve("a")
+	assert.True(t, ok)
+	assert.Equal(t, Symbol{Name: "a", Scope: GlobalScope, Index: 0}, result)
+}
+
+func TestResolveBuiltins(t *testing.T) {
+	global := NewSymbolTable()
+	firstLocal := NewEnclosedSymbolTable(global)
+	block := NewBlockSymbolTable(firstLocal)
+
+	expected := []Symbol{
+		{Name: "len", Scope: BuiltinScope, Index: 0},
+		{Name: "append", Scope: BuiltinScope, Index: 1},
+		{Name: "put", Scope: BuiltinScope, Index: 2},
+	}
+	for _, table := range []*SymbolTable{global, firstLocal, block} {
+		for _, sym := range expected {
+			result, ok := table.Resolve(sym.Name)
+			assert.True(t, ok)
+			assert.Equal(t, sym, result)
+		}
+		assert.Empty(t, table.FreeSymbols)
+	}
+
+	// shadowed by user definitions
+	global.Define("len")
+	result, ok := block.Resolve("len")
+	assert.True(t, ok)
+	assert.Equal(t, Symbol{Name: "len", Scope: GlobalScope, Index: 0}, result)
+}
+
+func TestModuleSymbolTable(t *testing.T) {
+	global := NewSymbolTable()
+	global.Define("a")
+	global.DefineHosted("host")
+
+	module := NewModuleSymbolTable(global)
+	assert.Equal(t, Symbol{Name: "b", Scope: GlobalScope, Index: 2}, module.Define("b"))
+	assert.Equal(t, Symbol{Name: "a", Scope: GlobalScope, Index: 3}, module.Define("a"))
+
+	// modules see their own globals and those of the host program, not those of the importing code
+	fn := NewEnclosedSymbolTable(module)
+	for _, expected := range []Symbol{
+		{Name: "a", Scope: GlobalScope, Index: 3},
+		{Name: "b", Scope: GlobalScope, Index: 2},
+		{Name: "host", Scope: GlobalScope, Index: 1},
+	} {
+		sym, ok := fn.Resolve(expected.Name)
+		assert.True(t, ok)
+		assert.Equal(t, expected, sym)
+	}
+	_, ok := NewModuleSymbolTable(global).Resolve("a")
+	assert.False(t, ok)
+	assert.Equal(t, []string{"a", "b"}, module.exportedNames())
+
+	// modules are recorded by the global table, their hidden globals allocated after other globals
+	_, ok = module.ResolveModule("lib.monkey")
+	assert.False(t, ok)
+	defined := module.DefineModule("lib.monkey", 7)
+	assert.Equal(t, ModuleSymbol{Symbol: Symbol{Name: "lib.monkey", Scope: GlobalScope, Index: 4}, Constant: 7}, defined)
+	resolved, ok := global.ResolveModule("lib.monkey")
+	assert.True(t, ok)
+	assert.Equal(t, defined, resolved)
+}
@@ -1,9 +1,26 @@
 package my_engine
 
 import (
+	"context"
+	"monkey/my_module"
 	"monkey/my_object"
 )
 
 type Engine interface {
 	Evaluate(code string) (result my_object.Object, err error)
+	// EvaluateContext: evaluate code until ctx is done,
+	// in which case the error matches ctx.Err() and tells where execution stops
+	EvaluateContext(ctx context.Context, code string) (result my_object.Object, err error)
+	// Define: bind a Go value to name for code evaluated afterwards, converting it by reflection;
+	// Go functions become callable like RegisterFunc
+	Define(name string, value any) error
+	// RegisterFunc: bind a Go function to name, converting monkey arguments to its parameters
+	// and its result back; mismatched arguments and a non-nil trailing error are raised as monkey errors
+	RegisterFunc(name string, fn any) error
+	// Call: call the function bound to name by code evaluated before, or a builtin, with args,
+	// nil ones passed as null; limits apply to the call like to Evaluate
+	Call(name string, args ...my_object.Object) (result my_object.Object, err error)
+	// SetLoader: load modules imported by code evaluated afterwards with loader;
+	// engines start with a loader looking modules up in the working directory
+	SetLoader(loader *my_module.Loader)
 }
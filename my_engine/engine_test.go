@@ -1,12 +1,19 @@
 package my_engine
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io/ioutil"
+	"monkey/my_module"
+	"monkey/my_object"
+	"monkey/my_parser"
+	"monkey/my_vm"
 	"os"
 	"path"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -60,3 +67,374 @@ func TestEngineWithExamples(t *testing.T) {
 		}
 	}
 }
+
+func TestEngineErrorStages(t *testing.T) {
+	tests := []struct {
+		code    string
+		stage   error
+		engines []Engine
+	}{
+		{"let 1;", my_parser.ErrParseError, []Engine{NewEvalEngine(), NewVMEngine()}},
+		{"undefinedIdent", ErrCompile, []Engine{NewVMEngine()}},
+		{"undefinedIdent", ErrRuntime, []Engine{NewEvalEngine()}},
+		{`len(1)`, ErrRuntime, []Engine{NewEvalEngine(), NewVMEngine()}},
+		{`1 + "a"`, ErrRuntime, []Engine{NewEvalEngine(), NewVMEngine()}},
+	}
+	for _, tt := range tests {
+		for _, eg := range tt.engines {
+			_, err := eg.Evaluate(tt.code)
+			assert.ErrorIs(t, err, tt.stage, "engine: %v: code: %s", eg, tt.code)
+			for _, other := range []error{my_parser.ErrParseError, ErrCompile, ErrRuntime} {
+				if other != tt.stage {
+					assert.False(t, errors.Is(err, other), "engine: %v: code: %s", eg, tt.code)
+				}
+			}
+		}
+	}
+}
+
+// TestEnginesAgree: code whose results used to differ between the engines
+func TestEnginesAgree(t *testing.T) {
+	tests := []struct {
+		code     string
+		expected string
+	}{
+		// a function assigning to its own name sees the value assigned
+		{"let f = fn() { f = 5; f }; f()", "5"},
+		{"let f = fn() { f = 5; f }; f(); f", "5"},
+		{"let g = fn() { let h = fn(n) { if (n == 0) { h = 7; return h; }; h(n - 1) }; h(2) }; g()", "7"},
+		// ++ and -- at the end of a line are increments, two signs before an operand on the same line
+		{"let a = 5; a--\na", "4"},
+		{"let a = 5; a++\na", "6"},
+		{"let a = 5; a++ + 1", "7"},
+		{"let a = 5; let b = a--; [a, b]", "[4,4]"},
+		{"let a = 5; a--1", "6"},
+		{"let a = 5; a -- a", "10"},
+		{"let a = [1, 2]; a[-1]++\na", "[1,3]"},
+		// reading, updating and writing an element count negative indices from the end alike
+		{"let a = [1]; a[-1] += 1; a", "[2]"},
+		{"let a = [1, 2, 3]; a[-3] *= 10; a[-1]--; [a[0], a[-1]]", "[10,2]"},
+		{"let a = [1, 2]; a[-2] += a[-1]; a", "[3,2]"},
+		{`"abc"[-3]`, "a"},
+		// a try or catch block ending with a statement yields null
+		{"try { let x = 1 } catch (e) { 0 }", "null"},
+		{"try { throw 1 } catch (e) { let y = 2 }", "null"},
+		{"let v = try { let x = 1 } catch (e) { 0 }; [v]", "[null]"},
+		{"try { let x = 1 } catch (e) { 0 } finally { 2 }", "null"},
+	}
+	for _, tt := range tests {
+		for _, eg := range []Engine{NewEvalEngine(), NewVMEngine()} {
+			res, err := eg.Evaluate(tt.code)
+			if assert.NoError(t, err, "engine: %T: code: %s", eg, tt.code) {
+				assert.Equal(t, tt.expected, res.String(), "engine: %T: code: %s", eg, tt.code)
+			}
+		}
+	}
+}
+
+// TestEnginesAgreeOnErrors: runtime errors reported with the same message by both engines
+func TestEnginesAgreeOnErrors(t *testing.T) {
+	tests := []struct {
+		code     string
+		expected string
+	}{
+		{"1.5 & 1", "unknown operator: FLOAT&FLOAT"},
+		{"true >> 2.5", "unknown operator: FLOAT>>FLOAT"},
+		{`"a" - "b"`, "unknown operator: STRING-STRING"},
+		{`"a" == 1`, "unknown operator: STRING==INT"},
+		{"null + null", "unknown operator: NULL+NULL"},
+		{"1 > null", "unknown operator: INT>NULL"},
+		{"[1] // 2", "unknown operator: ARRAY//INT"},
+		{"let a = [1]; a[-2] += 1", "index -2 out of array with length 1"},
+		{"let a = [1]; a[1]++", "index 1 out of array with length 1"},
+		{`let a = [1]; a["x"] -= 1`, "array-like indexing expecting INT, but got STRING"},
+	}
+	for _, tt := range tests {
+		for _, eg := range []Engine{NewEvalEngine(), NewVMEngine()} {
+			_, err := eg.Evaluate(tt.code)
+			assert.ErrorIs(t, err, ErrRuntime, "engine: %T: code: %s", eg, tt.code)
+			assert.EqualError(t, err, tt.expected, "engine: %T: code: %s", eg, tt.code)
+		}
+	}
+}
+
+func TestEngineUnboundedRecursion(t *testing.T) {
+	for _, eg := range []Engine{NewEvalEngine(), NewVMEngine()} {
+		_, err := eg.Evaluate("let f = fn() { f() }; f()")
+		assert.ErrorIs(t, err, ErrRuntime, "engine: %T", eg)
+		assert.EqualError(t, err, "stack overflow", "engine: %T", eg)
+		// the engine is still usable afterwards
+		res, err := eg.Evaluate("let g = fn(n) { if (n == 0) { 0 } else { 1 + g(n - 1) } }; g(100)")
+		if assert.NoError(t, err, "engine: %T", eg) {
+			assert.Equal(t, "100", res.String(), "engine: %T", eg)
+		}
+	}
+}
+
+func TestEngineFailedDefinitions(t *testing.T) {
+	for _, eg := range []Engine{NewEvalEngine(), NewVMEngine()} {
+		_, err := eg.Evaluate("let a = 1/0;")
+		assert.ErrorIs(t, err, ErrRuntime, "engine: %T", eg)
+		_, err = eg.Evaluate("a + 1")
+		assert.Regexp(t, `^(undefined variable|identifier not found): a$`, err, "engine: %T", eg)
+
+		_, err = eg.Evaluate("let b = 1; let c = 1/0;")
+		assert.Error(t, err, "engine: %T", eg)
+		res, err := eg.Evaluate("b + 1")
+		assert.NoError(t, err, "engine: %T", eg)
+		assert.Equal(t, "2", res.String(), "engine: %T", eg)
+
+		// names shadowed by definitions left unset keep their values
+		_, err = eg.Evaluate("let b = b/0;")
+		assert.Error(t, err, "engine: %T", eg)
+		res, err = eg.Evaluate("b")
+		assert.NoError(t, err, "engine: %T", eg)
+		assert.Equal(t, "1", res.String(), "engine: %T", eg)
+	}
+
+	// nothing runs if compiling fails, so nothing is defined
+	eg := NewVMEngine()
+	_, err := eg.Evaluate("let f = fn(){1}; let d = yy;")
+	assert.ErrorIs(t, err, ErrCompile)
+	_, err = eg.Evaluate("f()")
+	assert.ErrorIs(t, err, ErrCompile)
+	assert.EqualError(t, err, "undefined variable: f")
+}
+
+func TestCompileAndRunByteCode(t *testing.T) {
+	byteCode, err := Compile(`let double = fn(x) { x * 2 }; double(21)`)
+	assert.NoError(t, err)
+	res, err := RunByteCode(byteCode)
+	assert.NoError(t, err)
+	assert.Equal(t, "42", res.String())
+
+	_, err = Compile("undefinedIdent")
+	assert.ErrorIs(t, err, ErrCompile)
+}
+
+func TestEngineLimits(t *testing.T) {
+	tests := []struct {
+		code   string
+		limits Limits
+	}{
+		{"while(true) {}", Limits{MaxSteps: 10000}},
+		{"while(true) {}", Limits{Timeout: 20 * time.Millisecond}},
+		{"let f = fn(n) { f(n + 1) }; f(0)", Limits{MaxCallDepth: 100}},
+		{`"monkey" * 1000000000`, Limits{MaxAllocBytes: 1 << 20}},
+		{"let a = []; for (let i = 0; i < 100000; i = i + 1) { a = append(a, i) }", Limits{MaxAllocBytes: 1 << 20}},
+	}
+	for _, tt := range tests {
+		for _, eg := range []Engine{NewEvalEngineWithLimits(tt.limits), NewVMEngineWithLimits(tt.limits)} {
+			_, err := eg.Evaluate(tt.code)
+			assert.ErrorIs(t, err, ErrLimitExceeded, "engine: %T: code: %s", eg, tt.code)
+			assert.ErrorIs(t, err, ErrRuntime, "engine: %T: code: %s", eg, tt.code)
+
+			// limits apply to each evaluation afresh
+			res, err := eg.Evaluate("let x = 1 + 2; x")
+			assert.NoError(t, err, "engine: %T: code: %s", eg, tt.code)
+			assert.Equal(t, "3", res.String())
+		}
+	}
+}
+
+func TestEngineEvaluateContext(t *testing.T) {
+	code := "let a = 0;\nwhile(true) {\n  a = a + 1;\n}"
+	for _, eg := range []Engine{NewEvalEngine(), NewVMEngine()} {
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		_, err := eg.EvaluateContext(ctx, code)
+		cancel()
+		assert.ErrorIs(t, err, context.DeadlineExceeded, "engine: %T", eg)
+		assert.ErrorIs(t, err, ErrRuntime, "engine: %T", eg)
+		assert.Regexp(t, `^context deadline exceeded at [23]:\d+$`, err.Error(), "engine: %T", eg)
+
+		ctx, cancel = context.WithCancel(context.Background())
+		go func() {
+			time.Sleep(10 * time.Millisecond)
+			cancel()
+		}()
+		_, err = eg.EvaluateContext(ctx, code)
+		assert.ErrorIs(t, err, context.Canceled, "engine: %T", eg)
+
+		_, err = eg.EvaluateContext(ctx, "1")
+		assert.ErrorIs(t, err, context.Canceled, "engine: %T", eg)
+
+		// the engine is still usable with another context
+		res, err := eg.EvaluateContext(context.Background(), "a")
+		assert.NoError(t, err, "engine: %T", eg)
+		assert.NotNil(t, res, "engine: %T", eg)
+	}
+}
+
+func TestEngineCall(t *testing.T) {
+	code := `
+let greet = fn(name) { "hello " + name };
+let makeAdder = fn(x) { fn(y) { x + y } };
+let addTwo = makeAdder(2);
+let fib = fn(n) { if (n < 2) { n } else { fib(n - 1) + fib(n - 2) } };
+let spin = fn() { while (true) {} };
+let answer = 42;
+let isNull = fn(x) { x == null };
+`
+	tests := []struct {
+		name     string
+		args     []my_object.Object
+		expected string
+		err      string
+	}{
+		{"greet", []my_object.Object{&my_object.String{Value: "monkey"}}, "hello monkey", ""},
+		{"addTwo", []my_object.Object{&my_object.Integer{Value: 3}}, "5", ""},
+		{"fib", []my_object.Object{&my_object.Integer{Value: 10}}, "55", ""},
+		{"len", []my_object.Object{&my_object.String{Value: "four"}}, "4", ""},
+		{"double", []my_object.Object{&my_object.Integer{Value: 4}}, "8", ""},
+		{"greet", nil, "", "wrong number of arguments: want=1, got=0"},
+		{"answer", nil, "", "not a function: INT"},
+		{"isNull", []my_object.Object{nil}, "true", ""},
+	}
+	for _, eg := range []Engine{NewEvalEngineWithLimits(Limits{MaxSteps: 10000}), NewVMEngineWithLimits(Limits{MaxSteps: 10000})} {
+		_, err := eg.Evaluate(code)
+		assert.NoError(t, err, "engine: %T", eg)
+		assert.NoError(t, eg.RegisterFunc("double", func(n int) int { return 2 * n }))
+		// later code does not disturb functions defined before
+		_, err = eg.Evaluate(`let other = "constant"; 3.5`)
+		assert.NoError(t, err, "engine: %T", eg)
+
+		for _, tt := range tests {
+			res, err := eg.Call(tt.name, tt.args...)
+			if tt.err != "" {
+				assert.ErrorIs(t, err, ErrRuntime, "engine: %T: name: %s", eg, tt.name)
+				assert.EqualError(t, err, tt.err, "engine: %T: name: %s", eg, tt.name)
+				continue
+			}
+			if assert.NoError(t, err, "engine: %T: name: %s", eg, tt.name) {
+				assert.Equal(t, tt.expected, res.String(), "engine: %T: name: %s", eg, tt.name)
+			}
+		}
+
+		_, err = eg.Call("undefined")
+		assert.ErrorContains(t, err, "undefined", "engine: %T", eg)
+		_, err = eg.Call("spin")
+		assert.ErrorIs(t, err, ErrLimitExceeded, "engine: %T", eg)
+
+		// results of calls are usual objects for code evaluated afterwards
+		res, err := eg.Call("makeAdder", &my_object.Integer{Value: 10})
+		assert.NoError(t, err, "engine: %T", eg)
+		assert.NoError(t, eg.Define("addTen", res))
+		res, err = eg.Evaluate("addTen(1)")
+		if assert.NoError(t, err, "engine: %T", eg) {
+			assert.Equal(t, "11", res.String(), "engine: %T", eg)
+		}
+	}
+}
+
+func TestEngineTryCatch(t *testing.T) {
+	tests := []struct {
+		code     string
+		expected string
+		err      string
+	}{
+		{`try { 1 / 0 } catch (e) { e["message"] }`, "division by zero", ""},
+		{`try { [1, 2]["a"] } catch (e) { e["message"] }`, "array-like indexing expecting INT, but got STRING", ""},
+		{"let x = 0;\ntry {\n  1 + x / 0\n} catch (e) { [e[\"line\"], e[\"column\"]] }", "[3,7]", ""},
+		{`try { 5 } catch { 6 }`, "5", ""},
+		{`try { throw null } catch { "caught" }`, "caught", ""},
+		// unwinding calls with values left on the stack
+		{`let f = fn(n) { if (n == 0) { throw "bottom" }; 1 + f(n - 1) }; try { f(10) } catch (e) { e }`, "bottom", ""},
+		{`let f = fn(n) { if (n == 0) { throw n }; 1 + f(n - 1) }; 2 * try { f(10) } catch (e) { e + 1 }`, "2", ""},
+		{`let f = fn(xs) { let s = 0; for (let i = 0; i < len(xs); i = i + 1) { s = s + try { 10 / xs[i] } catch { 0 } }; s }; f([1, 0, 2])`, "15", ""},
+		// finally runs however the try block is left
+		{`let s = 0; for (let i = 0; i < 5; i = i + 1) { try { if (i == 1) { continue; }; if (i == 3) { break; }; s = s + i } finally { s = s + 100 } }; s`, "402", ""},
+		{`let n = 0; let f = fn() { try { return 1 } finally { n = 5 } }; [f(), n]`, "[1,5]", ""},
+		{`let n = 0; let r = try { 1 } finally { n = 2 }; [r, n]`, "[1,2]", ""},
+		{`let n = 0; try { try { throw 1 } catch (e) { throw e + 1 } finally { n = 10 } } catch (e) { e + n }`, "12", ""},
+		{`let n = 0; try { try { 1 / 0 } finally { n = 1 } } catch { n }`, "1", ""},
+		{`try { try { 1 / 0 } catch (e) { throw e } } catch (e) { e["message"] }`, "division by zero", ""},
+		// uncaught exceptions
+		{`throw "boom"`, "", "uncaught exception: boom"},
+		{`try { 1 / 0 } finally { 1 }`, "", "division by zero"},
+		{`try { 1 / 0 } catch (e) { throw e }`, "", "division by zero"},
+		{`try { 1 } finally { throw {"message": "in finally"} }`, "", "in finally"},
+	}
+	for _, tt := range tests {
+		for _, eg := range []Engine{NewEvalEngine(), NewVMEngine()} {
+			res, err := eg.Evaluate(tt.code)
+			if tt.err != "" {
+				assert.ErrorIs(t, err, ErrRuntime, "engine: %T: code: %s", eg, tt.code)
+				assert.ErrorContains(t, err, tt.err, "engine: %T: code: %s", eg, tt.code)
+				continue
+			}
+			if assert.NoError(t, err, "engine: %T: code: %s", eg, tt.code) {
+				assert.Equal(t, tt.expected, res.String(), "engine: %T: code: %s", eg, tt.code)
+			}
+		}
+	}
+
+	// exceeding limits is not an exception
+	limits := Limits{MaxSteps: 10000}
+	for _, eg := range []Engine{NewEvalEngineWithLimits(limits), NewVMEngineWithLimits(limits)} {
+		_, err := eg.Evaluate("try { while (true) {} } catch { 1 } finally { 2 }")
+		assert.ErrorIs(t, err, ErrLimitExceeded, "engine: %T", eg)
+	}
+}
+
+func TestEngineImport(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"lib/math.monkey": `import "./util";
+let square = fn(x) { x * x };
+let twice = fn(x) { util["double"](x) };
+let total = 0;
+for (let i = 0; i < 4; i = i + 1) { total = total + i };
+tick();`,
+		"lib/util.monkey": `let double = fn(x) { x * 2 };`,
+		"cycle_a.monkey":  `import cycle_b;`,
+		"cycle_b.monkey":  `import cycle_a;`,
+		"failing.monkey":  `let f = fn() { 1 + "a" }; f();`,
+		"nested.monkey":   `import failing;`,
+		"private.monkey":  `let leak = fn() { secret };`,
+	}
+	for name, code := range files {
+		path := path.Join(dir, name)
+		assert.NoError(t, os.MkdirAll(path[:strings.LastIndex(path, "/")], 0o755))
+		assert.NoError(t, os.WriteFile(path, []byte(code), 0o644))
+	}
+
+	for _, eg := range []Engine{NewEvalEngine(), NewVMEngine()} {
+		ticks := 0
+		assert.NoError(t, eg.RegisterFunc("tick", func() { ticks++ }))
+		eg.SetLoader(my_module.NewLoader(dir))
+
+		res, err := eg.Evaluate(`import "lib/math.monkey"; math["square"](3) + math["twice"](5) + math["total"]`)
+		if assert.NoError(t, err, "engine: %T", eg) {
+			assert.Equal(t, "25", res.String(), "engine: %T", eg)
+		}
+		// modules run once, however many times and names they are imported with
+		_, err = eg.Evaluate(`import "lib/math" as m; import lib; m["square"](4)`)
+		assert.ErrorContains(t, err, "module not found", "engine: %T", eg)
+		res, err = eg.Evaluate(`import "lib/math" as m; m["square"](4) + m["total"]`)
+		if assert.NoError(t, err, "engine: %T", eg) {
+			assert.Equal(t, "22", res.String(), "engine: %T", eg)
+		}
+		assert.Equal(t, 1, ticks, "engine: %T", eg)
+
+		_, err = eg.Evaluate(`import cycle_a`)
+		assert.ErrorContains(t, err, "import cycle: ", "engine: %T", eg)
+		// runtime errors tell the module failing, by the message or the traceback
+		_, err = eg.Evaluate(`import nested`)
+		assert.ErrorIs(t, err, ErrRuntime, "engine: %T", eg)
+		assert.ErrorContains(t, err, "unknown operator", "engine: %T", eg)
+		var runtimeErr *my_vm.RuntimeError
+		if errors.As(err, &runtimeErr) {
+			assert.Contains(t, runtimeErr.Traceback(), path.Join(dir, "failing.monkey")+" at 1:", "engine: %T", eg)
+			assert.Equal(t, path.Join(dir, "failing.monkey"), runtimeErr.Module, "engine: %T", eg)
+			assert.Equal(t, "1:16", runtimeErr.Pos.String(), "engine: %T", eg)
+		} else {
+			assert.ErrorContains(t, err, path.Join(dir, "failing.monkey")+": ", "engine: %T", eg)
+		}
+		_, err = eg.Evaluate(`let secret = 1; import private; private["leak"]()`)
+		assert.ErrorContains(t, err, "secret", "engine: %T", eg)
+	}
+
+	_, err := NewVMEngine().Evaluate(`import missing`)
+	assert.ErrorIs(t, err, ErrCompile)
+	assert.ErrorIs(t, err, my_module.ErrImport)
+}
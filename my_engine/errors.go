@@ -0,0 +1,31 @@
+package my_engine
+
+import (
+	"errors"
+	"fmt"
+	token "monkey/my_token"
+)
+
+// errors of stages after parsing; parse errors are my_parser.ErrorList matching my_parser.ErrParseError
+var (
+	ErrCompile = errors.New("compile error")
+	ErrRuntime = errors.New("runtime error")
+)
+
+// stageError: error raised in one stage of an engine,
+// matching the sentinel of the stage as well as the original error
+type stageError struct {
+	stage error
+	err   error
+}
+
+func (e *stageError) Error() string { return e.err.Error() }
+
+func (e *stageError) Unwrap() error { return e.err }
+
+func (e *stageError) Is(target error) bool { return target == e.stage }
+
+// interrupted: wrap err of a done context with where execution stops
+func interrupted(err error, pos token.Position) error {
+	return fmt.Errorf("%w at %s", err, pos)
+}
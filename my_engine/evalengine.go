@@ -1,23 +1,93 @@
 package my_engine
 
 import (
+	"context"
+	"errors"
+	"monkey/my_ast"
 	"monkey/my_evaluator"
+	"monkey/my_module"
 	"monkey/my_object"
 )
 
 type evalEngine struct {
-	env *my_object.Environment
+	// host: bindings defined by the host program, enclosing env and environments of modules
+	host *my_object.Environment
+	env  *my_object.Environment
+	// limits: zero values if unlimited
+	limits Limits
+	// meter: set on env once, so that every environment enclosed in env shares it
+	meter *my_object.Meter
 }
 
 func NewEvalEngine() Engine {
-	return &evalEngine{env: my_object.NewEnvironment()}
+	return NewEvalEngineWithLimits(Limits{})
+}
+
+// NewEvalEngineWithLimits: an evaluator engine enforcing limits on each Evaluate
+func NewEvalEngineWithLimits(limits Limits) Engine {
+	e := &evalEngine{host: my_object.NewEnvironment(), limits: limits, meter: my_object.NewMeter(limits)}
+	e.host.SetMeter(e.meter)
+	e.env = my_object.NewEnclosedEnvironment(e.host)
+	e.SetLoader(my_module.NewLoader())
+	return e
 }
 
 func (e *evalEngine) Evaluate(code string) (result my_object.Object, err error) {
+	return e.EvaluateContext(context.Background(), code)
+}
+
+func (e *evalEngine) EvaluateContext(ctx context.Context, code string) (result my_object.Object, err error) {
 	program, err := parse(code)
 	if err != nil {
 		return nil, err
 	}
-	evaluated := my_evaluator.Eval(program, e.env)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	e.meter.Reset(e.limits)
+	e.meter.SetContext(ctx)
+	return e.result(ctx, my_evaluator.Eval(program, e.env))
+}
+
+func (e *evalEngine) Call(name string, args ...my_object.Object) (my_object.Object, error) {
+	ctx := context.Background()
+	e.meter.Reset(e.limits)
+	e.meter.SetContext(ctx)
+	// resolve name like an identifier in code, builtins included
+	function := my_evaluator.Eval(&my_ast.Identifier{Value: name}, e.env)
+	if _, isErr := function.(*my_object.Error); isErr {
+		return e.result(ctx, function)
+	}
+	return e.result(ctx, my_evaluator.Apply(function, callArgs(args), e.env))
+}
+
+// result: the error raised while evaluating if any, otherwise what is evaluated
+func (e *evalEngine) result(ctx context.Context, evaluated my_object.Object) (my_object.Object, error) {
+	if err := e.meter.Err(); err != nil {
+		if ctxErr := ctx.Err(); errors.Is(err, ctxErr) {
+			err = interrupted(ctxErr, e.meter.StoppedAt())
+		}
+		return nil, &stageError{stage: ErrRuntime, err: err}
+	}
+	if errObj, ok := evaluated.(*my_object.Error); ok {
+		return nil, &stageError{stage: ErrRuntime, err: errors.New(errObj.Message)}
+	}
 	return evaluated, nil
 }
+
+func (e *evalEngine) Define(name string, value any) error {
+	return defineValue(e.define, name, value)
+}
+
+func (e *evalEngine) RegisterFunc(name string, fn any) error {
+	return registerFunc(e.define, name, fn)
+}
+
+// SetLoader: modules imported with a previous loader are imported again
+func (e *evalEngine) SetLoader(loader *my_module.Loader) {
+	e.env.SetImporter(my_evaluator.NewModuleImporter(loader, e.host))
+}
+
+func (e *evalEngine) define(name string, obj my_object.Object) {
+	e.host.Set(name, obj)
+}
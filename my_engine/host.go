@@ -0,0 +1,72 @@
+package my_engine
+
+import (
+	"errors"
+	"fmt"
+	"monkey/my_object"
+	token "monkey/my_token"
+	"reflect"
+)
+
+var ErrUnsupportedValue = my_object.ErrUnsupportedValue
+
+// ErrInvalidName: a name code cannot refer to, or one taken by a builtin
+var ErrInvalidName = errors.New("invalid name")
+
+// checkName: names bound by the host are identifiers like the lexer reads them, keywords and builtins excluded
+func checkName(name string) error {
+	if name == "" || token.LookupIdent(name) != token.IDENT {
+		return fmt.Errorf("%w: %q is not an identifier", ErrInvalidName, name)
+	}
+	for idx := 0; idx < len(name); idx++ {
+		ch := name[idx]
+		if !('a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || ch == '_') {
+			return fmt.Errorf("%w: %q is not an identifier", ErrInvalidName, name)
+		}
+	}
+	if _, ok := my_object.LookupBuiltin(name); ok {
+		return fmt.Errorf("%w: %q is a builtin", ErrInvalidName, name)
+	}
+	return nil
+}
+
+// registerFunc: check fn is a Go function before binding it
+func registerFunc(define func(string, my_object.Object), name string, fn any) error {
+	if err := checkName(name); err != nil {
+		return err
+	}
+	builtin, err := my_object.FromGoFunc(name, fn)
+	if err != nil {
+		return err
+	}
+	define(name, builtin)
+	return nil
+}
+
+func defineValue(define func(string, my_object.Object), name string, value any) error {
+	if err := checkName(name); err != nil {
+		return err
+	}
+	// functions are named after their binding in argument errors
+	if value != nil && reflect.TypeOf(value).Kind() == reflect.Func {
+		return registerFunc(define, name, value)
+	}
+	obj, err := my_object.FromGo(value)
+	if err != nil {
+		return err
+	}
+	define(name, obj)
+	return nil
+}
+
+// callArgs: arguments of Call with nil ones passed as null, leaving args untouched
+func callArgs(args []my_object.Object) []my_object.Object {
+	converted := make([]my_object.Object, len(args))
+	for idx, arg := range args {
+		if arg == nil {
+			arg = my_object.NULL
+		}
+		converted[idx] = arg
+	}
+	return converted
+}
@@ -0,0 +1,115 @@
+package my_engine
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEngineRegisterFunc(t *testing.T) {
+	tests := []struct {
+		code     string
+		expected string
+		err      string
+	}{
+		{"add(1, 2)", "3", ""},
+		{"sum()", "0", ""},
+		{"sum(1, 2.5, 3)", "6.5", ""},
+		{`join(["a", "b"], "-")`, "a-b", ""},
+		{`count({"a": 1, "b": 2})`, "3", ""},
+		{`typeOf(1) + typeOf("a") + typeOf([1]) + typeOf(null)`, "int64string[]interface {}<nil>", ""},
+		{"let double = fn(x) { add(x, x) }; double(4)", "8", ""},
+		{"noop()", "null", ""},
+		{`add("1", 2)`, "", "argument 1 to `add`: cannot use STRING as int"},
+		{"add(1)", "", "wrong number of arguments to `add`: got=1, want=2"},
+		{`join([1], "-")`, "", "argument 1 to `join`: element 0: cannot use INT as string"},
+		{"div(1, 0)", "", "division by zero"},
+		{"div(7, 2)", "3", ""},
+	}
+	for _, eg := range []Engine{NewEvalEngine(), NewVMEngine()} {
+		assert.NoError(t, eg.RegisterFunc("add", func(a, b int) int { return a + b }))
+		assert.NoError(t, eg.RegisterFunc("sum", func(nums ...float64) float64 {
+			total := 0.0
+			for _, num := range nums {
+				total += num
+			}
+			return total
+		}))
+		assert.NoError(t, eg.RegisterFunc("join", strings.Join))
+		assert.NoError(t, eg.RegisterFunc("count", func(m map[string]int) int {
+			total := 0
+			for _, v := range m {
+				total += v
+			}
+			return total
+		}))
+		assert.NoError(t, eg.RegisterFunc("typeOf", func(v any) string { return fmt.Sprintf("%T", v) }))
+		assert.NoError(t, eg.RegisterFunc("noop", func() {}))
+		assert.NoError(t, eg.RegisterFunc("div", func(a, b int) (int, error) {
+			if b == 0 {
+				return 0, errors.New("division by zero")
+			}
+			return a / b, nil
+		}))
+
+		for _, tt := range tests {
+			res, err := eg.Evaluate(tt.code)
+			if tt.err != "" {
+				assert.ErrorIs(t, err, ErrRuntime, "engine: %T: code: %s", eg, tt.code)
+				if assert.Error(t, err) {
+					assert.Equal(t, tt.err, err.Error(), "engine: %T: code: %s", eg, tt.code)
+				}
+				continue
+			}
+			if assert.NoError(t, err, "engine: %T: code: %s", eg, tt.code) {
+				assert.Equal(t, tt.expected, res.String(), "engine: %T: code: %s", eg, tt.code)
+			}
+		}
+
+		assert.ErrorIs(t, eg.RegisterFunc("one", 1), ErrUnsupportedValue)
+		assert.ErrorIs(t, eg.RegisterFunc("pair", func() (int, int) { return 1, 2 }), ErrUnsupportedValue)
+	}
+}
+
+func TestEngineDefine(t *testing.T) {
+	for _, eg := range []Engine{NewEvalEngine(), NewVMEngine()} {
+		assert.NoError(t, eg.Define("debug", false))
+		assert.NoError(t, eg.Define("config", map[string]any{
+			"name":  "monkey",
+			"ports": []uint16{80, 443},
+			"ratio": 0.5,
+		}))
+		assert.NoError(t, eg.Define("greet", func(name string) string { return "hello " + name }))
+
+		res, err := eg.Evaluate(`if (debug) { 0 } else { config["ports"][1] }`)
+		assert.NoError(t, err, "engine: %T", eg)
+		assert.Equal(t, "443", res.String(), "engine: %T", eg)
+
+		res, err = eg.Evaluate(`greet(config["name"])`)
+		assert.NoError(t, err, "engine: %T", eg)
+		assert.Equal(t, "hello monkey", res.String(), "engine: %T", eg)
+
+		// defining the name again replaces the binding
+		assert.NoError(t, eg.Define("debug", true))
+		res, err = eg.Evaluate(`debug`)
+		assert.NoError(t, err, "engine: %T", eg)
+		assert.Equal(t, "true", res.String(), "engine: %T", eg)
+
+		assert.ErrorIs(t, eg.Define("ch", make(chan int)), ErrUnsupportedValue)
+		assert.ErrorIs(t, eg.Define("big", uint64(1<<63)), ErrUnsupportedValue)
+
+		// names must be identifiers other than keywords and builtins
+		for _, name := range []string{"", "1x", "a-b", "x1", "let", "len"} {
+			assert.ErrorIs(t, eg.Define(name, 1), ErrInvalidName, "engine: %T: name: %q", eg, name)
+			assert.ErrorIs(t, eg.RegisterFunc(name, func() {}), ErrInvalidName, "engine: %T: name: %q", eg, name)
+		}
+		assert.EqualError(t, eg.Define("a-b", 1), `invalid name: "a-b" is not an identifier`)
+		assert.EqualError(t, eg.RegisterFunc("len", func() {}), `invalid name: "len" is a builtin`)
+		res, err = eg.Evaluate(`len("abc")`)
+		assert.NoError(t, err, "engine: %T", eg)
+		assert.Equal(t, "3", res.String(), "engine: %T", eg)
+	}
+}
@@ -0,0 +1,10 @@
+package my_engine
+
+import "monkey/my_object"
+
+// Limits: ceilings for running untrusted code with either engine, zero values mean unlimited;
+// the vm counts executed instructions as steps, the evaluator counts evaluated nodes
+type Limits = my_object.Limits
+
+// ErrLimitExceeded: matched by runtime errors raised when a limit is exceeded
+var ErrLimitExceeded = my_object.ErrLimitExceeded
@@ -1,9 +1,15 @@
 package my_engine
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
 	"monkey/my_ast"
+	"monkey/my_code"
 	"monkey/my_compiler"
 	"monkey/my_lexer"
+	"monkey/my_module"
 	"monkey/my_object"
 	"monkey/my_parser"
 	"monkey/my_vm"
@@ -13,6 +19,9 @@ type vmEngine struct {
 	compilerConstants   []my_object.Object
 	compilerSymbolTable *my_compiler.SymbolTable
 	vmGlobals           []my_object.Object
+	// limits: nil if unlimited
+	limits *Limits
+	loader *my_module.Loader
 }
 
 func NewVMEngine() Engine {
@@ -20,24 +29,143 @@ func NewVMEngine() Engine {
 		compilerConstants:   make([]my_object.Object, 0),
 		compilerSymbolTable: my_compiler.NewSymbolTable(),
 		vmGlobals:           my_vm.NewGlobals(),
+		loader:              my_module.NewLoader(),
 	}
 }
 
+// NewVMEngineWithLimits: a vm engine enforcing limits on each Evaluate
+func NewVMEngineWithLimits(limits Limits) Engine {
+	vme := NewVMEngine().(*vmEngine)
+	vme.limits = &limits
+	return vme
+}
+
 func (vme *vmEngine) Evaluate(code string) (my_object.Object, error) {
+	return vme.EvaluateContext(context.Background(), code)
+}
+
+func (vme *vmEngine) EvaluateContext(ctx context.Context, code string) (my_object.Object, error) {
 	program, err := parse(code)
 	if err != nil {
 		return nil, err
 	}
+	// globals defined by code failing to compile are forgotten, so are those left unset by code failing to run,
+	// like the evaluator binding only names whose values were evaluated
+	snapshot := vme.compilerSymbolTable.Snapshot()
 	comp := my_compiler.NewWithState(vme.compilerConstants, vme.compilerSymbolTable)
+	comp.SetLoader(vme.loader)
 	err = comp.Compile(program)
+	if err != nil {
+		vme.compilerSymbolTable.Restore(snapshot)
+		return nil, &stageError{stage: ErrCompile, err: err}
+	}
+	byteCode := comp.ByteCode()
+	// functions compiled so far keep referring to their constants by index
+	vme.compilerConstants = byteCode.Constants
+	res, err := run(ctx, my_vm.NewWithState(byteCode, vme.vmGlobals), vme.limits)
+	if err != nil {
+		vme.compilerSymbolTable.Revert(snapshot, func(sym my_compiler.Symbol) bool {
+			return vme.vmGlobals[sym.Index] != nil
+		})
+	}
+	return res, err
+}
+
+// Call: run a main program loading the global or builtin bound to name,
+// pushing args as extra constants and calling it
+func (vme *vmEngine) Call(name string, args ...my_object.Object) (my_object.Object, error) {
+	sym, ok := vme.compilerSymbolTable.Resolve(name)
+	if !ok {
+		return nil, &stageError{stage: ErrCompile, err: fmt.Errorf("undefined variable: %s", name)}
+	}
+	if len(args) > math.MaxUint8 {
+		return nil, &stageError{stage: ErrCompile, err: fmt.Errorf("too many arguments to %s: %d", name, len(args))}
+	}
+	numConstants := len(vme.compilerConstants)
+	constants := append(vme.compilerConstants[:numConstants:numConstants], callArgs(args)...)
+	instructions := make([]my_code.Instructions, 0, len(args)+3)
+	if sym.Scope == my_compiler.BuiltinScope {
+		instructions = append(instructions, my_code.Make(my_code.OpGetBuiltin, sym.Index))
+	} else {
+		instructions = append(instructions, my_code.Make(my_code.OpGetGlobal, sym.Index))
+	}
+	for idx := range args {
+		instructions = append(instructions, my_code.Make(my_code.OpConstant, numConstants+idx))
+	}
+	instructions = append(instructions, my_code.Make(my_code.OpCall, len(args)), my_code.Make(my_code.OpPop))
+	byteCode := &my_compiler.ByteCode{Instructions: my_code.Concat(instructions), Constants: constants}
+	return run(context.Background(), my_vm.NewWithState(byteCode, vme.vmGlobals), vme.limits)
+}
+
+// SetLoader: modules compiled with a previous loader are not compiled again
+func (vme *vmEngine) SetLoader(loader *my_module.Loader) {
+	vme.loader = loader
+}
+
+func (vme *vmEngine) Define(name string, value any) error {
+	return defineValue(vme.define, name, value)
+}
+
+func (vme *vmEngine) RegisterFunc(name string, fn any) error {
+	return registerFunc(vme.define, name, fn)
+}
+
+// define: a global binding known to the compiler and visible to modules, with its value set in the vm globals
+func (vme *vmEngine) define(name string, obj my_object.Object) {
+	sym := vme.compilerSymbolTable.DefineHosted(name)
+	vme.vmGlobals[sym.Index] = obj
+}
+
+// Compile: compile code to bytecode without running it,
+// modules it imports from the working directory included
+func Compile(code string) (*my_compiler.ByteCode, error) {
+	return CompileWithLoader(code, my_module.NewLoader())
+}
+
+// CompileWithLoader: compile code to bytecode without running it, modules it imports with loader included
+func CompileWithLoader(code string, loader *my_module.Loader) (*my_compiler.ByteCode, error) {
+	program, err := parse(code)
 	if err != nil {
 		return nil, err
 	}
-	virtualMachine := my_vm.NewWithState(comp.ByteCode(), vme.vmGlobals)
-	err = virtualMachine.Run()
+	comp := my_compiler.New()
+	comp.SetLoader(loader)
+	err = comp.Compile(program)
 	if err != nil {
+		return nil, &stageError{stage: ErrCompile, err: err}
+	}
+	return comp.ByteCode(), nil
+}
+
+// RunByteCode: run precompiled bytecode in a new vm
+func RunByteCode(byteCode *my_compiler.ByteCode) (my_object.Object, error) {
+	return run(context.Background(), my_vm.New(byteCode), nil)
+}
+
+// RunByteCodeWithLimits: run precompiled bytecode in a new vm enforcing limits
+func RunByteCodeWithLimits(byteCode *my_compiler.ByteCode, limits Limits) (my_object.Object, error) {
+	return run(context.Background(), my_vm.New(byteCode), &limits)
+}
+
+func run(ctx context.Context, virtualMachine *my_vm.VM, limits *Limits) (my_object.Object, error) {
+	if err := ctx.Err(); err != nil {
 		return nil, err
 	}
+	if limits != nil {
+		virtualMachine.SetLimits(*limits)
+	}
+	// contexts never done, like context.Background(), are not worth checking
+	if ctx.Done() != nil {
+		virtualMachine.SetContext(ctx)
+	}
+	err := virtualMachine.Run()
+	if err != nil {
+		var runtimeErr *my_vm.RuntimeError
+		if ctxErr := ctx.Err(); errors.Is(err, ctxErr) && errors.As(err, &runtimeErr) {
+			err = interrupted(runtimeErr, runtimeErr.Pos)
+		}
+		return nil, &stageError{stage: ErrRuntime, err: err}
+	}
 	stackTop := virtualMachine.LastPoppedStackItem()
 	return stackTop, nil
 }
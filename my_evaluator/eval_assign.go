@@ -0,0 +1,58 @@
+package my_evaluator
+
+import (
+	"monkey/my_ast"
+	"monkey/my_object"
+)
+
+// evalAssignExpression: compound assignment, evaluating the target once and yielding the value assigned
+func evalAssignExpression(node *my_ast.AssignExpression, env *my_object.Environment) my_object.Object {
+	switch target := node.Target.(type) {
+	case *my_ast.Identifier:
+		current, ok := env.Get(target.Value)
+		if !ok {
+			return newError("cannot assign to undefined identifier")
+		}
+		value := evalAssignedValue(node, current, env)
+		if isError(value) {
+			return value
+		}
+		reassigned, ok := env.Reassign(target.Value, value)
+		if !ok {
+			return newError("cannot assign to undefined identifier")
+		}
+		return reassigned
+	case *my_ast.IndexExpression:
+		container := Eval(target.Left, env)
+		if isError(container) {
+			return container
+		}
+		index := Eval(target.StartIndex, env)
+		if isError(index) {
+			return index
+		}
+		current := evalElement(container, index)
+		if isError(current) {
+			return current
+		}
+		value := evalAssignedValue(node, current, env)
+		if isError(value) {
+			return value
+		}
+		if err := my_object.SetElement(container, index, value); err != nil {
+			return newError("%s", err)
+		}
+		return value
+	default:
+		return newError("cannot assign to %s", node.Target.String())
+	}
+}
+
+// evalAssignedValue: the current value of the target combined with the value of the node
+func evalAssignedValue(node *my_ast.AssignExpression, current my_object.Object, env *my_object.Environment) my_object.Object {
+	value := Eval(node.Value, env)
+	if isError(value) {
+		return value
+	}
+	return evalInfixExpression(node.Operator, current, value, env)
+}
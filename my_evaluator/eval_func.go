@@ -17,8 +17,44 @@ func evalExpressions(exps []my_ast.Expression, env *my_object.Environment) []my_
 	return args
 }
 
-func evalFunction(fn *my_object.Function, args []my_object.Object) my_object.Object {
-	env := my_object.NewEnclosedEnvironment(fn.Env)
+// Apply: call function with args the way a call expression in env does
+func Apply(function my_object.Object, args []my_object.Object, env *my_object.Environment) my_object.Object {
+	switch function := function.(type) {
+	case *my_object.Builtin:
+		result := function.Fn(args...)
+		// registered Go functions may return nil for no value, the same as callBuiltin of the vm
+		if result == nil {
+			return NULL
+		}
+		if err := env.Meter().Alloc(result); err != nil {
+			return newError("%s", err)
+		}
+		return result
+	case *my_object.Function:
+		// extend env var now to create new set of bindings
+		return evalFunction(function, args, env)
+	default:
+		return newError("not a function: %s", function.Type())
+	}
+}
+
+// MaxCallDepth: function calls nested in each other, the same as frames of the vm,
+// so that unbounded recursion is an error instead of overflowing the Go stack
+const MaxCallDepth = 1024
+
+func evalFunction(fn *my_object.Function, args []my_object.Object, caller *my_object.Environment) my_object.Object {
+	if len(args) != len(fn.Parameters) {
+		return newError("wrong number of arguments: want=%d, got=%d", len(fn.Parameters), len(args))
+	}
+	if caller.CallDepth() >= MaxCallDepth {
+		return newError("stack overflow")
+	}
+	meter := fn.Env.Meter()
+	if err := meter.Enter(); err != nil {
+		return newError("%s", err)
+	}
+	defer meter.Leave()
+	env := my_object.NewCallEnvironment(fn.Env, caller)
 	for idx, param := range fn.Parameters {
 		env.Set(param.Value, args[idx])
 	}
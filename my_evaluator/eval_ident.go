@@ -10,7 +10,7 @@ func evalIdentifier(node *my_ast.Identifier, env *my_object.Environment) my_obje
 	if ok {
 		return val
 	}
-	if fn, ok := builtins[node.Value]; ok {
+	if fn := my_object.GetBuiltinByName(node.Value); fn != nil {
 		return fn
 	}
 	return newError("identifier not found: %s", node.Value)
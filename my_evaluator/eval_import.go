@@ -0,0 +1,71 @@
+package my_evaluator
+
+import (
+	"errors"
+	"monkey/my_ast"
+	"monkey/my_module"
+	"monkey/my_object"
+)
+
+// ModuleImporter: imports a module by evaluating it once in an environment of its own enclosed in host,
+// metered like the importing code; later imports of the module share its namespace
+type ModuleImporter struct {
+	loader *my_module.Loader
+	// host: bindings visible to every module, like those defined by the host program
+	host *my_object.Environment
+	// modules: namespaces of modules evaluated so far by their paths
+	modules map[string]my_object.Object
+	// failed: error of the last import failing, to tell errors of nested imports
+	// from errors of the module importing them
+	failed error
+}
+
+func NewModuleImporter(loader *my_module.Loader, host *my_object.Environment) *ModuleImporter {
+	return &ModuleImporter{loader: loader, host: host, modules: map[string]my_object.Object{}}
+}
+
+func (m *ModuleImporter) Import(name string, env *my_object.Environment) (namespace my_object.Object, err error) {
+	m.failed = nil
+	defer func() {
+		if err != nil {
+			m.failed = err
+		}
+	}()
+	path, err := m.loader.Resolve(name)
+	if err != nil {
+		return nil, err
+	}
+	if namespace, ok := m.modules[path]; ok {
+		return namespace, nil
+	}
+	program, err := m.loader.Enter(path)
+	if err != nil {
+		return nil, err
+	}
+	defer m.loader.Leave()
+	moduleEnv := my_object.NewEnclosedEnvironment(m.host)
+	moduleEnv.SetMeter(env.Meter())
+	moduleEnv.SetImporter(m)
+	if errObj, ok := Eval(program, moduleEnv).(*my_object.Error); ok {
+		if m.failed != nil && m.failed.Error() == errObj.Message {
+			return nil, m.failed
+		}
+		return nil, &my_module.Error{Path: path, Err: errors.New(errObj.Message)}
+	}
+	namespace = moduleEnv.Namespace()
+	m.modules[path] = namespace
+	return namespace, nil
+}
+
+func evalImportStatement(node *my_ast.ImportStatement, env *my_object.Environment) my_object.Object {
+	importer := env.Importer()
+	if importer == nil {
+		return newError("cannot import %q: no module loader", node.Path)
+	}
+	namespace, err := importer.Import(node.Path, env)
+	if err != nil {
+		return newError("%s", err)
+	}
+	env.Set(node.Ident.Value, namespace)
+	return nil
+}
@@ -41,6 +41,14 @@ func evalArrayIndexExpression(array *my_object.Array, indexNode *my_ast.IndexExp
 	if !indexNode.IsSetStartIndex {
 		return newError("array-like indexing with empty expression")
 	}
+	// shortcut: a single index, counting from the end if negative
+	if !indexNode.IsSetEndIndex && !indexNode.IsSetStride {
+		index := Eval(indexNode.StartIndex, env)
+		if isError(index) {
+			return index
+		}
+		return evalElement(array, index)
+	}
 	// parse start index
 	startIdx := int64(0)
 	if indexNode.StartIndex != nil {
@@ -55,15 +63,11 @@ func evalArrayIndexExpression(array *my_object.Array, indexNode *my_ast.IndexExp
 		return newError("index %d out of array with length %d", startIdx, len(array.Elements))
 	}
 	if startIdx < 0 {
-		if (-startIdx) >= int64(len(array.Elements)) {
+		if -startIdx > int64(len(array.Elements)) {
 			return newError("index %d out of array with length %d", startIdx, len(array.Elements))
 		}
 		startIdx = int64(len(array.Elements)) + startIdx
 	}
-	// shortcut: if no end index or stride, return element immediately
-	if !indexNode.IsSetEndIndex && !indexNode.IsSetStride {
-		return array.Elements[startIdx]
-	}
 	// parse end index
 	endIdx := int64(len(array.Elements))
 	if indexNode.EndIndex != nil {
@@ -136,3 +140,34 @@ func evalHashIndexExpression(hash *my_object.Hash, index my_ast.Expression, env
 	}
 	return pair.Value
 }
+
+// evalElement: left[index] with the index evaluated already, the same as a single index of evalIndexExpression
+func evalElement(left, index my_object.Object) my_object.Object {
+	switch left := left.(type) {
+	case *my_object.String:
+		chars := []rune(left.Value)
+		idx, err := my_object.ElementIndex(index, len(chars))
+		if err != nil {
+			return newError("%s", err)
+		}
+		return &my_object.String{Value: string(chars[idx])}
+	case *my_object.Array:
+		idx, err := my_object.ElementIndex(index, len(left.Elements))
+		if err != nil {
+			return newError("%s", err)
+		}
+		return left.Elements[idx]
+	case *my_object.Hash:
+		key, ok := index.(my_object.HashableObject)
+		if !ok {
+			return newError("key type not hashable: %s", index.Type())
+		}
+		pair, ok := left.Pairs[key.HashKey()]
+		if !ok {
+			return NULL
+		}
+		return pair.Value
+	default:
+		return newError("index operator not supported: %s", left.Type())
+	}
+}
@@ -1,13 +1,17 @@
 package my_evaluator
 
 import (
+	"math"
 	"monkey/my_ast"
 	"monkey/my_object"
 )
 
 func evalInfixNode(node *my_ast.InfixExpression, env *my_object.Environment) my_object.Object {
-	if node.Operator == "=" {
+	switch node.Operator {
+	case my_ast.INOP_REASSIGN:
 		return evalReassignInfix(node, env)
+	case my_ast.INOP_AND, my_ast.INOP_OR:
+		return evalLogicalInfix(node, env)
 	}
 
 	leftObj := Eval(node.Left, env)
@@ -18,50 +22,66 @@ func evalInfixNode(node *my_ast.InfixExpression, env *my_object.Environment) my_
 	if isError(rightObj) {
 		return rightObj
 	}
+	return evalInfixExpression(node.Operator, leftObj, rightObj, env)
+}
+
+// evalInfixExpression: apply operator to evaluated operands, converting booleans and integers implicitly
+func evalInfixExpression(
+	operator my_ast.InfixOperator, leftObj, rightObj my_object.Object, env *my_object.Environment,
+) my_object.Object {
 	switch leftObj := leftObj.(type) {
 	case *my_object.Integer:
 		switch rightObj := rightObj.(type) {
 		case *my_object.Integer:
-			return evalIntegerInfixExpression(node.Operator, leftObj, rightObj)
+			return evalIntegerInfixExpression(operator, leftObj, rightObj)
 		case *my_object.Boolean:
-			return evalIntegerInfixExpression(node.Operator, leftObj, booleanToIntObject(rightObj))
+			return evalIntegerInfixExpression(operator, leftObj, booleanToIntObject(rightObj))
 		case *my_object.Float:
-			return evalFloatInfixExpression(node.Operator, integerToFloatObject(leftObj), rightObj)
+			return evalFloatInfixExpression(operator, integerToFloatObject(leftObj), rightObj)
+		case *my_object.String:
+			if operator == "*" {
+				repeated, err := my_object.RepeatString(rightObj, leftObj, env.Meter())
+				if err != nil {
+					return newError("%s", err)
+				}
+				return repeated
+			}
+			return newError("unknown operator: %s%s%s", leftObj.Type(), operator, rightObj.Type())
 		case *my_object.Null:
-			return newError("unknown operator: %s%s%s", leftObj.Type(), node.Operator, rightObj.Type())
+			return newError("unknown operator: %s%s%s", leftObj.Type(), operator, rightObj.Type())
 		default:
-			return newError("unknown operator: %s%s%s", leftObj.Type(), node.Operator, rightObj.Type())
+			return newError("unknown operator: %s%s%s", leftObj.Type(), operator, rightObj.Type())
 		}
 	case *my_object.Boolean:
 		switch rightObj := rightObj.(type) {
 		case *my_object.Integer:
-			return evalIntegerInfixExpression(node.Operator, booleanToIntObject(leftObj), rightObj)
+			return evalIntegerInfixExpression(operator, booleanToIntObject(leftObj), rightObj)
 		case *my_object.Boolean:
-			return evalIntegerInfixExpression(node.Operator, booleanToIntObject(leftObj), booleanToIntObject(rightObj))
+			return evalIntegerInfixExpression(operator, booleanToIntObject(leftObj), booleanToIntObject(rightObj))
 		case *my_object.Float:
-			return evalFloatInfixExpression(node.Operator, booleanToFloatObject(leftObj), rightObj)
+			return evalFloatInfixExpression(operator, booleanToFloatObject(leftObj), rightObj)
 		case *my_object.Null:
-			return newError("unknown operator: %s%s%s", leftObj.Type(), node.Operator, rightObj.Type())
+			return newError("unknown operator: %s%s%s", leftObj.Type(), operator, rightObj.Type())
 		default:
-			return newError("unknown operator: %s%s%s", leftObj.Type(), node.Operator, rightObj.Type())
+			return newError("unknown operator: %s%s%s", leftObj.Type(), operator, rightObj.Type())
 		}
 	case *my_object.Float:
 		switch rightObj := rightObj.(type) {
 		case *my_object.Integer:
-			return evalFloatInfixExpression(node.Operator, leftObj, integerToFloatObject(rightObj))
+			return evalFloatInfixExpression(operator, leftObj, integerToFloatObject(rightObj))
 		case *my_object.Boolean:
-			return evalFloatInfixExpression(node.Operator, leftObj, booleanToFloatObject(rightObj))
+			return evalFloatInfixExpression(operator, leftObj, booleanToFloatObject(rightObj))
 		case *my_object.Float:
-			return evalFloatInfixExpression(node.Operator, leftObj, rightObj)
+			return evalFloatInfixExpression(operator, leftObj, rightObj)
 		case *my_object.Null:
-			return newError("unknown operator: %s%s%s", leftObj.Type(), node.Operator, rightObj.Type())
+			return newError("unknown operator: %s%s%s", leftObj.Type(), operator, rightObj.Type())
 		default:
-			return newError("unknown operator: %s%s%s", leftObj.Type(), node.Operator, rightObj.Type())
+			return newError("unknown operator: %s%s%s", leftObj.Type(), operator, rightObj.Type())
 		}
 	case *my_object.Null:
 		// TODO: NULL==NULL? NULL>=1 yields false or NULL?
 		if _, ok := rightObj.(*my_object.Null); ok {
-			switch node.Operator {
+			switch operator {
 			case "<":
 				fallthrough
 			case "!=":
@@ -75,21 +95,46 @@ func evalInfixNode(node *my_ast.InfixExpression, env *my_object.Environment) my_
 			case "==":
 				return TRUE
 			default:
-				return newError("unknown operator: %s%s%s", leftObj.Type(), node.Operator, rightObj.Type())
+				return newError("unknown operator: %s%s%s", leftObj.Type(), operator, rightObj.Type())
 			}
 		}
 		// an error?
-		return newError("unknown operator: %s%s%s", leftObj.Type(), node.Operator, rightObj.Type())
+		return newError("unknown operator: %s%s%s", leftObj.Type(), operator, rightObj.Type())
 	case *my_object.String:
-		if rightObj, ok := rightObj.(*my_object.String); ok {
-			if node.Operator == "+" {
-				return &my_object.String{Value: leftObj.Value + rightObj.Value}
+		switch rightObj := rightObj.(type) {
+		case *my_object.String:
+			return evalStringInfixExpression(operator, leftObj, rightObj)
+		case *my_object.Integer:
+			if operator == "*" {
+				repeated, err := my_object.RepeatString(leftObj, rightObj, env.Meter())
+				if err != nil {
+					return newError("%s", err)
+				}
+				return repeated
 			}
+			return newError("unknown operator: %s%s%s", leftObj.Type(), operator, rightObj.Type())
+		default:
+			return newError("unknown operator: %s%s%s", leftObj.Type(), operator, rightObj.Type())
 		}
-		return newError("unknown operator: %s%s%s", leftObj.Type(), node.Operator, rightObj.Type())
 	default:
-		return newError("unknown operator: %s%s%s", leftObj.Type(), node.Operator, rightObj.Type())
+		return newError("unknown operator: %s%s%s", leftObj.Type(), operator, rightObj.Type())
+	}
+}
+
+// evalLogicalInfix: && and || yield booleans, evaluating the right side only if the left side does not decide
+func evalLogicalInfix(node *my_ast.InfixExpression, env *my_object.Environment) my_object.Object {
+	left := Eval(node.Left, env)
+	if isError(left) {
+		return left
 	}
+	if isTruthy(left) == (node.Operator == my_ast.INOP_OR) {
+		return nativeBoolToBooleanObject(isTruthy(left))
+	}
+	right := Eval(node.Right, env)
+	if isError(right) {
+		return right
+	}
+	return nativeBoolToBooleanObject(isTruthy(right))
 }
 
 func evalIntegerInfixExpression(
@@ -105,7 +150,39 @@ func evalIntegerInfixExpression(
 	case "*":
 		return &my_object.Integer{Value: leftVal * rightVal}
 	case "/":
+		if rightVal == 0 {
+			return newError("division by zero")
+		}
 		return &my_object.Integer{Value: leftVal / rightVal}
+	case "//":
+		if rightVal == 0 {
+			return newError("division by zero")
+		}
+		return &my_object.Integer{Value: my_object.FloorDiv(leftVal, rightVal)}
+	case "%":
+		if rightVal == 0 {
+			return newError("division by zero")
+		}
+		return &my_object.Integer{Value: my_object.FloorMod(leftVal, rightVal)}
+	case "**":
+		if rightVal < 0 {
+			return newError("negative exponent: %d", rightVal)
+		}
+		return &my_object.Integer{Value: my_object.IntPow(leftVal, rightVal)}
+	case "&":
+		return &my_object.Integer{Value: leftVal & rightVal}
+	case "|":
+		return &my_object.Integer{Value: leftVal | rightVal}
+	case "^":
+		return &my_object.Integer{Value: leftVal ^ rightVal}
+	case "<<", ">>":
+		if rightVal < 0 {
+			return newError("negative shift count: %d", rightVal)
+		}
+		if operator == "<<" {
+			return &my_object.Integer{Value: leftVal << rightVal}
+		}
+		return &my_object.Integer{Value: leftVal >> rightVal}
 	case "<":
 		return nativeBoolToBooleanObject(leftVal < rightVal)
 	case ">":
@@ -137,6 +214,37 @@ func evalFloatInfixExpression(
 		return &my_object.Float{Value: leftVal * rightVal}
 	case "/":
 		return &my_object.Float{Value: leftVal / rightVal}
+	case "//":
+		return &my_object.Float{Value: my_object.FloatFloorDiv(leftVal, rightVal)}
+	case "%":
+		return &my_object.Float{Value: my_object.FloatFloorMod(leftVal, rightVal)}
+	case "**":
+		return &my_object.Float{Value: math.Pow(leftVal, rightVal)}
+	case "<":
+		return nativeBoolToBooleanObject(leftVal < rightVal)
+	case ">":
+		return nativeBoolToBooleanObject(leftVal > rightVal)
+	case "==":
+		return nativeBoolToBooleanObject(leftVal == rightVal)
+	case "!=":
+		return nativeBoolToBooleanObject(leftVal != rightVal)
+	case ">=":
+		return nativeBoolToBooleanObject(leftVal >= rightVal)
+	case "<=":
+		return nativeBoolToBooleanObject(leftVal <= rightVal)
+	default:
+		return newError("unknown operator: %s%s%s", left.Type(), operator, right.Type())
+	}
+}
+
+func evalStringInfixExpression(
+	operator my_ast.InfixOperator, left, right *my_object.String,
+) my_object.Object {
+	leftVal := left.Value
+	rightVal := right.Value
+	switch operator {
+	case "+":
+		return &my_object.String{Value: leftVal + rightVal}
 	case "<":
 		return nativeBoolToBooleanObject(leftVal < rightVal)
 	case ">":
@@ -30,13 +30,13 @@ func evalForloop(node *my_ast.ForExpression, env *my_object.Environment) my_obje
 			if isBreakError(bodyObjEvaluated) {
 				break
 			}
-			if isContinueError(bodyObjEvaluated) {
-				continue
-			}
-			if isError(bodyObjEvaluated) {
-				return bodyObjEvaluated
+			// continue still runs the update statement
+			if !isContinueError(bodyObjEvaluated) {
+				if isError(bodyObjEvaluated) || isReturnValue(bodyObjEvaluated) {
+					return bodyObjEvaluated
+				}
+				bodyObj = bodyObjEvaluated
 			}
-			bodyObj = bodyObjEvaluated
 		}
 		if node.UpdateStmt != nil {
 			updateObj := Eval(node.UpdateStmt, enclosed)
@@ -69,12 +69,11 @@ func evalWhileLoop(node *my_ast.WhileExpression, env *my_object.Environment) my_
 			if isContinueError(bodyObjEvaluated) {
 				continue
 			}
-			if isError(bodyObjEvaluated) {
+			if isError(bodyObjEvaluated) || isReturnValue(bodyObjEvaluated) {
 				return bodyObjEvaluated
 			}
 			bodyObj = bodyObjEvaluated
 		}
-
 	}
 	return bodyObj
 }
@@ -88,13 +87,13 @@ func evalDoWhileLoop(node *my_ast.DoWhileExpression, env *my_object.Environment)
 			if isBreakError(bodyObjEvaluated) {
 				break
 			}
-			if isContinueError(bodyObjEvaluated) {
-				continue
+			// continue still tests the condition
+			if !isContinueError(bodyObjEvaluated) {
+				if isError(bodyObjEvaluated) || isReturnValue(bodyObjEvaluated) {
+					return bodyObjEvaluated
+				}
+				bodyObj = bodyObjEvaluated
 			}
-			if isError(bodyObjEvaluated) {
-				return bodyObjEvaluated
-			}
-			bodyObj = bodyObjEvaluated
 		}
 		if node.TestExpr != nil {
 			testObj := Eval(node.TestExpr, enclosed)
@@ -15,6 +15,8 @@ func evalPrefixNode(node *my_ast.PrefixExpression, env *my_object.Environment) m
 		return evalPrefixOperatorBang(right)
 	case my_ast.PREOP_MINUS:
 		return evalPrefixOperatorMinus(right)
+	case my_ast.PREOP_TILDE:
+		return evalPrefixOperatorTilde(right)
 	}
 	return newError("unknown operator: %s%s", node.Operator, right.Type())
 }
@@ -55,3 +57,15 @@ func evalPrefixOperatorMinus(right my_object.Object) my_object.Object {
 		}
 	}
 }
+
+// evalPrefixOperatorTilde: bitwise not of integers, booleans taken as 0 or 1
+func evalPrefixOperatorTilde(right my_object.Object) my_object.Object {
+	switch right := right.(type) {
+	case *my_object.Integer:
+		return &my_object.Integer{Value: ^right.Value}
+	case *my_object.Boolean:
+		return &my_object.Integer{Value: ^booleanToIntObject(right).Value}
+	default:
+		return newError("unknown operator: %s%s", my_ast.PREOP_TILDE, right.Type())
+	}
+}
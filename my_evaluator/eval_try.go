@@ -0,0 +1,43 @@
+package my_evaluator
+
+import (
+	"monkey/my_ast"
+	"monkey/my_object"
+)
+
+// evalTryExpression: the value of the try block, or of the catch block if the try block raises an error;
+// the finally block runs in any case, replacing the result only if it raises an error or returns itself
+func evalTryExpression(te *my_ast.TryExpression, env *my_object.Environment) my_object.Object {
+	result := Eval(te.Body, my_object.NewEnclosedEnvironment(env))
+	if err, ok := result.(*my_object.Error); ok && te.Catch != nil && isCatchable(err, env) {
+		enclosed := my_object.NewEnclosedEnvironment(env)
+		if te.CatchParam != nil {
+			enclosed.Set(te.CatchParam.Value, my_object.CaughtValue(err))
+		}
+		result = Eval(te.Catch, enclosed)
+	}
+	if result == nil {
+		// a block ending with a statement yields null, the same as my_compiler.compileBlockValue
+		result = NULL
+	}
+	if te.Finally != nil {
+		final := Eval(te.Finally, my_object.NewEnclosedEnvironment(env))
+		if isError(final) || isReturnValue(final) {
+			return final
+		}
+	}
+	return result
+}
+
+func evalThrowStatement(ts *my_ast.ThrowStatement, env *my_object.Environment) my_object.Object {
+	val := Eval(ts.Value, env)
+	if isError(val) {
+		return val
+	}
+	return &my_object.Error{Message: my_object.UncaughtMessage(val), Thrown: val}
+}
+
+// isCatchable: break and continue unwind to their loops, and exceeding limits stops the program for good
+func isCatchable(err *my_object.Error, env *my_object.Environment) bool {
+	return !isBreakError(err) && !isContinueError(err) && env.Meter().Err() == nil
+}
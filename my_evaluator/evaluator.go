@@ -5,7 +5,39 @@ import (
 	"monkey/my_object"
 )
 
+// Eval: evaluate node in env, metered by the meter of env if any
 func Eval(node my_ast.Node, env *my_object.Environment) my_object.Object {
+	meter := env.Meter()
+	if err := meter.Step(); err != nil {
+		meter.StopAt(node.Span().Start)
+		return newError("%s", err)
+	}
+	result := eval(node, env)
+	if err, ok := result.(*my_object.Error); ok && !err.Pos.IsValid() && !isBreakError(err) && !isContinueError(err) {
+		// the innermost node failing locates the error
+		err.Pos = node.Span().Start
+	}
+	if meter != nil && allocates(node) && !isError(result) {
+		if err := meter.Alloc(result); err != nil {
+			return newError("%s", err)
+		}
+	}
+	return result
+}
+
+// allocates: whether evaluating node creates a new object;
+// results of builtin functions are counted where they are called
+func allocates(node my_ast.Node) bool {
+	switch node.(type) {
+	case *my_ast.Integer, *my_ast.Float, *my_ast.StringExpression,
+		*my_ast.ArrayExpression, *my_ast.HashExpression, *my_ast.Function,
+		*my_ast.PrefixExpression, *my_ast.InfixExpression, *my_ast.IndexExpression, *my_ast.AssignExpression:
+		return true
+	}
+	return false
+}
+
+func eval(node my_ast.Node, env *my_object.Environment) my_object.Object {
 	switch node := node.(type) {
 	case *my_ast.Program:
 		return evalProgram(node.Statements, env)
@@ -14,9 +46,11 @@ func Eval(node my_ast.Node, env *my_object.Environment) my_object.Object {
 	case *my_ast.ExpressionStatement:
 		return Eval(node.Expression, env)
 	case *my_ast.ReturnStatement:
-		return &my_object.ReturnValue{
-			Value: Eval(node.Value, env),
+		val := Eval(node.Value, env)
+		if isError(val) {
+			return val
 		}
+		return &my_object.ReturnValue{Value: val}
 	case *my_ast.BreakStatement:
 		return BREAK_ERROR
 	case *my_ast.ContinueStatement:
@@ -28,24 +62,20 @@ func Eval(node my_ast.Node, env *my_object.Environment) my_object.Object {
 		}
 		env.Set(node.Ident.Value, val)
 		return nil
+	case *my_ast.ImportStatement:
+		return evalImportStatement(node, env)
+	case *my_ast.ThrowStatement:
+		return evalThrowStatement(node, env)
 	case *my_ast.CallExpression:
 		function := Eval(node.Function, env)
 		if isError(function) {
 			return function
 		}
 		args := evalExpressions(node.Arguments, env)
-		switch function := function.(type) {
-		case *my_object.Builtin:
-			return function.Fn(args...)
-		case *my_object.Function:
-			if len(args) == 1 && isError(args[0]) {
-				return args[0]
-			}
-			// extend env var now to create new set of bindings
-			return evalFunction(function, args)
-		default:
-			return newError("not a function: %s", function.Type())
+		if _, ok := function.(*my_object.Function); ok && len(args) == 1 && isError(args[0]) {
+			return args[0]
 		}
+		return Apply(function, args, env)
 	case *my_ast.Function:
 		return &my_object.Function{Parameters: node.Parameters, Env: env, Body: node.Body}
 	case *my_ast.IfExpression:
@@ -54,6 +84,8 @@ func Eval(node my_ast.Node, env *my_object.Environment) my_object.Object {
 		return evalPrefixNode(node, env)
 	case *my_ast.InfixExpression:
 		return evalInfixNode(node, env)
+	case *my_ast.AssignExpression:
+		return evalAssignExpression(node, env)
 	case *my_ast.Identifier:
 		return evalIdentifier(node, env)
 	case *my_ast.Boolean:
@@ -89,6 +121,8 @@ func Eval(node my_ast.Node, env *my_object.Environment) my_object.Object {
 		return evalWhileLoop(node, env)
 	case *my_ast.DoWhileExpression:
 		return evalDoWhileLoop(node, env)
+	case *my_ast.TryExpression:
+		return evalTryExpression(node, env)
 	}
 	return newError("unknown node type: %s", node.String())
 }
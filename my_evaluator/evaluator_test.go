@@ -135,6 +135,11 @@ func TestErrorHandling(t *testing.T) {
 	tests := []*testCaseTyped{
 		{"return -if(false){10}", "unknown operator: -NULL", errType},
 		{"return NULL", "identifier not found: NULL", errType},
+		{"let a = 0; 1 / a", "division by zero", errType},
+		{"let f = fn() { return 1 / 0 }; try { f() } catch (e) { e[\"message\"] }", "division by zero", strType},
+		{"try { 1 / 0 } finally { 2 }", "division by zero", errType},
+		{"throw 1", "uncaught exception: 1", errType},
+		{"let a = 0; while (true) { try { break; } catch { a = 1 } }; a", 0, intType},
 	}
 	testCaseWithStruct(t, tests)
 }
@@ -175,6 +180,16 @@ func TestStringEvaluation(t *testing.T) {
 		{`"Hello\tWorld!\n"`, "Hello\tWorld!\n", strType},
 		{"\"Hello\"+ \t\"World\"", "HelloWorld", strType},
 		{"'Hello'- \n'World'", "unknown operator: STRING-STRING", errType},
+		{`"ab" * 3`, "ababab", strType},
+		{`3 * "ab"`, "ababab", strType},
+		{`"ab" * -1`, "", strType},
+		{`"a" == "a"`, true, boolType},
+		{`"a" != "a"`, false, boolType},
+		{`"a" < "b"`, true, boolType},
+		{`"b" <= "a"`, false, boolType},
+		{`"abc" > "abd"`, false, boolType},
+		{`"abc" >= "abc"`, true, boolType},
+		{`"a" == 1`, "unknown operator: STRING==INT", errType},
 	}
 	testCaseWithStruct(t, tests)
 }
@@ -188,6 +203,25 @@ func TestBuiltinLenFunction(t *testing.T) {
 	testCaseWithStruct(t, tests)
 }
 
+func TestBuiltinAppendFunction(t *testing.T) {
+	tests := []*testCaseTyped{
+		{"append([], 1)", []any{1}, arrType},
+		{"append([1, 2, 3], 4)", []any{1, 2, 3, 4}, arrType},
+		{"append(1, 1)", "first argument to `append` must be ARRAY: got=INT", errType},
+	}
+	testCaseWithStruct(t, tests)
+}
+
+func TestBuiltinPutFunction(t *testing.T) {
+	my_object.RegisterBuiltin("nothing", func(args ...my_object.Object) my_object.Object { return nil })
+	tests := []*testCaseTyped{
+		{"put(put(1))", nil, nullType},
+		{"let x = put(1); put(x); x", nil, nullType},
+		{"nothing() == null", true, boolType},
+	}
+	testCaseWithStruct(t, tests)
+}
+
 func TestArrayEvaluation(t *testing.T) {
 	tests := []*testCaseTyped{
 		{"[1, 2*2, 3+3]", []any{1, 4, 6}, arrType},
@@ -201,6 +235,11 @@ func TestArrayIndexExpression(t *testing.T) {
 		{"[1, 2, 3, 4][0]", 1, intType},
 		{"[1, 2, 3, 4][-1]", 4, intType},
 		{"[1, 2, 3, 4][4]", "index 4 out of array with length 4", errType},
+		{"[1, 2, 3, 4][-4]", 1, intType},
+		{"[1, 2, 3, 4][-5]", "index -5 out of array with length 4", errType},
+		{"[5][-1]", 5, intType},
+		{"[1, 2, 3, 4][-4:]", []any{1, 2, 3, 4}, arrType},
+		{"[1, 2, 3, 4][-5:]", "index -5 out of array with length 4", errType},
 		{"[1, 2, 3, 4][1:2]", []any{2}, arrType},
 		{"[1, 2, 3, 4][1:3]", []any{2, 3}, arrType},
 		{"[1, 2, 3, 4][2:5]", []any{3, 4}, arrType},
@@ -258,7 +297,9 @@ func TestHashIndexExpression(t *testing.T) {
 func TestForLoopExpression(t *testing.T) {
 	tests := []*testCaseTyped{
 		{"for(;false;){}", "", nullType},
-		{"let b = 1;for(let a=1;a<3; a = a+1){let b = a; return b;}", 2, intType},
+		{"let b = 1;for(let a=1;a<3; a = a+1){let b = a; return b;}", 1, intType},
+		{"let f = fn(){ for(let a=1;a<3; a = a+1){ if(a==2){ return a*10; } } }; f()", 20, intType},
+		{"let b = 0;for(let a=0;a<3; a = a+1){ if(a==1){ continue; } b = b + a; }; b", 2, intType},
 		{"let b = 1;for(let a=1;a<3;){a = a+1; b= a; b}", 3, intType},
 	}
 	testCaseWithStruct(t, tests)
@@ -278,6 +319,7 @@ func TestDoWhileLoopExpression(t *testing.T) {
 	tests := []*testCaseTyped{
 		{"do{1}while(false)", 1, intType},
 		{"let a=1;do{a=a+1; a;}while(a<3)", 3, intType},
+		{"let a=1;do{a=a+1; continue;}while(a<3);a", 3, intType},
 	}
 	testCaseWithStruct(t, tests)
 }
@@ -291,6 +333,49 @@ func TestGTELTEExpression(t *testing.T) {
 	testCaseWithStruct(t, tests)
 }
 
+func TestLogicalExpression(t *testing.T) {
+	tests := []*testCaseTyped{
+		{"true && 1", true, boolType},
+		{"1 && null", false, boolType},
+		{"false || 0", true, boolType},
+		{"null || false", false, boolType},
+		{"1 < 2 && 2 < 3 || false", true, boolType},
+		// the right side is left alone once the left side decides
+		{"let a = 0; false && (a = 1); true || (a = 2); a", 0, intType},
+		{"false && undefinedIdent", false, boolType},
+		{"true && undefinedIdent", "identifier not found: undefinedIdent", errType},
+	}
+	testCaseWithStruct(t, tests)
+}
+
+func TestArithmeticBitwiseExpression(t *testing.T) {
+	tests := []*testCaseTyped{
+		{"7 % 3", 1, intType},
+		{"-7 % 3", 2, intType},
+		{"7 % -3", -2, intType},
+		{"-7.5 % 2", 0.5, floatType},
+		{"-7 // 2", -4, intType},
+		{"7.0 // 2", 3.0, floatType},
+		{"2 ** 3 ** 2", 512, intType},
+		{"-2 ** 2", -4, intType},
+		{"~2 * 3", -9, intType},
+		{"2 ** 0.5 * 2 ** 0.5", 2.0000000000000004, floatType},
+		{"true ** 2 + true % 2", 2, intType},
+		{"6 & 3 | 8 ^ 1", 11, intType},
+		{"1 << 4 >> 2", 4, intType},
+		{"(-16) >> 2", -4, intType},
+		{"~5", -6, intType},
+		{"~true", -2, intType},
+		{"1 % 0", "division by zero", errType},
+		{"1 // false", "division by zero", errType},
+		{"2 ** -1", "negative exponent: -1", errType},
+		{"1 << -1", "negative shift count: -1", errType},
+		{"1.5 & 1", "unknown operator: FLOAT&FLOAT", errType},
+		{"~1.5", "unknown operator: ~FLOAT", errType},
+	}
+	testCaseWithStruct(t, tests)
+}
+
 func TestReassignExpression(t *testing.T) {
 	tests := []*testCaseTyped{
 		{"let a= true; a=2; a", 2, intType},
@@ -302,6 +387,32 @@ func TestReassignExpression(t *testing.T) {
 	testCaseWithStruct(t, tests)
 }
 
+func TestAssignExpression(t *testing.T) {
+	tests := []*testCaseTyped{
+		{"let a = 1; a += 2; a", 3, intType},
+		{"let a = 7; a -= 2; a *= 3; a %= 4", 3, intType},
+		{"let a = 7.0; a /= 2", 3.5, floatType},
+		{"let s = \"a\"; s += \"b\"; s", "ab", strType},
+		{"let i = 0; while (i < 5) { i++; }; i", 5, intType},
+		{"let i = 5; i--; i", 4, intType},
+		// ++ and -- followed by an operand are two signs
+		{"1--1", 2, intType},
+		{"let a = 3; a--1", 4, intType},
+		{"let a = 1; if (true) { a += 1 }; a", 2, intType},
+		{"let a = [1, 2, 3]; a[0] += 10; a[-1]--; a[0] + a[2]", 13, intType},
+		{"let h = {\"n\": 1}; h[\"n\"] *= 5; h[\"n\"]", 5, intType},
+		{"let a = [1]; let b = a; a[0] += 1; b[0]", 2, intType},
+		// the target is evaluated once
+		{"let n = 0; let a = [0]; let f = fn() { n++; a }; f()[0] += 1; [n, a[0]]", []any{1, 1}, arrType},
+		{"a += 1", "cannot assign to undefined identifier", errType},
+		{"let s = \"a\"; s[0] += \"b\"", "index assignment not supported: STRING", errType},
+		{"let a = [1]; a[1] += 1", "index 1 out of array with length 1", errType},
+		{"let h = {}; h[\"k\"] += 1", "unknown operator: NULL+INT", errType},
+		{"let a = [1]; a[0] /= 0", "division by zero", errType},
+	}
+	testCaseWithStruct(t, tests)
+}
+
 func TestBreakContinueStatements(t *testing.T) {
 	tests := []*testCaseTyped{
 		{"break", "break outside loop", errType},
@@ -24,6 +24,10 @@ func isError(obj my_object.Object) bool {
 	return false
 }
 
+func isReturnValue(obj my_object.Object) bool {
+	return obj != nil && obj.Type() == my_object.RETURN_VALUE_OBJ
+}
+
 func tryUnwrapReturnValue(obj my_object.Object) my_object.Object {
 	if returnVal, ok := obj.(*my_object.ReturnValue); ok {
 		return returnVal.Value
@@ -72,7 +72,8 @@ func testTokensWithInput(t *testing.T, input string, expects []*token.Token) {
 			break
 		}
 		// fmt.Printf("t: type: %s: literal: %s\n", t.Type, t.Literal)
-		assert.Equal(t, *exp, tok)
+		assert.Equal(t, exp.Type, tok.Type)
+		assert.Equal(t, exp.Literal, tok.Literal)
 	}
 }
 
@@ -95,3 +96,25 @@ func TestMapToken(t *testing.T) {
 	}
 	testTokensWithInput(t, input, expects)
 }
+
+func TestTokenSpan(t *testing.T) {
+	input := "let a = 1.5;\n\tfoo(\"bar\")\n"
+	expects := []token.Span{
+		{Start: token.Position{Offset: 0, Line: 1, Column: 1}, End: token.Position{Offset: 3, Line: 1, Column: 4}},
+		{Start: token.Position{Offset: 4, Line: 1, Column: 5}, End: token.Position{Offset: 5, Line: 1, Column: 6}},
+		{Start: token.Position{Offset: 6, Line: 1, Column: 7}, End: token.Position{Offset: 7, Line: 1, Column: 8}},
+		{Start: token.Position{Offset: 8, Line: 1, Column: 9}, End: token.Position{Offset: 11, Line: 1, Column: 12}},
+		{Start: token.Position{Offset: 11, Line: 1, Column: 12}, End: token.Position{Offset: 12, Line: 1, Column: 13}},
+		{Start: token.Position{Offset: 14, Line: 2, Column: 2}, End: token.Position{Offset: 17, Line: 2, Column: 5}},
+		{Start: token.Position{Offset: 17, Line: 2, Column: 5}, End: token.Position{Offset: 18, Line: 2, Column: 6}},
+		{Start: token.Position{Offset: 18, Line: 2, Column: 6}, End: token.Position{Offset: 23, Line: 2, Column: 11}},
+		{Start: token.Position{Offset: 23, Line: 2, Column: 11}, End: token.Position{Offset: 24, Line: 2, Column: 12}},
+		{Start: token.Position{Offset: 25, Line: 3, Column: 1}, End: token.Position{Offset: 25, Line: 3, Column: 1}},
+	}
+	lexer := New(input)
+	for _, exp := range expects {
+		tok := lexer.NextToken()
+		assert.Equal(t, exp, tok.Span, "token %s", tok.Literal)
+	}
+	assert.Equal(t, token.Position{Offset: 25, Line: 3, Column: 1}, lexer.NextToken().Span.Start)
+}
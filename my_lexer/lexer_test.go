@@ -33,6 +33,14 @@ a<=1;
 a>=1;
 break;
 continue;
+import "lib.monkey";
+try {} catch (e) { throw e } finally {}
+a && b || c;
+a % b ** c // d;
+a & b | c ^ ~d << 1 >> 2;
+a += b -= c *= d /= e %= f;
+a++; a--;
+1--1 ++ )
 `
 
 	tests := []struct {
@@ -148,6 +156,73 @@ continue;
 		{token.SEMICOLON, ";"},
 		{token.CONTINUE, "continue"},
 		{token.SEMICOLON, ";"},
+		{token.IMPORT, "import"},
+		{token.STRING, "lib.monkey"},
+		{token.SEMICOLON, ";"},
+		{token.TRY, "try"},
+		{token.LBRACE, "{"},
+		{token.RBRACE, "}"},
+		{token.CATCH, "catch"},
+		{token.LPAREN, "("},
+		{token.IDENT, "e"},
+		{token.RPAREN, ")"},
+		{token.LBRACE, "{"},
+		{token.THROW, "throw"},
+		{token.IDENT, "e"},
+		{token.RBRACE, "}"},
+		{token.FINALLY, "finally"},
+		{token.LBRACE, "{"},
+		{token.RBRACE, "}"},
+		{token.IDENT, "a"},
+		{token.AND, "&&"},
+		{token.IDENT, "b"},
+		{token.OR, "||"},
+		{token.IDENT, "c"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "a"},
+		{token.PERCENT, "%"},
+		{token.IDENT, "b"},
+		{token.POWER, "**"},
+		{token.IDENT, "c"},
+		{token.FLOOR_SLASH, "//"},
+		{token.IDENT, "d"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "a"},
+		{token.AMPERSAND, "&"},
+		{token.IDENT, "b"},
+		{token.PIPE, "|"},
+		{token.IDENT, "c"},
+		{token.CARET, "^"},
+		{token.TILDE, "~"},
+		{token.IDENT, "d"},
+		{token.SHL, "<<"},
+		{token.INT, "1"},
+		{token.SHR, ">>"},
+		{token.INT, "2"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "a"},
+		{token.PLUS_ASSIGN, "+="},
+		{token.IDENT, "b"},
+		{token.MINUS_ASSIGN, "-="},
+		{token.IDENT, "c"},
+		{token.ASTERISK_ASSIGN, "*="},
+		{token.IDENT, "d"},
+		{token.SLASH_ASSIGN, "/="},
+		{token.IDENT, "e"},
+		{token.PERCENT_ASSIGN, "%="},
+		{token.IDENT, "f"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "a"},
+		{token.INCREMENT, "++"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "a"},
+		{token.DECREMENT, "--"},
+		{token.SEMICOLON, ";"},
+		{token.INT, "1"},
+		{token.DECREMENT, "--"},
+		{token.INT, "1"},
+		{token.INCREMENT, "++"},
+		{token.RPAREN, ")"},
 		{token.EOF, ""},
 	}
 
@@ -0,0 +1,106 @@
+package my_module
+
+import (
+	"errors"
+	"fmt"
+	"monkey/my_ast"
+	lexer "monkey/my_lexer"
+	"monkey/my_parser"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrImport: a module cannot be found, read or parsed, or is imported in a cycle
+var ErrImport = errors.New("import error")
+
+// Error: error loading the module at Path, matching ErrImport as well as Err
+type Error struct {
+	Path string
+	Err  error
+}
+
+func (e *Error) Error() string { return fmt.Sprintf("%s: %s", e.Path, e.Err) }
+
+func (e *Error) Unwrap() error { return e.Err }
+
+func (e *Error) Is(target error) bool { return target == ErrImport }
+
+// Loader: finds and parses modules for import statements on behalf of an engine;
+// modules being loaded form a stack, the innermost one importing the next module
+type Loader struct {
+	// SearchPaths: directories to look modules up in, in order
+	SearchPaths []string
+	loading     []string
+}
+
+// NewLoader: a loader looking modules up in searchPaths, the working directory if none
+func NewLoader(searchPaths ...string) *Loader {
+	if len(searchPaths) == 0 {
+		searchPaths = []string{"."}
+	}
+	return &Loader{SearchPaths: searchPaths}
+}
+
+// Resolve: absolute path of the module imported as name, the extension .monkey being optional;
+// names starting with ./ or ../ are relative to the importing module,
+// or the first search path for code outside modules; other names are looked up in search paths
+func (l *Loader) Resolve(name string) (string, error) {
+	file := filepath.FromSlash(name)
+	if filepath.Ext(file) != my_parser.ModuleExt {
+		file += my_parser.ModuleExt
+	}
+	if filepath.IsAbs(file) {
+		if info, err := os.Stat(file); err != nil || info.IsDir() {
+			return "", &Error{Path: name, Err: errors.New("module not found")}
+		}
+		return filepath.Clean(file), nil
+	}
+	var dirs []string
+	switch {
+	case strings.HasPrefix(name, "./") || strings.HasPrefix(name, "../"):
+		if n := len(l.loading); n > 0 {
+			dirs = []string{filepath.Dir(l.loading[n-1])}
+		} else {
+			dirs = l.SearchPaths[:1]
+		}
+	default:
+		dirs = l.SearchPaths
+	}
+	for _, dir := range dirs {
+		path, err := filepath.Abs(filepath.Join(dir, file))
+		if err != nil {
+			return "", &Error{Path: name, Err: err}
+		}
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path, nil
+		}
+	}
+	return "", &Error{Path: name, Err: fmt.Errorf("module not found in %s", strings.Join(dirs, string(filepath.ListSeparator)))}
+}
+
+// Enter: parse the module at path and mark it as being loaded until the matching Leave;
+// entering a module already being loaded is an import cycle
+func (l *Loader) Enter(path string) (*my_ast.Program, error) {
+	for idx, loading := range l.loading {
+		if loading == path {
+			cycle := append(append([]string{}, l.loading[idx:]...), path)
+			return nil, &Error{Path: path, Err: fmt.Errorf("import cycle: %s", strings.Join(cycle, " -> "))}
+		}
+	}
+	code, err := os.ReadFile(path)
+	if err != nil {
+		return nil, &Error{Path: path, Err: err}
+	}
+	p := my_parser.New(lexer.New(string(code)))
+	program := p.Parse()
+	if err := p.Error(); err != nil {
+		return nil, &Error{Path: path, Err: err}
+	}
+	l.loading = append(l.loading, path)
+	return program, nil
+}
+
+func (l *Loader) Leave() {
+	l.loading = l.loading[:len(l.loading)-1]
+}
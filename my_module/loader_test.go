@@ -0,0 +1,98 @@
+package my_module
+
+import (
+	"errors"
+	"monkey/my_parser"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeModules(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	for name, code := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		assert.NoError(t, os.WriteFile(path, []byte(code), 0o644))
+	}
+	return dir
+}
+
+func TestLoaderResolve(t *testing.T) {
+	dir := writeModules(t, map[string]string{
+		"main/util.monkey":    "",
+		"main/lib/a.monkey":   "",
+		"vendor/util.monkey":  "",
+		"vendor/extra.monkey": "",
+	})
+	main, vendor := filepath.Join(dir, "main"), filepath.Join(dir, "vendor")
+	l := NewLoader(main, vendor)
+
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{"util", filepath.Join(main, "util.monkey")},
+		{"util.monkey", filepath.Join(main, "util.monkey")},
+		{"lib/a", filepath.Join(main, "lib", "a.monkey")},
+		{"extra", filepath.Join(vendor, "extra.monkey")},
+		{"./util", filepath.Join(main, "util.monkey")},
+		{filepath.Join(vendor, "util.monkey"), filepath.Join(vendor, "util.monkey")},
+	}
+	for _, tt := range tests {
+		path, err := l.Resolve(tt.name)
+		if assert.NoError(t, err, "name: %s", tt.name) {
+			assert.Equal(t, tt.expected, path, "name: %s", tt.name)
+		}
+	}
+
+	// relative names are relative to the module being loaded only
+	_, err := l.Enter(filepath.Join(main, "lib", "a.monkey"))
+	assert.NoError(t, err)
+	_, err = l.Resolve("./util")
+	assert.ErrorIs(t, err, ErrImport)
+	path, err := l.Resolve("../util")
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(main, "util.monkey"), path)
+	l.Leave()
+
+	for _, name := range []string{"missing", "./extra", "lib", filepath.Join(dir, "missing.monkey")} {
+		_, err := l.Resolve(name)
+		assert.ErrorIs(t, err, ErrImport, "name: %s", name)
+	}
+}
+
+func TestLoaderEnter(t *testing.T) {
+	dir := writeModules(t, map[string]string{
+		"a.monkey":      `import b; let x = 1;`,
+		"b.monkey":      `import a;`,
+		"broken.monkey": `let = 1;`,
+	})
+	l := NewLoader(dir)
+	a, b := filepath.Join(dir, "a.monkey"), filepath.Join(dir, "b.monkey")
+
+	program, err := l.Enter(a)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(program.Statements))
+	_, err = l.Enter(b)
+	assert.NoError(t, err)
+	_, err = l.Enter(a)
+	assert.ErrorIs(t, err, ErrImport)
+	assert.EqualError(t, err, a+": import cycle: "+a+" -> "+b+" -> "+a)
+	l.Leave()
+	l.Leave()
+
+	// a module can be entered again once left
+	_, err = l.Enter(a)
+	assert.NoError(t, err)
+	l.Leave()
+
+	_, err = l.Enter(filepath.Join(dir, "broken.monkey"))
+	assert.ErrorIs(t, err, ErrImport)
+	assert.ErrorIs(t, err, my_parser.ErrParseError)
+	var moduleErr *Error
+	assert.True(t, errors.As(err, &moduleErr))
+	assert.Equal(t, filepath.Join(dir, "broken.monkey"), moduleErr.Path)
+}
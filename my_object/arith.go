@@ -0,0 +1,63 @@
+package my_object
+
+import (
+	"math"
+	"strings"
+)
+
+// Arithmetic shared by both engines where Go operators differ from monkey ones:
+// // and % round towards negative infinity like python, so that a == (a // b) * b + a % b
+// with the result of % taking the sign of b. Callers report division by zero,
+// negative exponents and negative shift counts of integers before calling.
+
+func FloorDiv(a, b int64) int64 {
+	q := a / b
+	if a%b != 0 && (a < 0) != (b < 0) {
+		q--
+	}
+	return q
+}
+
+func FloorMod(a, b int64) int64 {
+	m := a % b
+	if m != 0 && (m < 0) != (b < 0) {
+		m += b
+	}
+	return m
+}
+
+func FloatFloorDiv(a, b float64) float64 {
+	return math.Floor(a / b)
+}
+
+func FloatFloorMod(a, b float64) float64 {
+	m := math.Mod(a, b)
+	if m != 0 && (m < 0) != (b < 0) {
+		m += b
+	}
+	return m
+}
+
+// IntPow: a to the power of b by squaring, wrapping around on overflow like other integer operators
+func IntPow(a, b int64) int64 {
+	result := int64(1)
+	for ; b > 0; b >>= 1 {
+		if b&1 == 1 {
+			result *= a
+		}
+		a *= a
+	}
+	return result
+}
+
+// RepeatString: s repeated n times like python, non-positive n yields "";
+// the result is checked against the meter before it is allocated
+func RepeatString(s *String, n *Integer, meter *Meter) (*String, error) {
+	if n.Value <= 0 {
+		return &String{Value: ""}, nil
+	}
+	if err := meter.CanAlloc(RepeatSize(len(s.Value), n.Value)); err != nil {
+		return nil, err
+	}
+	return &String{Value: strings.Repeat(s.Value, int(n.Value))}, nil
+}
@@ -0,0 +1,90 @@
+package my_object
+
+import "fmt"
+
+// BuiltinDefinition: a builtin function with the name it is called by in monkey code
+type BuiltinDefinition struct {
+	Name    string
+	Builtin *Builtin
+}
+
+// Builtins: registry shared by the evaluator and the vm;
+// index of each builtin is stable as it is compiled into bytecode, so new ones are only appended
+var Builtins = []BuiltinDefinition{
+	{
+		"len",
+		&Builtin{Fn: func(args ...Object) Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments: got=%d, want=1", len(args))
+			}
+			switch arg := args[0].(type) {
+			case *String:
+				return &Integer{Value: int64(len(arg.Value))}
+			case *Array:
+				return &Integer{Value: int64(len(arg.Elements))}
+			default:
+				return newError("argument to len not supported: got %s", arg.Type())
+			}
+		}},
+	},
+	{
+		"append",
+		&Builtin{Fn: func(args ...Object) Object {
+			if len(args) != 2 {
+				return newError("wrong number of arguments: got=%d, want=2", len(args))
+			}
+			if args[0].Type() != ARRAY_OBJ {
+				return newError("first argument to `append` must be ARRAY: got=%s", args[0].Type())
+			}
+			elements := args[0].(*Array).Elements
+			newElements := make([]Object, len(elements)+1)
+			copy(newElements, elements)
+			newElements[len(elements)] = args[1]
+			return &Array{Elements: newElements}
+		}},
+	},
+	{
+		"put",
+		&Builtin{Fn: func(args ...Object) Object {
+			fmt.Println()
+			for _, arg := range args {
+				fmt.Print(arg.String())
+			}
+			return NULL
+		}},
+	},
+}
+
+// LookupBuiltin: find index of the builtin in the registry by name
+func LookupBuiltin(name string) (int, bool) {
+	for idx, def := range Builtins {
+		if def.Name == name {
+			return idx, true
+		}
+	}
+	return -1, false
+}
+
+// GetBuiltinByName: nil if no builtin is registered with the name
+func GetBuiltinByName(name string) *Builtin {
+	if idx, ok := LookupBuiltin(name); ok {
+		return Builtins[idx].Builtin
+	}
+	return nil
+}
+
+// RegisterBuiltin: make a Go function callable by name from monkey code in both engines;
+// an existing builtin with the same name is replaced in place, keeping its index
+func RegisterBuiltin(name string, fn BuiltinFunction) int {
+	builtin := &Builtin{Fn: fn}
+	if idx, ok := LookupBuiltin(name); ok {
+		Builtins[idx].Builtin = builtin
+		return idx
+	}
+	Builtins = append(Builtins, BuiltinDefinition{Name: name, Builtin: builtin})
+	return len(Builtins) - 1
+}
+
+func newError(format string, a ...any) *Error {
+	return &Error{Message: fmt.Sprintf(format, a...)}
+}
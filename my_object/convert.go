@@ -0,0 +1,430 @@
+package my_object
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+)
+
+// ErrUnsupportedValue: a Go value or type without a monkey counterpart, or an object not fitting a Go type
+var ErrUnsupportedValue = errors.New("unsupported value")
+
+var (
+	objectType = reflect.TypeOf((*Object)(nil)).Elem()
+	errorType  = reflect.TypeOf((*error)(nil)).Elem()
+	anyType    = reflect.TypeOf((*any)(nil)).Elem()
+)
+
+// conversionError: reads as its message alone, matching ErrUnsupportedValue
+type conversionError struct {
+	msg string
+}
+
+func (e *conversionError) Error() string { return e.msg }
+
+func (e *conversionError) Unwrap() error { return ErrUnsupportedValue }
+
+func conversionErrorf(format string, a ...any) error {
+	return &conversionError{msg: fmt.Sprintf(format, a...)}
+}
+
+// FromGo: convert a Go value to an object.
+//
+//	nil, nil pointers, slices and maps      NULL
+//	Object                                  itself
+//	bool                                    BOOLEAN
+//	signed and unsigned integers            INT, failing if it overflows
+//	float32, float64                        FLOAT
+//	string                                  STRING
+//	slices and arrays                       ARRAY
+//	maps with hashable keys                 HASH
+//	structs                                 HASH keyed by field names, see StructFieldName
+//	funcs                                   BUILTIN, see FromGoFunc
+//	pointers and interfaces                 what they point to
+//
+// Values containing themselves through pointers, maps or slices are not supported.
+func FromGo(value any) (Object, error) {
+	return fromGo(reflect.ValueOf(value), "value", map[visit]bool{})
+}
+
+// ToGo: convert obj to the Go value target points to, the reverse of FromGo;
+// targets of interface type any take the natural Go value of obj:
+// int64, float64, bool, string, nil, []any,
+// map[string]any for hashes keyed by strings only and map[any]any for other hashes;
+// a nil obj converts like NULL
+func ToGo(obj Object, target any) error {
+	ptr := reflect.ValueOf(target)
+	if ptr.Kind() != reflect.Pointer || ptr.IsNil() {
+		return fmt.Errorf("%w: target must be a non-nil pointer: got %T", ErrUnsupportedValue, target)
+	}
+	value, err := toGo(obj, ptr.Type().Elem())
+	if err != nil {
+		return err
+	}
+	ptr.Elem().Set(value)
+	return nil
+}
+
+// FromGoFunc: make a builtin out of a Go function, converting arguments to its parameters by ToGo
+// and its result back by FromGo; a trailing error result is raised as a monkey error,
+// so are arguments not fitting the parameters.
+func FromGoFunc(name string, fn any) (*Builtin, error) {
+	value := reflect.ValueOf(fn)
+	if value.Kind() != reflect.Func || value.IsNil() {
+		return nil, fmt.Errorf("%w: %s is not a function: %T", ErrUnsupportedValue, name, fn)
+	}
+	return wrapFunc(name, value)
+}
+
+func wrapFunc(name string, fn reflect.Value) (*Builtin, error) {
+	fnType := fn.Type()
+	numOut := fnType.NumOut()
+	returnsError := numOut > 0 && fnType.Out(numOut-1) == errorType
+	if returnsError {
+		numOut--
+	}
+	if numOut > 1 {
+		return nil, fmt.Errorf("%w: %s returns more than one value besides error", ErrUnsupportedValue, name)
+	}
+	numIn := fnType.NumIn()
+	return &Builtin{Fn: func(args ...Object) Object {
+		if fnType.IsVariadic() && len(args) < numIn-1 {
+			return newError("wrong number of arguments to `%s`: got=%d, want at least %d", name, len(args), numIn-1)
+		}
+		if !fnType.IsVariadic() && len(args) != numIn {
+			return newError("wrong number of arguments to `%s`: got=%d, want=%d", name, len(args), numIn)
+		}
+		in := make([]reflect.Value, len(args))
+		for idx, arg := range args {
+			var paramType reflect.Type
+			if fnType.IsVariadic() && idx >= numIn-1 {
+				paramType = fnType.In(numIn - 1).Elem()
+			} else {
+				paramType = fnType.In(idx)
+			}
+			value, err := toGo(arg, paramType)
+			if err != nil {
+				return newError("argument %d to `%s`: %s", idx+1, name, err)
+			}
+			in[idx] = value
+		}
+		out := fn.Call(in)
+		if returnsError && !out[len(out)-1].IsNil() {
+			return newError("%s", out[len(out)-1].Interface())
+		}
+		if numOut == 0 {
+			return NULL
+		}
+		obj, err := fromGo(out[0], "result", map[visit]bool{})
+		if err != nil {
+			return newError("result of `%s`: %s", name, err)
+		}
+		return obj
+	}}, nil
+}
+
+// StructFieldName: key of an exported struct field in a hash,
+// the name in its `monkey:"name"` tag or the field name as is; empty if tagged `monkey:"-"`
+func StructFieldName(field reflect.StructField) string {
+	if !field.IsExported() {
+		return ""
+	}
+	tag := field.Tag.Get("monkey")
+	if tag == "-" {
+		return ""
+	}
+	if name, _, _ := strings.Cut(tag, ","); name != "" {
+		return name
+	}
+	return field.Name
+}
+
+// visit: a pointer, map or slice under conversion, as in reflect.DeepEqual
+type visit struct {
+	ptr uintptr
+	typ reflect.Type
+	len int
+}
+
+// fromGo: path names the value in errors, like value[1].Name;
+// visiting holds pointers, maps and slices enclosing value, which value must not refer to again
+func fromGo(value reflect.Value, path string, visiting map[visit]bool) (Object, error) {
+	if !value.IsValid() {
+		return NULL, nil
+	}
+	if value.Type().Implements(objectType) {
+		if (value.Kind() == reflect.Pointer || value.Kind() == reflect.Interface) && value.IsNil() {
+			return NULL, nil
+		}
+		return value.Interface().(Object), nil
+	}
+	switch value.Kind() {
+	case reflect.Pointer, reflect.Map, reflect.Slice:
+		if value.IsNil() {
+			break
+		}
+		v := visit{ptr: value.Pointer(), typ: value.Type()}
+		if value.Kind() == reflect.Slice {
+			v.len = value.Len()
+		}
+		if visiting[v] {
+			return nil, conversionErrorf("%s: cyclic %s not supported", path, value.Type())
+		}
+		visiting[v] = true
+		defer delete(visiting, v)
+	}
+	switch value.Kind() {
+	case reflect.Bool:
+		return NativeBool(value.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &Integer{Value: value.Int()}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if value.Uint() > math.MaxInt64 {
+			return nil, conversionErrorf("%s: %d overflows INT", path, value.Uint())
+		}
+		return &Integer{Value: int64(value.Uint())}, nil
+	case reflect.Float32, reflect.Float64:
+		return &Float{Value: value.Float()}, nil
+	case reflect.String:
+		return &String{Value: value.String()}, nil
+	case reflect.Slice, reflect.Array:
+		if value.Kind() == reflect.Slice && value.IsNil() {
+			return NULL, nil
+		}
+		elements := make([]Object, value.Len())
+		for i := range elements {
+			element, err := fromGo(value.Index(i), fmt.Sprintf("%s[%d]", path, i), visiting)
+			if err != nil {
+				return nil, err
+			}
+			elements[i] = element
+		}
+		return &Array{Elements: elements}, nil
+	case reflect.Map:
+		if value.IsNil() {
+			return NULL, nil
+		}
+		hash := &Hash{Pairs: make(map[HashKey]HashPair, value.Len())}
+		iter := value.MapRange()
+		for iter.Next() {
+			key, err := fromGo(iter.Key(), path+" key", visiting)
+			if err != nil {
+				return nil, err
+			}
+			element, err := fromGo(iter.Value(), fmt.Sprintf("%s[%s]", path, key), visiting)
+			if err != nil {
+				return nil, err
+			}
+			if err := setPair(hash, key, element); err != nil {
+				return nil, conversionErrorf("%s: %s", path, err)
+			}
+		}
+		return hash, nil
+	case reflect.Struct:
+		hash := &Hash{Pairs: map[HashKey]HashPair{}}
+		for i := 0; i < value.NumField(); i++ {
+			name := StructFieldName(value.Type().Field(i))
+			if name == "" {
+				continue
+			}
+			element, err := fromGo(value.Field(i), path+"."+name, visiting)
+			if err != nil {
+				return nil, err
+			}
+			setPair(hash, &String{Value: name}, element)
+		}
+		return hash, nil
+	case reflect.Pointer, reflect.Interface:
+		if value.IsNil() {
+			return NULL, nil
+		}
+		return fromGo(value.Elem(), path, visiting)
+	case reflect.Func:
+		if value.IsNil() {
+			return NULL, nil
+		}
+		return wrapFunc(path, value)
+	}
+	return nil, conversionErrorf("%s: Go type %s not supported", path, value.Type())
+}
+
+func setPair(hash *Hash, key, value Object) error {
+	hashable, ok := key.(HashableObject)
+	if !ok {
+		return fmt.Errorf("key not hashable: %s", key.Type())
+	}
+	hash.Pairs[hashable.HashKey()] = HashPair{Key: key, Value: value}
+	return nil
+}
+
+func toGo(obj Object, typ reflect.Type) (reflect.Value, error) {
+	if obj == nil {
+		obj = NULL
+	}
+	if typ != anyType && reflect.TypeOf(obj).AssignableTo(typ) {
+		return reflect.ValueOf(obj), nil
+	}
+	mismatch := func() (reflect.Value, error) {
+		return reflect.Value{}, conversionErrorf("cannot use %s as %s", obj.Type(), typ)
+	}
+	if _, isNull := obj.(*Null); isNull {
+		switch typ.Kind() {
+		case reflect.Pointer, reflect.Interface, reflect.Slice, reflect.Map:
+			return reflect.Zero(typ), nil
+		}
+		return mismatch()
+	}
+	value := reflect.New(typ).Elem()
+	switch typ.Kind() {
+	case reflect.Bool:
+		obj, ok := obj.(*Boolean)
+		if !ok {
+			return mismatch()
+		}
+		value.SetBool(obj.Value)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		obj, ok := obj.(*Integer)
+		if !ok || value.OverflowInt(obj.Value) {
+			return mismatch()
+		}
+		value.SetInt(obj.Value)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		obj, ok := obj.(*Integer)
+		if !ok || obj.Value < 0 || value.OverflowUint(uint64(obj.Value)) {
+			return mismatch()
+		}
+		value.SetUint(uint64(obj.Value))
+	case reflect.Float32, reflect.Float64:
+		switch obj := obj.(type) {
+		case *Float:
+			if value.OverflowFloat(obj.Value) {
+				return mismatch()
+			}
+			value.SetFloat(obj.Value)
+		case *Integer:
+			value.SetFloat(float64(obj.Value))
+		default:
+			return mismatch()
+		}
+	case reflect.String:
+		obj, ok := obj.(*String)
+		if !ok {
+			return mismatch()
+		}
+		value.SetString(obj.Value)
+	case reflect.Slice:
+		obj, ok := obj.(*Array)
+		if !ok {
+			return mismatch()
+		}
+		value.Set(reflect.MakeSlice(typ, len(obj.Elements), len(obj.Elements)))
+		for i, element := range obj.Elements {
+			converted, err := toGo(element, typ.Elem())
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("element %d: %w", i, err)
+			}
+			value.Index(i).Set(converted)
+		}
+	case reflect.Array:
+		obj, ok := obj.(*Array)
+		if !ok || len(obj.Elements) != typ.Len() {
+			return mismatch()
+		}
+		for i, element := range obj.Elements {
+			converted, err := toGo(element, typ.Elem())
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("element %d: %w", i, err)
+			}
+			value.Index(i).Set(converted)
+		}
+	case reflect.Map:
+		obj, ok := obj.(*Hash)
+		if !ok {
+			return mismatch()
+		}
+		value.Set(reflect.MakeMapWithSize(typ, len(obj.Pairs)))
+		for _, pair := range obj.Pairs {
+			key, err := toGo(pair.Key, typ.Key())
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("key %s: %w", pair.Key, err)
+			}
+			element, err := toGo(pair.Value, typ.Elem())
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("value of key %s: %w", pair.Key, err)
+			}
+			value.SetMapIndex(key, element)
+		}
+	case reflect.Struct:
+		obj, ok := obj.(*Hash)
+		if !ok {
+			return mismatch()
+		}
+		fields := map[string]int{}
+		for i := 0; i < typ.NumField(); i++ {
+			if name := StructFieldName(typ.Field(i)); name != "" {
+				fields[name] = i
+			}
+		}
+		for _, pair := range obj.Pairs {
+			key, ok := pair.Key.(*String)
+			if !ok {
+				return reflect.Value{}, conversionErrorf("key %s: cannot use %s as field name of %s", pair.Key, pair.Key.Type(), typ)
+			}
+			idx, ok := fields[key.Value]
+			if !ok {
+				return reflect.Value{}, conversionErrorf("unknown field %q of %s", key.Value, typ)
+			}
+			element, err := toGo(pair.Value, typ.Field(idx).Type)
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("field %s: %w", key.Value, err)
+			}
+			value.Field(idx).Set(element)
+		}
+	case reflect.Pointer:
+		element, err := toGo(obj, typ.Elem())
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		value.Set(reflect.New(typ.Elem()))
+		value.Elem().Set(element)
+	case reflect.Interface:
+		if typ != anyType {
+			return mismatch()
+		}
+		natural, err := toGo(obj, naturalType(obj))
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		value.Set(natural)
+	default:
+		return mismatch()
+	}
+	return value, nil
+}
+
+// naturalType: Go type an object converts to when any type is accepted
+func naturalType(obj Object) reflect.Type {
+	switch obj := obj.(type) {
+	case *Boolean:
+		return reflect.TypeOf(false)
+	case *Integer:
+		return reflect.TypeOf(int64(0))
+	case *Float:
+		return reflect.TypeOf(float64(0))
+	case *String:
+		return reflect.TypeOf("")
+	case *Array:
+		return reflect.SliceOf(anyType)
+	case *Hash:
+		for _, pair := range obj.Pairs {
+			if _, ok := pair.Key.(*String); !ok {
+				return reflect.MapOf(anyType, anyType)
+			}
+		}
+		return reflect.MapOf(reflect.TypeOf(""), anyType)
+	default:
+		return objectType
+	}
+}
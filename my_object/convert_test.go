@@ -0,0 +1,146 @@
+package my_object
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type server struct {
+	Name    string   `monkey:"name"`
+	Ports   []uint16 `monkey:"ports"`
+	Ratio   float64
+	Debug   bool `monkey:"-"`
+	Owner   *server
+	private int
+}
+
+func TestFromGo(t *testing.T) {
+	tests := []struct {
+		value    any
+		expected string
+	}{
+		{nil, "null"},
+		{true, "true"},
+		{int8(-3), "-3"},
+		{uint32(7), "7"},
+		{2.5, "2.5"},
+		{"monkey", "monkey"},
+		{[]int{1, 2}, "[1,2]"},
+		{[2]string{"a", "b"}, "[a,b]"},
+		{[]int(nil), "null"},
+		{(*server)(nil), "null"},
+		{map[string]int{"a": 1}, "{a:1}"},
+		{struct{ Port int }{80}, "{Port:80}"},
+		{&Integer{Value: 1}, "1"},
+	}
+	for _, tt := range tests {
+		obj, err := FromGo(tt.value)
+		if assert.NoError(t, err, "value: %#v", tt.value) {
+			assert.Equal(t, tt.expected, obj.String(), "value: %#v", tt.value)
+		}
+	}
+
+	for _, value := range []any{make(chan int), uint64(1 << 63), map[string]any{"ch": make(chan int)}} {
+		_, err := FromGo(value)
+		assert.ErrorIs(t, err, ErrUnsupportedValue, "value: %#v", value)
+	}
+	_, err := FromGo([]any{1, make(chan int)})
+	assert.EqualError(t, err, "value[1]: Go type chan int not supported")
+
+	// values referred to twice are fine, values containing themselves are not
+	shared := []int{1}
+	obj, err := FromGo([][]int{shared, shared})
+	assert.NoError(t, err)
+	assert.Equal(t, "[[1],[1]]", obj.String())
+	loop := &server{Name: "loop"}
+	loop.Owner = loop
+	cyclicMap := map[string]any{}
+	cyclicMap["self"] = cyclicMap
+	cyclicSlice := []any{nil}
+	cyclicSlice[0] = cyclicSlice
+	for _, tt := range []struct {
+		value any
+		err   string
+	}{
+		{loop, "value.Owner: cyclic *my_object.server not supported"},
+		{cyclicMap, "value[self]: cyclic map[string]interface {} not supported"},
+		{cyclicSlice, "value[0]: cyclic []interface {} not supported"},
+	} {
+		_, err := FromGo(tt.value)
+		assert.ErrorIs(t, err, ErrUnsupportedValue)
+		assert.EqualError(t, err, tt.err)
+	}
+}
+
+func TestToGo(t *testing.T) {
+	var n int
+	assert.NoError(t, ToGo(&Integer{Value: 42}, &n))
+	assert.Equal(t, 42, n)
+
+	var f float32
+	assert.NoError(t, ToGo(&Integer{Value: 2}, &f))
+	assert.Equal(t, float32(2), f)
+
+	var strs []string
+	assert.NoError(t, ToGo(&Array{Elements: []Object{&String{Value: "a"}, &String{Value: "b"}}}, &strs))
+	assert.Equal(t, []string{"a", "b"}, strs)
+
+	var ptr *int
+	assert.NoError(t, ToGo(NULL, &ptr))
+	assert.Nil(t, ptr)
+	ptr = &n
+	assert.NoError(t, ToGo(nil, &ptr))
+	assert.Nil(t, ptr)
+
+	var natural any
+	assert.NoError(t, ToGo(&Array{Elements: []Object{&Integer{Value: 1}, TRUE, NULL}}, &natural))
+	assert.Equal(t, []any{int64(1), true, nil}, natural)
+
+	// round trip through a hash, nested pointers included
+	in := server{Name: "web", Ports: []uint16{80, 443}, Ratio: 0.5, Debug: true, Owner: &server{Name: "root"}, private: 1}
+	obj, err := FromGo(in)
+	assert.NoError(t, err)
+	assert.NoError(t, ToGo(obj, &natural))
+	assert.Equal(t, map[string]any{
+		"name":  "web",
+		"ports": []any{int64(80), int64(443)},
+		"Ratio": 0.5,
+		"Owner": map[string]any{"name": "root", "ports": nil, "Ratio": 0.0, "Owner": nil},
+	}, natural)
+	var out server
+	assert.NoError(t, ToGo(obj, &out))
+	assert.Equal(t, server{Name: "web", Ports: []uint16{80, 443}, Ratio: 0.5, Owner: &server{Name: "root"}}, out)
+
+	var keys map[any]any
+	assert.NoError(t, ToGo(&Hash{Pairs: map[HashKey]HashPair{
+		(&Integer{Value: 1}).HashKey(): {Key: &Integer{Value: 1}, Value: &String{Value: "one"}},
+	}}, &keys))
+	assert.Equal(t, map[any]any{int64(1): "one"}, keys)
+
+	tests := []struct {
+		obj    Object
+		target any
+		err    string
+	}{
+		{&String{Value: "1"}, new(int), "cannot use STRING as int"},
+		{&Integer{Value: 300}, new(uint8), "cannot use INT as uint8"},
+		{&Integer{Value: -1}, new(uint), "cannot use INT as uint"},
+		{&Float{Value: 1e39}, new(float32), "cannot use FLOAT as float32"},
+		{nil, new(int), "cannot use NULL as int"},
+		{NULL, new(string), "cannot use NULL as string"},
+		{&Array{Elements: []Object{&Integer{Value: 1}}}, new([]string), "element 0: cannot use INT as string"},
+		{&Hash{Pairs: map[HashKey]HashPair{
+			(&String{Value: "port"}).HashKey(): {Key: &String{Value: "port"}, Value: &Integer{Value: 1}},
+		}}, new(server), `unknown field "port" of my_object.server`},
+		{&Hash{Pairs: map[HashKey]HashPair{
+			(&String{Value: "name"}).HashKey(): {Key: &String{Value: "name"}, Value: &Integer{Value: 1}},
+		}}, new(server), "field name: cannot use INT as string"},
+		{&Integer{Value: 1}, 0, "unsupported value: target must be a non-nil pointer: got int"},
+	}
+	for _, tt := range tests {
+		err := ToGo(tt.obj, tt.target)
+		assert.ErrorIs(t, err, ErrUnsupportedValue, "obj: %s, target: %T", tt.obj, tt.target)
+		assert.EqualError(t, err, tt.err, "obj: %s, target: %T", tt.obj, tt.target)
+	}
+}
@@ -0,0 +1,45 @@
+package my_object
+
+import "fmt"
+
+// Element assignment shared by both engines; arrays and hashes are updated in place,
+// so every binding referring to them sees the new element.
+
+// ElementIndex: position of the element at index in an array-like value of length,
+// counting from the end if negative like python
+func ElementIndex(index Object, length int) (int, error) {
+	integer, ok := index.(*Integer)
+	if !ok {
+		return 0, fmt.Errorf("array-like indexing expecting INT, but got %s", index.Type())
+	}
+	idx := integer.Value
+	if idx < 0 {
+		idx += int64(length)
+	}
+	if idx < 0 || idx >= int64(length) {
+		return 0, fmt.Errorf("index %d out of array with length %d", integer.Value, length)
+	}
+	return int(idx), nil
+}
+
+// SetElement: container[index] = value for arrays and hashes
+func SetElement(container, index, value Object) error {
+	switch container := container.(type) {
+	case *Array:
+		idx, err := ElementIndex(index, len(container.Elements))
+		if err != nil {
+			return err
+		}
+		container.Elements[idx] = value
+		return nil
+	case *Hash:
+		key, ok := index.(HashableObject)
+		if !ok {
+			return fmt.Errorf("key type not hashable: %s", index.Type())
+		}
+		container.Pairs[key.HashKey()] = HashPair{Key: index, Value: value}
+		return nil
+	default:
+		return fmt.Errorf("index assignment not supported: %s", container.Type())
+	}
+}
@@ -3,12 +3,55 @@ package my_object
 type Environment struct {
 	values map[string]Object
 	outie  *Environment
+	// meter: shared by all environments enclosed in the same outermost one
+	meter *Meter
+	// importer: shared like meter, nil if code cannot import modules
+	importer Importer
+	// callDepth: function calls the environment is evaluated in, nested in each other
+	callDepth int
+}
+
+// Importer: loads the namespace of a module imported by code evaluated in env
+type Importer interface {
+	Import(name string, env *Environment) (Object, error)
 }
 
 func NewEnvironment() *Environment { return &Environment{values: map[string]Object{}, outie: nil} }
 
 func NewEnclosedEnvironment(outie *Environment) *Environment {
-	return &Environment{values: map[string]Object{}, outie: outie}
+	return &Environment{values: map[string]Object{}, outie: outie, meter: outie.meter, importer: outie.importer, callDepth: outie.callDepth}
+}
+
+// NewCallEnvironment: bindings of a call to a function defined in outie made by code evaluated in caller,
+// one call deeper than caller
+func NewCallEnvironment(outie, caller *Environment) *Environment {
+	env := NewEnclosedEnvironment(outie)
+	env.callDepth = caller.callDepth + 1
+	return env
+}
+
+// CallDepth: function calls the environment is evaluated in, nested in each other
+func (e *Environment) CallDepth() int { return e.callDepth }
+
+// SetMeter: meter evaluation in the environment and environments enclosed in it afterwards;
+// set it on the outermost environment once and Reset it for each run
+func (e *Environment) SetMeter(meter *Meter) { e.meter = meter }
+
+func (e *Environment) Meter() *Meter { return e.meter }
+
+// SetImporter: let import statements evaluated in the environment and environments enclosed in it afterwards load modules
+func (e *Environment) SetImporter(importer Importer) { e.importer = importer }
+
+func (e *Environment) Importer() Importer { return e.importer }
+
+// Namespace: bindings of the environment itself, not of outer ones, as a hash keyed by their names
+func (e *Environment) Namespace() *Hash {
+	hash := &Hash{Pairs: make(map[HashKey]HashPair, len(e.values))}
+	for name, value := range e.values {
+		key := &String{Value: name}
+		hash.Pairs[key.HashKey()] = HashPair{Key: key, Value: value}
+	}
+	return hash
 }
 
 func (e *Environment) Get(name string) (Object, bool) {
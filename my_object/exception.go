@@ -0,0 +1,42 @@
+package my_object
+
+import token "monkey/my_token"
+
+// keys of error values handed to catch blocks for runtime errors
+const (
+	ErrorMessageKey = "message"
+	ErrorLineKey    = "line"
+	ErrorColumnKey  = "column"
+)
+
+// NewErrorValue: a runtime error caught by a try expression as a value,
+// a hash with its message and the line and column it is raised at, 0 if unknown
+func NewErrorValue(message string, pos token.Position) *Hash {
+	hash := &Hash{Pairs: make(map[HashKey]HashPair, 3)}
+	setPair(hash, &String{Value: ErrorMessageKey}, &String{Value: message})
+	setPair(hash, &String{Value: ErrorLineKey}, &Integer{Value: int64(pos.Line)})
+	setPair(hash, &String{Value: ErrorColumnKey}, &Integer{Value: int64(pos.Column)})
+	return hash
+}
+
+// CaughtValue: what a catch block receives for err, the thrown value itself or an error value for runtime errors
+func CaughtValue(err *Error) Object {
+	if err.Thrown != nil {
+		return err.Thrown
+	}
+	return NewErrorValue(err.Message, err.Pos)
+}
+
+// UncaughtMessage: message of an exception thrown with value and never caught;
+// rethrown error values keep their original message
+func UncaughtMessage(value Object) string {
+	if hash, ok := value.(*Hash); ok {
+		key := &String{Value: ErrorMessageKey}
+		if pair, ok := hash.Pairs[key.HashKey()]; ok {
+			if message, ok := pair.Value.(*String); ok {
+				return message.Value
+			}
+		}
+	}
+	return "uncaught exception: " + value.String()
+}
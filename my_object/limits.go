@@ -0,0 +1,181 @@
+package my_object
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	token "monkey/my_token"
+	"time"
+)
+
+var ErrLimitExceeded = errors.New("execution limit exceeded")
+
+// Limits: ceilings for running untrusted code, zero values mean unlimited
+type Limits struct {
+	// MaxSteps: instructions executed by the vm or nodes evaluated by the evaluator
+	MaxSteps int
+	// MaxCallDepth: function calls nested in each other
+	MaxCallDepth int
+	// MaxAllocBytes: total size of objects allocated, see SizeOf
+	MaxAllocBytes int
+	// Timeout: wall-clock time of one run
+	Timeout time.Duration
+}
+
+// checkInterval: steps between two checks of the clock and the context, a power of 2
+const checkInterval = 1 << 10
+
+// Meter: usage of one run against its limits and its context; a nil Meter is unlimited.
+// Once a limit is exceeded or the context is done, the meter keeps failing with the same error.
+type Meter struct {
+	limits     Limits
+	deadline   time.Time
+	ctx        context.Context
+	steps      int
+	depth      int
+	allocBytes int
+	err        error
+	// stoppedAt: where the run stops because of err, if the engine knows
+	stoppedAt token.Position
+}
+
+func NewMeter(limits Limits) *Meter {
+	m := &Meter{}
+	m.Reset(limits)
+	return m
+}
+
+// Reset: start metering a new run, the deadline counts from now on
+func (m *Meter) Reset(limits Limits) {
+	*m = Meter{limits: limits}
+	if limits.Timeout > 0 {
+		m.deadline = time.Now().Add(limits.Timeout)
+	}
+}
+
+// SetContext: stop the run once ctx is done, failing with ctx.Err();
+// call it after Reset, which forgets the context of the previous run
+func (m *Meter) SetContext(ctx context.Context) {
+	m.ctx = ctx
+}
+
+// Err: the limit exceeded or the error of the context, nil if none is
+func (m *Meter) Err() error {
+	if m == nil {
+		return nil
+	}
+	return m.err
+}
+
+// StopAt: record where the run stops once the meter fails, the first position wins
+func (m *Meter) StopAt(pos token.Position) {
+	if m != nil && m.err != nil && !m.stoppedAt.IsValid() {
+		m.stoppedAt = pos
+	}
+}
+
+func (m *Meter) StoppedAt() token.Position {
+	if m == nil {
+		return token.Position{}
+	}
+	return m.stoppedAt
+}
+
+// Step: count an instruction or a node and check the deadline and the context every now and then
+func (m *Meter) Step() error {
+	if m == nil || m.err != nil {
+		return m.Err()
+	}
+	m.steps++
+	if m.limits.MaxSteps > 0 && m.steps > m.limits.MaxSteps {
+		return m.fail("more than %d steps", m.limits.MaxSteps)
+	}
+	if m.steps&(checkInterval-1) != 0 {
+		return nil
+	}
+	if m.ctx != nil && m.ctx.Err() != nil {
+		m.err = m.ctx.Err()
+		return m.err
+	}
+	if !m.deadline.IsZero() && time.Now().After(m.deadline) {
+		return m.fail("timeout after %s", m.limits.Timeout)
+	}
+	return nil
+}
+
+// Enter: count a function call, to be paired with Leave when it returns
+func (m *Meter) Enter() error {
+	if m == nil || m.err != nil {
+		return m.Err()
+	}
+	m.depth++
+	if m.limits.MaxCallDepth > 0 && m.depth > m.limits.MaxCallDepth {
+		return m.fail("call depth exceeds %d", m.limits.MaxCallDepth)
+	}
+	return nil
+}
+
+func (m *Meter) Leave() {
+	if m != nil {
+		m.depth--
+	}
+}
+
+// Alloc: count obj as newly allocated
+func (m *Meter) Alloc(obj Object) error {
+	if m == nil || m.err != nil {
+		return m.Err()
+	}
+	if err := m.CanAlloc(SizeOf(obj)); err != nil {
+		return err
+	}
+	m.allocBytes += SizeOf(obj)
+	return nil
+}
+
+// CanAlloc: check before allocating something large, without counting it
+func (m *Meter) CanAlloc(bytes int) error {
+	if m == nil || m.err != nil {
+		return m.Err()
+	}
+	if m.limits.MaxAllocBytes > 0 && bytes > m.limits.MaxAllocBytes-m.allocBytes {
+		return m.fail("allocations exceed %d bytes", m.limits.MaxAllocBytes)
+	}
+	return nil
+}
+
+func (m *Meter) fail(format string, a ...any) error {
+	m.err = fmt.Errorf("%w: %s", ErrLimitExceeded, fmt.Sprintf(format, a...))
+	return m.err
+}
+
+// RepeatSize: size of unit repeated n times, saturating at math.MaxInt
+func RepeatSize(unit int, n int64) int {
+	if unit == 0 || n <= 0 {
+		return 0
+	}
+	if n > int64(math.MaxInt/unit) {
+		return math.MaxInt
+	}
+	return unit * int(n)
+}
+
+// objectHeaderSize: rough size of an object itself, regardless of its contents
+const objectHeaderSize = 16
+
+// SizeOf: rough number of bytes an object occupies, not counting objects it refers to
+func SizeOf(obj Object) int {
+	switch obj := obj.(type) {
+	case *String:
+		return objectHeaderSize + len(obj.Value)
+	case *Array:
+		return objectHeaderSize + 16*len(obj.Elements)
+	case *Hash:
+		return objectHeaderSize + 48*len(obj.Pairs)
+	case *Closure:
+		return objectHeaderSize + 16*len(obj.Free)
+	default:
+		return objectHeaderSize
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"hash/fnv"
 	"math"
 	"monkey/my_ast"
+	"monkey/my_code"
+	token "monkey/my_token"
 	"strconv"
 	"strings"
 )
@@ -12,18 +14,21 @@ import (
 type ObjectType string
 
 const (
-	INTEGER_OBJ          = "INT"
-	UNSIGNED_INTEGER_OBJ = "UINT"
-	FLOAT_OBJ            = "FLOAT"
-	BOOLEAN_OBJ          = "BOOLEAN"
-	NULL_OBJ             = "NULL"
-	RETURN_VALUE_OBJ     = "RETURN_VALUE"
-	ERROR_OBJ            = "ERROR"
-	FUNCTION_OBJ         = "FUNCTION"
-	STRING_OBJ           = "STRING"
-	BUILTIN_OBJ          = "BUILTIN"
-	ARRAY_OBJ            = "ARRAY"
-	HASH_OBJ             = "HASH"
+	INTEGER_OBJ           = "INT"
+	UNSIGNED_INTEGER_OBJ  = "UINT"
+	FLOAT_OBJ             = "FLOAT"
+	BOOLEAN_OBJ           = "BOOLEAN"
+	NULL_OBJ              = "NULL"
+	RETURN_VALUE_OBJ      = "RETURN_VALUE"
+	ERROR_OBJ             = "ERROR"
+	FUNCTION_OBJ          = "FUNCTION"
+	STRING_OBJ            = "STRING"
+	BUILTIN_OBJ           = "BUILTIN"
+	ARRAY_OBJ             = "ARRAY"
+	HASH_OBJ              = "HASH"
+	COMPILED_FUNCTION_OBJ = "COMPILED_FUNCTION"
+	CLOSURE_OBJ           = "CLOSURE"
+	CELL_OBJ              = "CELL"
 )
 
 type Object interface {
@@ -114,6 +119,21 @@ func (n *Null) Type() ObjectType { return NULL_OBJ }
 
 func (n *Null) String() string { return "null" }
 
+// shared by both engines, which tell booleans and null by identity
+var (
+	TRUE  = &Boolean{Value: true}
+	FALSE = &Boolean{Value: false}
+	NULL  = &Null{}
+)
+
+// NativeBool: the shared boolean object of b
+func NativeBool(b bool) *Boolean {
+	if b {
+		return TRUE
+	}
+	return FALSE
+}
+
 type ReturnValue struct {
 	Value Object
 }
@@ -126,6 +146,10 @@ func (r *ReturnValue) String() string {
 
 type Error struct {
 	Message string
+	// Pos: where the error is raised, not valid if unknown
+	Pos token.Position
+	// Thrown: the value of a throw statement, nil for runtime errors
+	Thrown Object
 }
 
 func (e *Error) Type() ObjectType { return ERROR_OBJ }
@@ -213,3 +237,47 @@ func (h *Hash) String() string {
 	}
 	return "{" + strings.Join(pairs, ",") + "}"
 }
+
+type CompiledFunction struct {
+	Instructions my_code.Instructions
+	// NumLocals: number of local bindings including parameters the function needs
+	NumLocals     int
+	NumParameters int
+	// Name: name of the binding the function literal is assigned to, empty for anonymous functions
+	Name string
+	// Lines: source positions of instructions, empty if compiled without debug info
+	Lines my_code.LineTable
+	// Module: path of the module whose source Lines refer to, empty for the main program
+	Module string
+}
+
+func (cf *CompiledFunction) Type() ObjectType { return COMPILED_FUNCTION_OBJ }
+
+func (cf *CompiledFunction) String() string {
+	return fmt.Sprintf("CompiledFunction[%p]", cf)
+}
+
+type Closure struct {
+	Fn *CompiledFunction
+	// Free: cells of free variables captured when the closure is created,
+	// or the enclosing closure itself when it refers to its own name
+	Free []Object
+}
+
+func (c *Closure) Type() ObjectType { return CLOSURE_OBJ }
+
+func (c *Closure) String() string {
+	return fmt.Sprintf("Closure[%p]", c)
+}
+
+// Cell: a local binding captured by closures, kept in the local slot of its frame and in the closures alike
+// so that assignments through any of them are seen by all; never visible to monkey code
+type Cell struct {
+	Value Object
+}
+
+func (c *Cell) Type() ObjectType { return CELL_OBJ }
+
+func (c *Cell) String() string {
+	return fmt.Sprintf("Cell[%p]", c)
+}
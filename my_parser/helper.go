@@ -2,6 +2,7 @@ package my_parser
 
 import (
 	"fmt"
+	"monkey/my_ast"
 	token "monkey/my_token"
 )
 
@@ -19,15 +20,39 @@ func (p *Parser) isPeekToken(t token.TokenType) bool {
 	return p.peekToken.Type == t
 }
 
-func (p *Parser) appendError(msg string) {
-	if p.err == nil {
-		p.err = ErrParseError
+// spanFrom: span from start to the end of current token
+func (p *Parser) spanFrom(start token.Position) token.Span {
+	return token.Span{Start: start, End: p.curToken.Span.End}
+}
+
+// startOf: start position of a parsed node, or current token if node failed to parse
+func (p *Parser) startOf(node my_ast.Node) token.Position {
+	if node == nil {
+		return p.curToken.Span.Start
+	}
+	return node.Span().Start
+}
+
+func (p *Parser) appendError(pos token.Position, msg string) {
+	// one error is enough if parsing fails repeatedly at the same token
+	if n := len(p.errs); n > 0 && p.errs[n-1].Pos == pos {
+		return
+	}
+	p.errs = append(p.errs, Diagnostic{Pos: pos, Msg: msg})
+}
+
+// synchronize: skip tokens of a broken statement until ; or } or EOF
+func (p *Parser) synchronize() {
+	for !p.isCurToken(token.SEMICOLON) &&
+		!p.isCurToken(token.RBRACE) &&
+		!p.isCurToken(token.EOF) {
+		p.nextToken()
 	}
-	p.err = fmt.Errorf("%s: %w", msg, p.err)
 }
 
 func (p *Parser) appendTokenError(expect token.TokenType, value token.Token) {
 	p.appendError(
+		value.Span.Start,
 		fmt.Sprintf(
 			"expecting token %s, but got %s with literal %s instead",
 			string(expect), string(value.Type), value.Literal,
@@ -38,6 +63,7 @@ func (p *Parser) appendTokenError(expect token.TokenType, value token.Token) {
 func (p *Parser) appendExprFuncError(value token.Token, isPrefix bool) {
 	if isPrefix {
 		p.appendError(
+			value.Span.Start,
 			fmt.Sprintf(
 				"no prefix parse func: token type: %s: literal: %s",
 				string(value.Type), value.Literal,
@@ -45,6 +71,7 @@ func (p *Parser) appendExprFuncError(value token.Token, isPrefix bool) {
 		)
 	} else {
 		p.appendError(
+			value.Span.Start,
 			fmt.Sprintf(
 				"no infix parse func: token type: %s: literal: %s",
 				string(value.Type), value.Literal,
@@ -13,31 +13,68 @@ const (
 	_ PrecedenceLevel = iota
 	LOWEST
 	REASSIGN    // let a =1; a=2;
+	LOGICAL_OR  // ||
+	LOGICAL_AND // &&
 	EQUALS      // ==
 	LESSGREATER // > or <
+	BIT_OR      // |
+	BIT_XOR     // ^
+	BIT_AND     // &
+	SHIFT       // << or >>
 	SUM         // +
-	PREFIX      // -X or !X
-	PRODUCT     // *
+	PRODUCT     // * / // %
+	PREFIX      // -X or !X or ~X
+	POWER       // **, right associative
 	CALL        // myFunction(X)
 	INDEX       // []
 	INDEXCOLON  // :
 )
 
 var InfixOperatorToPrecedences = map[my_ast.InfixOperator]PrecedenceLevel{
-	my_ast.INOP_MINUS:      SUM,
-	my_ast.INOP_PLUS:       SUM,
-	my_ast.INOP_ASTERISK:   PRODUCT,
-	my_ast.INOP_SLASH:      PRODUCT,
-	my_ast.INOP_LT:         LESSGREATER,
-	my_ast.INOP_GT:         LESSGREATER,
-	my_ast.INOP_EQ:         EQUALS,
-	my_ast.INOP_NOT_EQ:     EQUALS,
-	my_ast.INOP_CALL:       CALL,
-	my_ast.INOP_INDEX:      INDEX,
-	my_ast.INOP_INDEXCOLON: INDEXCOLON,
-	my_ast.INOP_GTE:        LESSGREATER,
-	my_ast.INOP_LTE:        LESSGREATER,
-	my_ast.INOP_REASSIGN:   REASSIGN,
+	my_ast.INOP_MINUS:       SUM,
+	my_ast.INOP_PLUS:        SUM,
+	my_ast.INOP_ASTERISK:    PRODUCT,
+	my_ast.INOP_SLASH:       PRODUCT,
+	my_ast.INOP_LT:          LESSGREATER,
+	my_ast.INOP_GT:          LESSGREATER,
+	my_ast.INOP_EQ:          EQUALS,
+	my_ast.INOP_NOT_EQ:      EQUALS,
+	my_ast.INOP_CALL:        CALL,
+	my_ast.INOP_INDEX:       INDEX,
+	my_ast.INOP_INDEXCOLON:  INDEXCOLON,
+	my_ast.INOP_GTE:         LESSGREATER,
+	my_ast.INOP_LTE:         LESSGREATER,
+	my_ast.INOP_REASSIGN:    REASSIGN,
+	my_ast.INOP_OR:          LOGICAL_OR,
+	my_ast.INOP_AND:         LOGICAL_AND,
+	my_ast.INOP_PERCENT:     PRODUCT,
+	my_ast.INOP_FLOOR_SLASH: PRODUCT,
+	my_ast.INOP_POWER:       POWER,
+	my_ast.INOP_AMPERSAND:   BIT_AND,
+	my_ast.INOP_PIPE:        BIT_OR,
+	my_ast.INOP_CARET:       BIT_XOR,
+	my_ast.INOP_SHL:         SHIFT,
+	my_ast.INOP_SHR:         SHIFT,
+	// compound assignments
+	my_ast.InfixOperator(token.PLUS_ASSIGN):     REASSIGN,
+	my_ast.InfixOperator(token.MINUS_ASSIGN):    REASSIGN,
+	my_ast.InfixOperator(token.ASTERISK_ASSIGN): REASSIGN,
+	my_ast.InfixOperator(token.SLASH_ASSIGN):    REASSIGN,
+	my_ast.InfixOperator(token.PERCENT_ASSIGN):  REASSIGN,
+	// a++ and a-- as increments, or a - -b as signs, see parseIncrementOrSigns
+	my_ast.InfixOperator(token.INCREMENT): SUM,
+	my_ast.InfixOperator(token.DECREMENT): SUM,
+}
+
+// compoundAssignOperators: operators applied to the target by compound assignments and increments
+var compoundAssignOperators = map[token.TokenType]my_ast.InfixOperator{
+	token.PLUS_ASSIGN:     my_ast.INOP_PLUS,
+	token.MINUS_ASSIGN:    my_ast.INOP_MINUS,
+	token.ASTERISK_ASSIGN: my_ast.INOP_ASTERISK,
+	token.SLASH_ASSIGN:    my_ast.INOP_SLASH,
+	token.PERCENT_ASSIGN:  my_ast.INOP_PERCENT,
+	token.INCREMENT:       my_ast.INOP_PLUS,
+	token.DECREMENT:       my_ast.INOP_MINUS,
 }
 
 func tokenPrecedenceLevel(t *token.Token) PrecedenceLevel {
@@ -59,8 +96,11 @@ func (p *Parser) parseExpression(precedence PrecedenceLevel) my_ast.Expression {
 		p.appendExprFuncError(p.curToken, true)
 		return nil
 	}
-	leftExpr := prefixExpr()
+	return p.continueExpression(prefixExpr(), precedence)
+}
 
+// continueExpression: parse infix operators binding tighter than precedence after leftExpr
+func (p *Parser) continueExpression(leftExpr my_ast.Expression, precedence PrecedenceLevel) my_ast.Expression {
 	// NOTE: consume to semicolon or EOF
 	// or when meet a higher precedence with current token
 	for p.peekToken.Type != token.SEMICOLON &&
@@ -77,36 +117,42 @@ func (p *Parser) parseExpression(precedence PrecedenceLevel) my_ast.Expression {
 }
 
 func (p *Parser) parseIdentifier() my_ast.Expression {
-	return &my_ast.Identifier{
+	ident := &my_ast.Identifier{
 		Value: p.curToken.Literal,
 	}
+	ident.SetSpan(p.curToken.Span)
+	return ident
 }
 
 func (p *Parser) parseIntegerLiteral() my_ast.Expression {
 	val, err := strconv.ParseUint(p.curToken.Literal, 10, 64)
 	if err != nil {
-		p.appendError(fmt.Sprintf("cannot parse %s as uint :%v", p.curToken.Literal, err))
+		p.appendError(p.curToken.Span.Start, fmt.Sprintf("cannot parse %s as uint :%v", p.curToken.Literal, err))
 		return nil
 	}
-	return &my_ast.Integer{Value: val}
+	integer := &my_ast.Integer{Value: val}
+	integer.SetSpan(p.curToken.Span)
+	return integer
 }
 
 func (p *Parser) parseFloatLiteral() my_ast.Expression {
 	val, err := strconv.ParseFloat(p.curToken.Literal, 64)
 	if err != nil {
-		p.appendError(fmt.Sprintf("cannot parse %s as float: %v", p.curToken.Literal, err))
+		p.appendError(p.curToken.Span.Start, fmt.Sprintf("cannot parse %s as float: %v", p.curToken.Literal, err))
 		return nil
 	}
-	return &my_ast.Float{Value: val}
+	float := &my_ast.Float{Value: val}
+	float.SetSpan(p.curToken.Span)
+	return float
 }
 
 func (p *Parser) parseBooleanLiteral() my_ast.Expression {
-	if p.curToken.Type == token.TRUE {
-		return &my_ast.Boolean{Value: true}
-	} else if p.curToken.Type == token.FALSE {
-		return &my_ast.Boolean{Value: false}
+	if p.curToken.Type == token.TRUE || p.curToken.Type == token.FALSE {
+		boolean := &my_ast.Boolean{Value: p.curToken.Type == token.TRUE}
+		boolean.SetSpan(p.curToken.Span)
+		return boolean
 	} else {
-		p.appendError(fmt.Sprintf(
+		p.appendError(p.curToken.Span.Start, fmt.Sprintf(
 			"cannot parse %s with type %s as boolean", p.curToken.Literal, p.curToken.Type,
 		))
 		return nil
@@ -114,11 +160,13 @@ func (p *Parser) parseBooleanLiteral() my_ast.Expression {
 }
 
 func (p *Parser) parsePrefixExpression() my_ast.Expression {
+	start := p.curToken.Span.Start
 	expr := &my_ast.PrefixExpression{
 		Operator: my_ast.PrefixOperator(p.curToken.Type),
 	}
 	p.nextToken()
 	expr.Right = p.parseExpression(PREFIX)
+	expr.SetSpan(p.spanFrom(start))
 	return expr
 }
 
@@ -127,12 +175,112 @@ func (p *Parser) parseInfixExpression(left my_ast.Expression) my_ast.Expression
 		Left:     left,
 		Operator: my_ast.InfixOperator(p.curToken.Type),
 	}
+	start := p.startOf(left)
 	precedence := tokenPrecedenceLevel(&p.curToken)
+	if exp.Operator == my_ast.INOP_POWER {
+		// 2 ** 3 ** 2 is 2 ** (3 ** 2)
+		precedence--
+	}
 	p.nextToken()
 	exp.Right = p.parseExpression(precedence)
+	exp.SetSpan(p.spanFrom(start))
+	return exp
+}
+
+// parseAssignExpression: <IDENT or INDEX> += <EXPR>, and the same for -= *= /= %=
+func (p *Parser) parseAssignExpression(left my_ast.Expression) my_ast.Expression {
+	if !p.checkAssignTarget(left) {
+		return nil
+	}
+	exp := &my_ast.AssignExpression{
+		Target:   left,
+		Operator: compoundAssignOperators[p.curToken.Type],
+	}
+	start := p.startOf(left)
+	// right-associative: a += b -= 1 is a += (b -= 1)
+	precedence := tokenPrecedenceLevel(&p.curToken) - 1
+	p.nextToken()
+	exp.Value = p.parseExpression(precedence)
+	exp.SetSpan(p.spanFrom(start))
+	return exp
+}
+
+// parseDoubleMinus: -- before an expression negates it twice
+func (p *Parser) parseDoubleMinus() my_ast.Expression {
+	start := p.curToken.Span.Start
+	expr := &my_ast.PrefixExpression{Operator: my_ast.PREOP_MINUS, Right: p.parseSecondMinus()}
+	expr.SetSpan(p.spanFrom(start))
+	return expr
+}
+
+// parseSecondMinus: the operand of -- read as two signs negated by the second one
+func (p *Parser) parseSecondMinus() my_ast.Expression {
+	start := secondChar(p.curToken.Span.Start)
+	expr := &my_ast.PrefixExpression{Operator: my_ast.PREOP_MINUS}
+	p.nextToken()
+	expr.Right = p.parseExpression(PREFIX)
+	expr.SetSpan(p.spanFrom(start))
+	return expr
+}
+
+func secondChar(pos token.Position) token.Position {
+	return token.Position{Offset: pos.Offset + 1, Line: pos.Line, Column: pos.Column + 1}
+}
+
+// parseIncrementOrSigns: cur token is ++ or --, which is an increment of left like a++,
+// unless an operand follows on the same line: then it is two signs, so that a--1 is a - (-1)
+func (p *Parser) parseIncrementOrSigns(left my_ast.Expression) my_ast.Expression {
+	if _, ok := p.prefixParseFns[p.peekToken.Type]; !ok || p.peekToken.Span.Start.Line != p.curToken.Span.End.Line {
+		return p.parseIncrement(left)
+	}
+	if p.isCurToken(token.INCREMENT) {
+		// there is no prefix +
+		p.appendExprFuncError(token.Token{
+			Type:    token.PLUS,
+			Literal: "+",
+			Span:    token.Span{Start: secondChar(p.curToken.Span.Start), End: p.curToken.Span.End},
+		}, true)
+		return nil
+	}
+	exp := &my_ast.InfixExpression{Left: left, Operator: my_ast.INOP_MINUS}
+	exp.Right = p.continueExpression(p.parseSecondMinus(), SUM)
+	exp.SetSpan(p.spanFrom(p.startOf(left)))
+	return exp
+}
+
+// parseIncrement: <IDENT or INDEX>++ or --, adding or subtracting 1 like += 1 or -= 1; cur token is ++ or --
+func (p *Parser) parseIncrement(target my_ast.Expression) my_ast.Expression {
+	if !p.checkAssignTarget(target) {
+		return nil
+	}
+	one := &my_ast.Integer{Value: 1}
+	one.SetSpan(p.curToken.Span)
+	exp := &my_ast.AssignExpression{
+		Target:   target,
+		Operator: compoundAssignOperators[p.curToken.Type],
+		Value:    one,
+	}
+	exp.SetSpan(p.spanFrom(p.startOf(target)))
 	return exp
 }
 
+// checkAssignTarget: only bindings and single elements of arrays or hashes can be assigned to
+func (p *Parser) checkAssignTarget(target my_ast.Expression) bool {
+	switch target := target.(type) {
+	case *my_ast.Identifier:
+		return true
+	case *my_ast.IndexExpression:
+		if target.IsElement() {
+			return true
+		}
+	case nil:
+		// already reported
+		return false
+	}
+	p.appendError(target.Span().Start, fmt.Sprintf("cannot assign to %s", target.String()))
+	return false
+}
+
 func (p *Parser) parseGroupedExpression() my_ast.Expression {
 	p.nextToken()
 	exp := p.parseExpression(LOWEST)
@@ -146,6 +294,7 @@ func (p *Parser) parseGroupedExpression() my_ast.Expression {
 }
 
 func (p *Parser) parseIfExpression() my_ast.Expression {
+	start := p.curToken.Span.Start
 	// parse if condition as expression
 	p.nextToken()
 	if !p.isCurToken(token.LPAREN) {
@@ -171,6 +320,7 @@ func (p *Parser) parseIfExpression() my_ast.Expression {
 	// } else
 	// no "else" token is legal, return immediately
 	if !p.isPeekToken(token.ELSE) {
+		ie.SetSpan(p.spanFrom(start))
 		return ie
 	}
 	// else { xx
@@ -183,10 +333,12 @@ func (p *Parser) parseIfExpression() my_ast.Expression {
 		p.appendTokenError(token.RBRACE, p.curToken)
 		return nil
 	}
+	ie.SetSpan(p.spanFrom(start))
 	return ie
 }
 
 func (p *Parser) parseFunction() my_ast.Expression {
+	start := p.curToken.Span.Start
 	p.nextToken()
 	// TODO: no function name after fn?
 	fe := &my_ast.Function{
@@ -198,6 +350,7 @@ func (p *Parser) parseFunction() my_ast.Expression {
 	}
 	p.nextToken()
 	fe.Body = p.parseBlockStatement()
+	fe.SetSpan(p.spanFrom(start))
 	return fe
 }
 
@@ -212,11 +365,11 @@ func (p *Parser) parseFunctionParameters() []*my_ast.Identifier {
 		// p.nextToken()
 		return params
 	}
-	params = append(params, &my_ast.Identifier{Value: p.curToken.Literal})
+	params = append(params, p.parseIdentifier().(*my_ast.Identifier))
 	for p.isPeekToken(token.COMMA) {
 		p.nextToken()
 		p.nextToken()
-		params = append(params, &my_ast.Identifier{Value: p.curToken.Literal})
+		params = append(params, p.parseIdentifier().(*my_ast.Identifier))
 	}
 	p.nextToken()
 	if !p.isCurToken(token.RPAREN) {
@@ -227,7 +380,10 @@ func (p *Parser) parseFunctionParameters() []*my_ast.Identifier {
 }
 
 func (p *Parser) parseCallExpression(leftFunc my_ast.Expression) my_ast.Expression {
-	return &my_ast.CallExpression{Function: leftFunc, Arguments: p.parseCallArguments()}
+	start := p.startOf(leftFunc)
+	call := &my_ast.CallExpression{Function: leftFunc, Arguments: p.parseCallArguments()}
+	call.SetSpan(p.spanFrom(start))
+	return call
 }
 
 func (p *Parser) parseCallArguments() []my_ast.Expression {
@@ -255,13 +411,18 @@ func (p *Parser) parseCallArguments() []my_ast.Expression {
 }
 
 func (p *Parser) parseStringExpression() my_ast.Expression {
-	return &my_ast.StringExpression{
+	str := &my_ast.StringExpression{
 		Value: p.curToken.Literal,
 	}
+	str.SetSpan(p.curToken.Span)
+	return str
 }
 
 func (p *Parser) parseArrayExpression() my_ast.Expression {
-	return &my_ast.ArrayExpression{Elements: p.parseExpressionList(token.RBRACKET)}
+	start := p.curToken.Span.Start
+	array := &my_ast.ArrayExpression{Elements: p.parseExpressionList(token.RBRACKET)}
+	array.SetSpan(p.spanFrom(start))
+	return array
 }
 
 func (p *Parser) parseExpressionList(end token.TokenType) []my_ast.Expression {
@@ -286,6 +447,17 @@ func (p *Parser) parseExpressionList(end token.TokenType) []my_ast.Expression {
 }
 
 func (p *Parser) parseIndexExpression(left my_ast.Expression) my_ast.Expression {
+	start := p.startOf(left)
+	exp := p.parseIndexRange(left)
+	if exp == nil {
+		return nil
+	}
+	exp.SetSpan(p.spanFrom(start))
+	return exp
+}
+
+// parseIndexRange: parse [start:end:stride] after left
+func (p *Parser) parseIndexRange(left my_ast.Expression) *my_ast.IndexExpression {
 	exp := &my_ast.IndexExpression{
 		Left:            left,
 		StartIndex:      nil,
@@ -313,7 +485,7 @@ func (p *Parser) parseIndexExpression(left my_ast.Expression) my_ast.Expression
 		return exp
 	}
 	if !p.isCurToken(token.COLON) {
-		p.appendError(fmt.Sprintf("Expected : or ], but got: %s", p.curToken.Literal))
+		p.appendError(p.curToken.Span.Start, fmt.Sprintf("Expected : or ], but got: %s", p.curToken.Literal))
 		return nil
 	}
 	exp.IsSetStartIndex = true
@@ -336,7 +508,7 @@ func (p *Parser) parseIndexExpression(left my_ast.Expression) my_ast.Expression
 		return exp
 	}
 	if !p.isCurToken(token.COLON) {
-		p.appendError(fmt.Sprintf("Expected : or ], but got: %s", p.curToken.Literal))
+		p.appendError(p.curToken.Span.Start, fmt.Sprintf("Expected : or ], but got: %s", p.curToken.Literal))
 		return nil
 	}
 
@@ -360,6 +532,7 @@ func (p *Parser) parseIndexExpression(left my_ast.Expression) my_ast.Expression
 }
 
 func (p *Parser) parseHashLiteral() my_ast.Expression {
+	start := p.curToken.Span.Start
 	hash := &my_ast.HashExpression{
 		Pairs: make(map[my_ast.Expression]my_ast.Expression),
 		Keys:  make([]my_ast.Expression, 0),
@@ -378,6 +551,7 @@ func (p *Parser) parseHashLiteral() my_ast.Expression {
 		hash.Keys = append(hash.Keys, key)
 		if !p.isPeekToken(token.RBRACE) && !p.isPeekToken(token.COMMA) {
 			p.appendError(
+				p.peekToken.Span.Start,
 				fmt.Sprintf("expecting token RBRACE or COMMA, but got %s with literal %s instead", string(p.peekToken.Type), p.peekToken.Literal),
 			)
 			return nil
@@ -391,10 +565,12 @@ func (p *Parser) parseHashLiteral() my_ast.Expression {
 		p.appendTokenError(token.RBRACE, p.peekToken)
 		return nil
 	}
+	hash.SetSpan(p.spanFrom(start))
 	return hash
 }
 
 func (p *Parser) parseForExpression() my_ast.Expression {
+	start := p.curToken.Span.Start
 	p.nextToken()
 	if !p.isCurToken(token.LPAREN) {
 		p.appendTokenError(token.LPAREN, p.curToken)
@@ -432,10 +608,12 @@ func (p *Parser) parseForExpression() my_ast.Expression {
 	}
 	p.nextToken()
 	forExpression.Body = p.parseBlockStatement()
+	forExpression.SetSpan(p.spanFrom(start))
 	return forExpression
 }
 
 func (p *Parser) parseWhileExression() my_ast.Expression {
+	start := p.curToken.Span.Start
 	p.nextToken()
 	if !p.isCurToken(token.LPAREN) {
 		p.appendTokenError(token.LPAREN, p.curToken)
@@ -461,10 +639,12 @@ func (p *Parser) parseWhileExression() my_ast.Expression {
 	if whileExpr.Body == nil {
 		return nil
 	}
+	whileExpr.SetSpan(p.spanFrom(start))
 	return whileExpr
 }
 
 func (p *Parser) parseDoWhileExpression() my_ast.Expression {
+	start := p.curToken.Span.Start
 	p.nextToken()
 	if !p.isCurToken(token.LBRACE) {
 		p.appendTokenError(token.LBRACE, p.curToken)
@@ -500,9 +680,64 @@ func (p *Parser) parseDoWhileExpression() my_ast.Expression {
 	} else {
 		doWhileExpr.TestExpr = nil
 	}
+	doWhileExpr.SetSpan(p.spanFrom(start))
 	return doWhileExpr
 }
 
 func (p *Parser) parseNullLiteral() my_ast.Expression {
-	return my_ast.NULL
+	null := &my_ast.Null{}
+	null.SetSpan(p.curToken.Span)
+	return null
+}
+
+// parseTryExpression: try { <BLOCK> } [catch [(<IDENT>)] { <BLOCK> }] [finally { <BLOCK> }]
+// example: try { risky() } catch (e) { e["message"] } finally { cleanup() }
+// at least one of catch and finally is required
+func (p *Parser) parseTryExpression() my_ast.Expression {
+	start := p.curToken.Span.Start
+	p.nextToken()
+	te := &my_ast.TryExpression{Body: p.parseBlockStatement()}
+	if !p.isCurToken(token.RBRACE) {
+		p.appendTokenError(token.RBRACE, p.curToken)
+		return nil
+	}
+	if p.isPeekToken(token.CATCH) {
+		p.nextToken()
+		if p.isPeekToken(token.LPAREN) {
+			p.nextToken()
+			if !p.isPeekToken(token.IDENT) {
+				p.appendTokenError(token.IDENT, p.peekToken)
+				return nil
+			}
+			p.nextToken()
+			te.CatchParam = &my_ast.Identifier{Value: p.curToken.Literal}
+			te.CatchParam.SetSpan(p.curToken.Span)
+			if !p.isPeekToken(token.RPAREN) {
+				p.appendTokenError(token.RPAREN, p.peekToken)
+				return nil
+			}
+			p.nextToken()
+		}
+		p.nextToken()
+		te.Catch = p.parseBlockStatement()
+		if !p.isCurToken(token.RBRACE) {
+			p.appendTokenError(token.RBRACE, p.curToken)
+			return nil
+		}
+	}
+	if p.isPeekToken(token.FINALLY) {
+		p.nextToken()
+		p.nextToken()
+		te.Finally = p.parseBlockStatement()
+		if !p.isCurToken(token.RBRACE) {
+			p.appendTokenError(token.RBRACE, p.curToken)
+			return nil
+		}
+	}
+	if te.Catch == nil && te.Finally == nil {
+		p.appendTokenError(token.CATCH, p.peekToken)
+		return nil
+	}
+	te.SetSpan(p.spanFrom(start))
+	return te
 }
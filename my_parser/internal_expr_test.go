@@ -17,7 +17,7 @@ func TestParseExpressionStatement(t *testing.T) {
 	assert.NotNil(t, prog)
 	assert.NotNil(t, prog.Statements)
 	assert.Equal(t, 3, len(prog.Statements))
-	assert.Nil(t, p.err)
+	assert.Nil(t, p.Errors())
 	assert.Equal(t, "a", prog.Statements[0].(*my_ast.ExpressionStatement).Expression.(*my_ast.Identifier).Value)
 	assert.Equal(t, "b", prog.Statements[1].(*my_ast.ExpressionStatement).Expression.(*my_ast.Identifier).Value)
 	letStmt, lok := prog.Statements[2].(*my_ast.LetStatement)
@@ -34,7 +34,7 @@ func TestParseNumberStatement(t *testing.T) {
 	assert.NotNil(t, prog)
 	assert.NotNil(t, prog.Statements)
 	assert.Equal(t, 2, len(prog.Statements))
-	assert.Nil(t, p.err)
+	assert.Nil(t, p.Errors())
 	assert.EqualValues(t, 1, prog.Statements[0].(*my_ast.ExpressionStatement).Expression.(*my_ast.Integer).Value)
 	assert.EqualValues(t, 1.234, prog.Statements[1].(*my_ast.ExpressionStatement).Expression.(*my_ast.Float).Value)
 }
@@ -47,7 +47,7 @@ func TestPrefixExpressionStatement(t *testing.T) {
 	assert.NotNil(t, prog)
 	assert.NotNil(t, prog.Statements)
 	assert.Equal(t, 2, len(prog.Statements))
-	assert.Nil(t, p.err)
+	assert.Nil(t, p.Errors())
 	prefixNode, pok := prog.Statements[0].(*my_ast.ExpressionStatement).
 		Expression.(*my_ast.PrefixExpression)
 	assert.True(t, pok)
@@ -75,6 +75,8 @@ func TestInfixExpressionStatement(t *testing.T) {
 		{"5\t<5", 5, 5, "<"},
 		{"5== 5;", 5, 5, "=="},
 		{"5 !=5", 5, 5, "!="},
+		{"5 && 5", 5, 5, "&&"},
+		{"5 || 5", 5, 5, "||"},
 	}
 	for _, test := range tests {
 		l := lexer.New(test.input)
@@ -99,7 +101,8 @@ type TestWithExpect struct {
 
 func TestOperatorPrecedence(t *testing.T) {
 	tests := []TestWithExpect{
-		{"-b*c", "(-(b*c));"},
+		{"-b*c", "((-b)*c);"},
+		{"!true * 3", "((!true)*3);"},
 		{"a*b-c", "((a*b)-c);"},
 		{"!-c", "(!(-c));"},
 		{"-1+2", "((-1)+2);"},
@@ -182,7 +185,7 @@ func TestParseLetReturn(t *testing.T) {
 	assert.NotNil(t, prog)
 	assert.NotNil(t, prog.Statements)
 	assert.Equal(t, 2, len(prog.Statements))
-	assert.Nil(t, p.err)
+	assert.Nil(t, p.Errors())
 	assert.Equal(t, "a", prog.Statements[0].(*my_ast.LetStatement).Ident.Value)
 	assert.Equal(t, "b", prog.Statements[0].(*my_ast.LetStatement).Value.(*my_ast.Identifier).Value)
 	assert.Equal(t, "c", prog.Statements[1].(*my_ast.ReturnStatement).Value.(*my_ast.Identifier).Value)
@@ -210,7 +213,7 @@ func TestStringLiteral(t *testing.T) {
 	p := New(l)
 	prog := p.Parse()
 	assert.NotNil(t, prog)
-	assert.Nil(t, p.err)
+	assert.Nil(t, p.Errors())
 	assert.NotNil(t, prog.Statements)
 	assert.Equal(t, 1, len(prog.Statements))
 	es, eok := prog.Statements[0].(*my_ast.ExpressionStatement)
@@ -290,6 +293,36 @@ func TestParseGTELTEExpression(t *testing.T) {
 	testSingleStringedStatements(t, tests)
 }
 
+func TestParseLogicalExpression(t *testing.T) {
+	tests := []TestWithExpect{
+		{"a || b && c", "(a||(b&&c));"},
+		{"a && b || c", "((a&&b)||c);"},
+		{"a && b && c", "((a&&b)&&c);"},
+		{"a == 1 && b < 2", "((a==1)&&(b<2));"},
+		{"x = a || !b", "(x=(a||(!b)));"},
+	}
+	testSingleStringedStatements(t, tests)
+}
+
+func TestParseArithmeticBitwiseExpression(t *testing.T) {
+	tests := []TestWithExpect{
+		{"a % b * c // d", "(((a%b)*c)//d);"},
+		{"2 ** 3 ** 2", "(2**(3**2));"},
+		{"-2 ** 2", "(-(2**2));"},
+		{"-7 % 3", "((-7)%3);"},
+		{"-7 // 2", "((-7)//2);"},
+		{"-a * b % c", "(((-a)*b)%c);"},
+		{"~2 * 3", "((~2)*3);"},
+		{"x * -a // b", "((x*(-a))//b);"},
+		{"a * b ** c", "(a*(b**c));"},
+		{"a | b ^ c & d", "(a|(b^(c&d)));"},
+		{"1 + 2 << 3 & 4", "(((1+2)<<3)&4);"},
+		{"a & 1 == 0", "((a&1)==0);"},
+		{"~a >> 1", "((~a)>>1);"},
+	}
+	testSingleStringedStatements(t, tests)
+}
+
 func TestParseReassignExpression(t *testing.T) {
 	tests := []TestWithExpect{
 		{"a=a+1", "(a=(a+1));"},
@@ -297,6 +330,35 @@ func TestParseReassignExpression(t *testing.T) {
 	testSingleStringedStatements(t, tests)
 }
 
+func TestParseAssignExpression(t *testing.T) {
+	tests := []TestWithExpect{
+		{"a += 1", "(a+=1);"},
+		{"a -= b * 2", "(a-=(b*2));"},
+		{"a[0] *= 2", "((a[0])*=2);"},
+		{"h[\"k\"] /= 2", "((h[k])/=2);"},
+		{"h[\"k\"] %= a = 3", "((h[k])%=(a=3));"},
+		{"a++", "(a+=1);"},
+		{"a[i]--", "((a[i])-=1);"},
+		{"--a", "(-(-a));"},
+		{"1--1", "(1-(-1));"},
+		{"a--1", "(a-(-1));"},
+		{"a -- b", "(a-(-b));"},
+		{"a--1 * 2", "(a-((-1)*2));"},
+		{"x * a--1", "((x*a)-(-1));"},
+		{"a++ + 1", "((a+=1)+1);"},
+		{"let b = a--", "let b = (a-=1);"},
+	}
+	testSingleStringedStatements(t, tests)
+
+	// ++ and -- at the end of a line are increments, whatever the next line is
+	tests = []TestWithExpect{
+		{"a--\na", "(a-=1);a;"},
+		{"a++\na", "(a+=1);a;"},
+		{"for (i = 0; i < 3; i++) { a[i]-- }", "for((i=0);(i<3);(i+=1)){((a[i])-=1);};"},
+	}
+	testMultipleStringedStatements(t, tests)
+}
+
 func TestParseBreakContinueStatement(t *testing.T) {
 	tests := []TestWithExpect{
 		{"break;1+2", "break;(1+2);"},
@@ -1,8 +1,11 @@
 package my_parser
 
 import (
+	"fmt"
 	"monkey/my_ast"
 	token "monkey/my_token"
+	"path"
+	"strings"
 )
 
 // parseStatement parse until curToken is ; or EOF
@@ -16,6 +19,11 @@ func (p *Parser) parseStatement() my_ast.Statement {
 		return p.parseBreakStatement()
 	case token.CONTINUE:
 		return p.parseContinueStatement()
+	case token.THROW:
+		return p.parseThrowStatement()
+	case token.IMPORT:
+		p.appendError(p.curToken.Span.Start, "import is only allowed at the top level")
+		return nil
 	default:
 		return p.parseExpressionStatement()
 	}
@@ -24,6 +32,7 @@ func (p *Parser) parseStatement() my_ast.Statement {
 // parseLetStatement: let <IDENT> = <EXPR>
 // example: let a = 1 + 2
 func (p *Parser) parseLetStatement() *my_ast.LetStatement {
+	start := p.curToken.Span.Start
 	stmt := &my_ast.LetStatement{}
 	if !p.isPeekToken(token.IDENT) {
 		p.appendTokenError(token.IDENT, p.peekToken)
@@ -33,6 +42,7 @@ func (p *Parser) parseLetStatement() *my_ast.LetStatement {
 	stmt.Ident = &my_ast.Identifier{
 		Value: p.curToken.Literal,
 	}
+	stmt.Ident.SetSpan(p.curToken.Span)
 	if !p.isPeekToken(token.REASSIGN) {
 		p.appendTokenError(token.REASSIGN, p.peekToken)
 		return nil
@@ -40,25 +50,32 @@ func (p *Parser) parseLetStatement() *my_ast.LetStatement {
 	p.nextToken()
 	p.nextToken()
 	stmt.Value = p.parseExpression(LOWEST)
+	if fn, ok := stmt.Value.(*my_ast.Function); ok {
+		fn.Name = stmt.Ident.Value
+	}
 	if p.isPeekToken(token.SEMICOLON) {
 		p.nextToken()
 	}
+	stmt.SetSpan(p.spanFrom(start))
 	return stmt
 }
 
 // parseReturnStatement: return <EXPR>
 // example: return a
 func (p *Parser) parseReturnStatement() *my_ast.ReturnStatement {
+	start := p.curToken.Span.Start
 	stmt := &my_ast.ReturnStatement{}
 	p.nextToken()
 	stmt.Value = p.parseExpression(LOWEST)
 	if p.isPeekToken(token.SEMICOLON) {
 		p.nextToken()
 	}
+	stmt.SetSpan(p.spanFrom(start))
 	return stmt
 }
 
 func (p *Parser) parseExpressionStatement() *my_ast.ExpressionStatement {
+	start := p.curToken.Span.Start
 	stmt := &my_ast.ExpressionStatement{
 		Expression: p.parseExpression(LOWEST),
 	}
@@ -67,6 +84,7 @@ func (p *Parser) parseExpressionStatement() *my_ast.ExpressionStatement {
 	if p.isPeekToken(token.SEMICOLON) {
 		p.nextToken()
 	}
+	stmt.SetSpan(p.spanFrom(start))
 	return stmt
 }
 
@@ -76,21 +94,103 @@ func (p *Parser) parseBlockStatement() *my_ast.BlockStatement {
 		p.appendTokenError(token.LBRACE, p.curToken)
 		return nil
 	}
+	start := p.curToken.Span.Start
 	p.nextToken()
 	bs := &my_ast.BlockStatement{}
 	for !p.isCurToken(token.RBRACE) && !p.isCurToken(token.EOF) {
-		bs.Statements = append(bs.Statements, p.parseStatement())
+		errCount := len(p.errs)
+		stmt := p.parseStatement()
+		if len(p.errs) > errCount {
+			p.synchronize()
+			if p.isCurToken(token.RBRACE) {
+				break
+			}
+		} else {
+			bs.Statements = append(bs.Statements, stmt)
+		}
 		p.nextToken()
 	}
+	bs.SetSpan(p.spanFrom(start))
 	return bs
 }
 
 func (p *Parser) parseBreakStatement() *my_ast.BreakStatement {
+	stmt := &my_ast.BreakStatement{}
+	stmt.SetSpan(p.curToken.Span)
 	p.nextToken()
-	return &my_ast.BreakStatement{}
+	return stmt
 }
 
 func (p *Parser) parseContinueStatement() *my_ast.ContinueStatement {
+	stmt := &my_ast.ContinueStatement{}
+	stmt.SetSpan(p.curToken.Span)
+	p.nextToken()
+	return stmt
+}
+
+// parseThrowStatement: throw <EXPR>
+// example: throw "not found"
+func (p *Parser) parseThrowStatement() *my_ast.ThrowStatement {
+	start := p.curToken.Span.Start
+	stmt := &my_ast.ThrowStatement{}
 	p.nextToken()
-	return &my_ast.ContinueStatement{}
+	stmt.Value = p.parseExpression(LOWEST)
+	if p.isPeekToken(token.SEMICOLON) {
+		p.nextToken()
+	}
+	stmt.SetSpan(p.spanFrom(start))
+	return stmt
+}
+
+// parseImportStatement: import <STRING|IDENT> [as <IDENT>]
+// example: import "lib/math.monkey" as m
+// without `as`, the module is bound to its file name without extension
+func (p *Parser) parseImportStatement() *my_ast.ImportStatement {
+	start := p.curToken.Span.Start
+	stmt := &my_ast.ImportStatement{}
+	if !p.isPeekToken(token.STRING) && !p.isPeekToken(token.IDENT) {
+		p.appendTokenError(token.STRING, p.peekToken)
+		return nil
+	}
+	p.nextToken()
+	stmt.Path = p.curToken.Literal
+	if p.isPeekToken(token.IDENT) && p.peekToken.Literal == my_ast.ImportAs {
+		p.nextToken()
+		if !p.isPeekToken(token.IDENT) {
+			p.appendTokenError(token.IDENT, p.peekToken)
+			return nil
+		}
+		p.nextToken()
+		stmt.Ident = &my_ast.Identifier{Value: p.curToken.Literal}
+		stmt.Ident.SetSpan(p.curToken.Span)
+	} else {
+		name := strings.TrimSuffix(path.Base(stmt.Path), ModuleExt)
+		if !isIdentifier(name) {
+			p.appendError(p.curToken.Span.Start, fmt.Sprintf("cannot bind module %q to %q, add `as <name>`", stmt.Path, name))
+			return nil
+		}
+		stmt.Ident = &my_ast.Identifier{Value: name}
+		stmt.Ident.SetSpan(p.curToken.Span)
+	}
+	if p.isPeekToken(token.SEMICOLON) {
+		p.nextToken()
+	}
+	stmt.SetSpan(p.spanFrom(start))
+	return stmt
+}
+
+// ModuleExt: extension of module files, optional in import statements
+const ModuleExt = ".monkey"
+
+// isIdentifier: whether name is lexed as a single identifier
+func isIdentifier(name string) bool {
+	if name == "" || token.LookupIdent(name) != token.IDENT {
+		return false
+	}
+	for _, ch := range name {
+		if !('a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || ch == '_') {
+			return false
+		}
+	}
+	return true
 }
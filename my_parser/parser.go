@@ -2,6 +2,7 @@ package my_parser
 
 import (
 	"errors"
+	"fmt"
 	"monkey/my_ast"
 	lexer "monkey/my_lexer"
 	token "monkey/my_token"
@@ -11,7 +12,7 @@ type Parser struct {
 	lexer     *lexer.Lexer
 	curToken  token.Token
 	peekToken token.Token
-	err       error
+	errs      ErrorList
 
 	prefixParseFns map[token.TokenType]prefixParseFn
 	infixParseFns  map[token.TokenType]infixParseFn
@@ -19,6 +20,35 @@ type Parser struct {
 
 var ErrParseError = errors.New("parse error")
 
+// Diagnostic: a syntax error located at Pos in source code
+type Diagnostic struct {
+	Pos token.Position
+	Msg string
+}
+
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%s: %s", d.Pos, d.Msg)
+}
+
+// ErrorList: all diagnostics reported by a parser in source order;
+// it is an error which wraps ErrParseError
+type ErrorList []Diagnostic
+
+func (el ErrorList) Error() string {
+	switch len(el) {
+	case 0:
+		return ErrParseError.Error()
+	case 1:
+		return el[0].String()
+	default:
+		return fmt.Sprintf("%s (and %d more errors)", el[0], len(el)-1)
+	}
+}
+
+func (el ErrorList) Unwrap() error {
+	return ErrParseError
+}
+
 func New(l *lexer.Lexer) *Parser {
 	p := &Parser{
 		lexer:          l,
@@ -32,6 +62,8 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerPrefix(token.FLOAT, p.parseFloatLiteral)
 	p.registerPrefix(token.BANG, p.parsePrefixExpression)
 	p.registerPrefix(token.MINUS, p.parsePrefixExpression)
+	p.registerPrefix(token.DECREMENT, p.parseDoubleMinus)
+	p.registerPrefix(token.TILDE, p.parsePrefixExpression)
 	p.registerPrefix(token.TRUE, p.parseBooleanLiteral)
 	p.registerPrefix(token.FALSE, p.parseBooleanLiteral)
 	p.registerPrefix(token.LPAREN, p.parseGroupedExpression)
@@ -44,6 +76,7 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerPrefix(token.WHILE, p.parseWhileExression)
 	p.registerPrefix(token.DO, p.parseDoWhileExpression)
 	p.registerPrefix(token.NULL, p.parseNullLiteral)
+	p.registerPrefix(token.TRY, p.parseTryExpression)
 
 	p.registerInfix(token.MINUS, p.parseInfixExpression)
 	p.registerInfix(token.PLUS, p.parseInfixExpression)
@@ -58,6 +91,23 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerInfix(token.LTE, p.parseInfixExpression)
 	p.registerInfix(token.GTE, p.parseInfixExpression)
 	p.registerInfix(token.REASSIGN, p.parseInfixExpression)
+	p.registerInfix(token.AND, p.parseInfixExpression)
+	p.registerInfix(token.OR, p.parseInfixExpression)
+	p.registerInfix(token.PERCENT, p.parseInfixExpression)
+	p.registerInfix(token.POWER, p.parseInfixExpression)
+	p.registerInfix(token.FLOOR_SLASH, p.parseInfixExpression)
+	p.registerInfix(token.AMPERSAND, p.parseInfixExpression)
+	p.registerInfix(token.PIPE, p.parseInfixExpression)
+	p.registerInfix(token.CARET, p.parseInfixExpression)
+	p.registerInfix(token.SHL, p.parseInfixExpression)
+	p.registerInfix(token.SHR, p.parseInfixExpression)
+	p.registerInfix(token.PLUS_ASSIGN, p.parseAssignExpression)
+	p.registerInfix(token.MINUS_ASSIGN, p.parseAssignExpression)
+	p.registerInfix(token.ASTERISK_ASSIGN, p.parseAssignExpression)
+	p.registerInfix(token.SLASH_ASSIGN, p.parseAssignExpression)
+	p.registerInfix(token.PERCENT_ASSIGN, p.parseAssignExpression)
+	p.registerInfix(token.INCREMENT, p.parseIncrementOrSigns)
+	p.registerInfix(token.DECREMENT, p.parseIncrementOrSigns)
 
 	// lexer.NextToken() will continue to produce EOF if finished without error
 	p.nextToken()
@@ -66,21 +116,41 @@ func New(l *lexer.Lexer) *Parser {
 }
 
 func (p *Parser) Parse() *my_ast.Program {
+	start := p.curToken.Span.Start
 	prog := &my_ast.Program{
 		Statements: []my_ast.Statement{},
 	}
 	for p.curToken.Type != token.EOF {
-		stmt := p.parseStatement()
-		if stmt != nil {
+		errCount := len(p.errs)
+		var stmt my_ast.Statement
+		if p.isCurToken(token.IMPORT) {
+			stmt = p.parseImportStatement()
+		} else {
+			stmt = p.parseStatement()
+		}
+		if len(p.errs) > errCount {
+			// drop the broken statement and continue with the next one
+			p.synchronize()
+		} else if stmt != nil {
 			prog.Statements = append(prog.Statements, stmt)
 		}
 		p.nextToken()
 	}
+	prog.SetSpan(p.spanFrom(start))
 	return prog
 }
 
+// Error: nil if no syntax error, otherwise an ErrorList
 func (p *Parser) Error() error {
-	return p.err
+	if len(p.errs) == 0 {
+		return nil
+	}
+	return p.errs
+}
+
+// Errors: all syntax errors found in source code
+func (p *Parser) Errors() []Diagnostic {
+	return p.errs
 }
 
 func (p *Parser) registerPrefix(t token.TokenType, f prefixParseFn) {
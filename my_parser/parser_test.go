@@ -1,8 +1,10 @@
 package my_parser
 
 import (
+	"errors"
 	"monkey/my_ast"
 	lexer "monkey/my_lexer"
+	token "monkey/my_token"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -28,7 +30,7 @@ func TestLetStatement(t *testing.T) {
 			identValue,
 			prog.Statements[idx].(*my_ast.LetStatement).Ident.Value)
 	}
-	assert.Nil(t, p.err)
+	assert.Nil(t, p.Errors())
 }
 
 func TestLetStatementError(t *testing.T) {
@@ -38,7 +40,7 @@ func TestLetStatementError(t *testing.T) {
 	l := lexer.New(input)
 	p := New(l)
 	p.Parse()
-	assert.ErrorIs(t, p.err, ErrParseError)
+	assert.ErrorIs(t, p.Error(), ErrParseError)
 }
 
 func TestReturnStatement(t *testing.T) {
@@ -51,5 +53,168 @@ func TestReturnStatement(t *testing.T) {
 	assert.NotNil(t, prog)
 	assert.NotNil(t, prog.Statements)
 	assert.Equal(t, 2, len(prog.Statements))
-	assert.Nil(t, p.err)
+	assert.Nil(t, p.Errors())
+}
+
+func TestLetStatementFunctionName(t *testing.T) {
+	input := `let myFunction = fn() { };`
+	l := lexer.New(input)
+	p := New(l)
+	prog := p.Parse()
+	assert.Nil(t, p.Errors())
+	assert.Equal(t, 1, len(prog.Statements))
+	fn, ok := prog.Statements[0].(*my_ast.LetStatement).Value.(*my_ast.Function)
+	assert.True(t, ok)
+	assert.Equal(t, "myFunction", fn.Name)
+}
+
+func TestImportStatement(t *testing.T) {
+	input := `import "lib/math.monkey";
+import strings
+import "lib/my-utils" as utils;`
+	l := lexer.New(input)
+	p := New(l)
+	prog := p.Parse()
+	assert.Nil(t, p.Errors())
+	expected := [][2]string{{"lib/math.monkey", "math"}, {"strings", "strings"}, {"lib/my-utils", "utils"}}
+	assert.Equal(t, len(expected), len(prog.Statements))
+	for idx, stmt := range prog.Statements {
+		imp := stmt.(*my_ast.ImportStatement)
+		assert.Equal(t, expected[idx][0], imp.Path)
+		assert.Equal(t, expected[idx][1], imp.Ident.Value)
+	}
+	assert.Equal(t, `import "lib/my-utils" as utils;`, prog.Statements[2].String())
+
+	l = lexer.New(`import "my-utils";
+if (true) { import strings };
+import 1;`)
+	p = New(l)
+	p.Parse()
+	assert.Equal(t, []Diagnostic{
+		{Pos: token.Position{Offset: 7, Line: 1, Column: 8}, Msg: `cannot bind module "my-utils" to "my-utils", add ` + "`as <name>`"},
+		{Pos: token.Position{Offset: 31, Line: 2, Column: 13}, Msg: "import is only allowed at the top level"},
+		{Pos: token.Position{Offset: 56, Line: 3, Column: 8}, Msg: "expecting token STRING, but got INT with literal 1 instead"},
+	}, p.Errors())
+}
+
+func TestTryExpression(t *testing.T) {
+	input := `try { f() } catch (e) { e } finally { g() };
+try { f() } catch { 1 };
+try { f() } finally { g() };
+throw "oops";`
+	l := lexer.New(input)
+	p := New(l)
+	prog := p.Parse()
+	assert.Nil(t, p.Errors())
+	expected := []string{
+		"try{f();}catch(e){e;}finally{g();};",
+		"try{f();}catch{1;};",
+		"try{f();}finally{g();};",
+		`throw oops;`,
+	}
+	assert.Equal(t, len(expected), len(prog.Statements))
+	for idx, stmt := range prog.Statements {
+		assert.Equal(t, expected[idx], stmt.String())
+	}
+	te := prog.Statements[0].(*my_ast.ExpressionStatement).Expression.(*my_ast.TryExpression)
+	assert.Equal(t, "e", te.CatchParam.Value)
+	assert.Nil(t, prog.Statements[1].(*my_ast.ExpressionStatement).Expression.(*my_ast.TryExpression).CatchParam)
+
+	l = lexer.New("try { f() };\ntry { f() } catch (1)")
+	p = New(l)
+	p.Parse()
+	assert.Equal(t, []Diagnostic{
+		{Pos: token.Position{Offset: 11, Line: 1, Column: 12}, Msg: "expecting token CATCH, but got ; with literal ; instead"},
+		{Pos: token.Position{Offset: 32, Line: 2, Column: 20}, Msg: "expecting token IDENT, but got INT with literal 1 instead"},
+	}, p.Errors())
+}
+
+func TestParseErrorPosition(t *testing.T) {
+	input := "let a = 1;\nlet 2;"
+	l := lexer.New(input)
+	p := New(l)
+	p.Parse()
+	assert.ErrorIs(t, p.Error(), ErrParseError)
+	assert.Equal(t, "2:5: expecting token IDENT, but got INT with literal 2 instead", p.Error().Error())
+	var errList ErrorList
+	assert.True(t, errors.As(p.Error(), &errList))
+	assert.Equal(t, 1, len(errList))
+	assert.Equal(t, token.Position{Offset: 15, Line: 2, Column: 5}, errList[0].Pos)
+}
+
+func TestParseMultipleErrors(t *testing.T) {
+	input := `let a = 1;
+let 2;
+let b = fn(x) {
+	x + ;
+	x
+};
+let c = (1 + 2;
+let d = a + b(1);
+`
+	l := lexer.New(input)
+	p := New(l)
+	prog := p.Parse()
+	assert.Equal(t, []Diagnostic{
+		{Pos: token.Position{Offset: 15, Line: 2, Column: 5}, Msg: "expecting token IDENT, but got INT with literal 2 instead"},
+		{Pos: token.Position{Offset: 39, Line: 4, Column: 6}, Msg: "no prefix parse func: token type: ;: literal: ;"},
+		{Pos: token.Position{Offset: 61, Line: 7, Column: 15}, Msg: "expecting token ), but got ; with literal ; instead"},
+	}, p.Errors())
+	assert.Equal(t, "2:5: expecting token IDENT, but got INT with literal 2 instead (and 2 more errors)", p.Error().Error())
+
+	// statements without errors are kept
+	idents := []string{}
+	for _, stmt := range prog.Statements {
+		idents = append(idents, stmt.(*my_ast.LetStatement).Ident.Value)
+	}
+	assert.Equal(t, []string{"a", "d"}, idents)
+}
+
+func TestAssignExpressionErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		msg   string
+	}{
+		{"1 += 2", "1:1: cannot assign to 1"},
+		{"a[1:] += 2", "1:1: cannot assign to (a[1:])"},
+		{"f() ++", "1:1: cannot assign to f()"},
+		{"++a", "1:1: no prefix parse func: token type: ++: literal: ++"},
+		{"a + b++", "1:1: cannot assign to (a+b)"},
+		{"1++1", "1:3: no prefix parse func: token type: +: literal: +"},
+	}
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.Parse()
+		if assert.Error(t, p.Error(), "input=%s", tt.input) {
+			assert.Equal(t, tt.msg, p.Errors()[0].String(), "input=%s", tt.input)
+		}
+	}
+}
+
+func TestNodeSpan(t *testing.T) {
+	input := "let add = fn(a, b) {\n  a + b\n};\nadd(1, 2)[0];"
+	l := lexer.New(input)
+	p := New(l)
+	prog := p.Parse()
+	assert.Nil(t, p.Errors())
+	assert.Equal(t, 2, len(prog.Statements))
+
+	source := func(node my_ast.Node) string {
+		span := node.Span()
+		return input[span.Start.Offset:span.End.Offset]
+	}
+	letStmt := prog.Statements[0].(*my_ast.LetStatement)
+	assert.Equal(t, "let add = fn(a, b) {\n  a + b\n};", source(letStmt))
+	assert.Equal(t, "add", source(letStmt.Ident))
+	fn := letStmt.Value.(*my_ast.Function)
+	assert.Equal(t, "fn(a, b) {\n  a + b\n}", source(fn))
+	assert.Equal(t, "b", source(fn.Parameters[1]))
+	infix := fn.Body.Statements[0].(*my_ast.ExpressionStatement).Expression
+	assert.Equal(t, "a + b", source(infix))
+	assert.Equal(t, token.Position{Offset: 23, Line: 2, Column: 3}, infix.Span().Start)
+
+	index := prog.Statements[1].(*my_ast.ExpressionStatement).Expression.(*my_ast.IndexExpression)
+	assert.Equal(t, "add(1, 2)[0]", source(index))
+	assert.Equal(t, "add(1, 2)", source(index.Left))
+	assert.Equal(t, token.Position{Offset: 32, Line: 4, Column: 1}, index.Span().Start)
 }
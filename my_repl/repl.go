@@ -1,10 +1,16 @@
 package my_repl
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"monkey/my_engine"
+	"monkey/my_module"
+	"monkey/my_parser"
+	token "monkey/my_token"
+	"monkey/my_vm"
 	"os"
+	"strings"
 
 	// "monkey/evaluator"
 	// "monkey/parser"
@@ -30,7 +36,7 @@ func Start(out io.Writer, engine my_engine.Engine) {
 		default:
 			evaluated, err := engine.Evaluate(line)
 			if err != nil {
-				PrintErrors(out, err)
+				PrintErrors(out, line, err)
 				return
 			}
 			if evaluated != nil {
@@ -55,9 +61,82 @@ const MONKEY_FACE = `            __,__
            '-----'
 `
 
-func PrintErrors(out io.Writer, err error) {
+// PrintErrors prints err raised from code;
+// errors with positions are printed with a caret under the offending code,
+// read from the module raising them if not code itself.
+func PrintErrors(out io.Writer, code string, err error) {
 	io.WriteString(out, MONKEY_FACE)
 	io.WriteString(out, "Woops! We ran into some monkey business here!\n")
-	io.WriteString(out, " parser errors:\n")
-	io.WriteString(out, fmt.Sprintf("\t%s\n", err.Error()))
+	switch {
+	case errors.Is(err, my_engine.ErrCompile):
+		io.WriteString(out, " compile errors:\n")
+	case errors.Is(err, my_engine.ErrRuntime):
+		io.WriteString(out, " runtime errors:\n")
+	default:
+		io.WriteString(out, " parser errors:\n")
+	}
+	var runtimeErr *my_vm.RuntimeError
+	if errors.As(err, &runtimeErr) {
+		printRuntimeError(out, code, runtimeErr)
+		return
+	}
+	var errList my_parser.ErrorList
+	if !errors.As(err, &errList) || len(errList) == 0 {
+		io.WriteString(out, fmt.Sprintf("\t%s\n", err.Error()))
+		return
+	}
+	var moduleErr *my_module.Error
+	if errors.As(err, &moduleErr) {
+		io.WriteString(out, fmt.Sprintf("\t%s:\n", moduleErr.Path))
+		code = moduleSource(moduleErr.Path)
+	}
+	for _, diag := range errList {
+		io.WriteString(out, fmt.Sprintf("\t%s\n", diag))
+		printCaret(out, code, diag.Pos)
+	}
+}
+
+// printRuntimeError prints where the vm stops with the active frames
+func printRuntimeError(out io.Writer, code string, err *my_vm.RuntimeError) {
+	if !err.Pos.IsValid() {
+		io.WriteString(out, fmt.Sprintf("\t%s\n", err.Error()))
+	} else if err.Module != "" {
+		io.WriteString(out, fmt.Sprintf("\t%s:%s: %s\n", err.Module, err.Pos, err.Error()))
+		printCaret(out, moduleSource(err.Module), err.Pos)
+	} else {
+		io.WriteString(out, fmt.Sprintf("\t%s: %s\n", err.Pos, err.Error()))
+		printCaret(out, code, err.Pos)
+	}
+	for _, line := range strings.Split(strings.TrimSuffix(err.Traceback(), "\n"), "\n") {
+		io.WriteString(out, fmt.Sprintf("\t%s\n", line))
+	}
+}
+
+// moduleSource: source of the module at path, empty if it cannot be read
+func moduleSource(path string) string {
+	code, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return string(code)
+}
+
+// printCaret prints the line of code at pos with a caret under its column, nothing if code is unknown
+func printCaret(out io.Writer, code string, pos token.Position) {
+	if !pos.IsValid() || code == "" || pos.Offset > len(code) {
+		return
+	}
+	lineStart := strings.LastIndexByte(code[:pos.Offset], '\n') + 1
+	lineEnd := len(code)
+	if idx := strings.IndexByte(code[pos.Offset:], '\n'); idx >= 0 {
+		lineEnd = pos.Offset + idx
+	}
+	// keep tabs so that the caret lines up with the code
+	indent := strings.Map(func(r rune) rune {
+		if r == '\t' {
+			return r
+		}
+		return ' '
+	}, code[lineStart:pos.Offset])
+	io.WriteString(out, fmt.Sprintf("\t%s\n\t%s^\n", code[lineStart:lineEnd], indent))
 }
@@ -1,5 +1,7 @@
 package my_token
 
+import "fmt"
+
 type TokenType string
 
 const (
@@ -20,6 +22,24 @@ const (
 	ASTERISK = "*"
 	SLASH    = "/"
 
+	PERCENT     = "%"
+	POWER       = "**"
+	FLOOR_SLASH = "//"
+	AMPERSAND   = "&"
+	PIPE        = "|"
+	CARET       = "^"
+	TILDE       = "~"
+	SHL         = "<<"
+	SHR         = ">>"
+
+	PLUS_ASSIGN     = "+="
+	MINUS_ASSIGN    = "-="
+	ASTERISK_ASSIGN = "*="
+	SLASH_ASSIGN    = "/="
+	PERCENT_ASSIGN  = "%="
+	INCREMENT       = "++"
+	DECREMENT       = "--"
+
 	LT  = "<"
 	GT  = ">"
 	LTE = "<="
@@ -28,6 +48,9 @@ const (
 	EQ     = "=="
 	NOT_EQ = "!="
 
+	AND = "&&"
+	OR  = "||"
+
 	// Delimiters
 	COMMA     = ","
 	SEMICOLON = ";"
@@ -54,11 +77,48 @@ const (
 	BREAK    = "BREAK"
 	CONTINUE = "CONTINUE"
 	NULL     = "NULL"
+	IMPORT   = "IMPORT"
+	TRY      = "TRY"
+	CATCH    = "CATCH"
+	FINALLY  = "FINALLY"
+	THROW    = "THROW"
 )
 
 type Token struct {
 	Type    TokenType
 	Literal string
+	Span    Span
+}
+
+// Position: a location in source code;
+// Offset is the byte offset starting from 0;
+// Line and Column start from 1, Column counts in bytes.
+type Position struct {
+	Offset int
+	Line   int
+	Column int
+}
+
+// IsValid: zero value Position is not a location from source code
+func (p Position) IsValid() bool {
+	return p.Line > 0
+}
+
+func (p Position) String() string {
+	if !p.IsValid() {
+		return "-"
+	}
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
+}
+
+// Span: source code between [Start, End)
+type Span struct {
+	Start Position
+	End   Position
+}
+
+func (s Span) String() string {
+	return s.Start.String()
 }
 
 var keywords = map[string]TokenType{
@@ -75,6 +135,11 @@ var keywords = map[string]TokenType{
 	"break":    BREAK,
 	"continue": CONTINUE,
 	"null":     NULL,
+	"import":   IMPORT,
+	"try":      TRY,
+	"catch":    CATCH,
+	"finally":  FINALLY,
+	"throw":    THROW,
 }
 
 func LookupIdent(ident string) TokenType {
@@ -98,6 +163,11 @@ var kwreversed = map[TokenType]string{
 	BREAK:    "break",
 	CONTINUE: "continue",
 	NULL:     "null",
+	IMPORT:   "import",
+	TRY:      "try",
+	CATCH:    "catch",
+	FINALLY:  "finally",
+	THROW:    "throw",
 }
 
 func LookupKeywords(t TokenType) string {
@@ -0,0 +1,93 @@
+package my_vm
+
+import (
+	"fmt"
+	"monkey/my_code"
+	token "monkey/my_token"
+	"strings"
+)
+
+// RuntimeError: error raised while running bytecode, with where it happens;
+// Error() keeps the message alone so it reads the same as errors from the evaluator
+type RuntimeError struct {
+	Err error
+	// IP: offset of the failing instruction in its function
+	IP int
+	// Pos: source position of the failing instruction, invalid if compiled without debug info
+	Pos token.Position
+	// Module: path of the module whose source Pos is in, empty for the main program
+	Module string
+	// Frames: active frames when the error is raised, the outermost main being the first
+	Frames []TraceFrame
+}
+
+// TraceFrame: a function being executed and where it is executing
+type TraceFrame struct {
+	Function string
+	IP       int
+	Pos      token.Position
+}
+
+func (e *RuntimeError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *RuntimeError) Unwrap() error {
+	return e.Err
+}
+
+// Traceback: active frames, one per line, the most recent call last;
+// repeated frames of deep recursion are collapsed into one line
+func (e *RuntimeError) Traceback() string {
+	sb := &strings.Builder{}
+	sb.WriteString("traceback (most recent call last):\n")
+	for i := 0; i < len(e.Frames); {
+		frame := e.Frames[i]
+		sb.WriteString(fmt.Sprintf("  %s at %s (ip %04d)\n", frame.Function, frame.Pos, frame.IP))
+		repeated := 0
+		for i++; i < len(e.Frames) && e.Frames[i] == frame; i++ {
+			repeated++
+		}
+		if repeated > 0 {
+			sb.WriteString(fmt.Sprintf("  [previous frame repeated %d more times]\n", repeated))
+		}
+	}
+	return sb.String()
+}
+
+// runtimeError: attach positions of active frames to err
+func (vm *VM) runtimeError(err error) *RuntimeError {
+	frames := make([]TraceFrame, 0, vm.framesIndex)
+	for idx, frame := range vm.frames[:vm.framesIndex] {
+		ip := instructionStart(frame.Instructions(), frame.ip)
+		name := frame.cl.Fn.Name
+		if idx == 0 {
+			name = "main"
+		} else if name == "" {
+			name = "<anonymous>"
+		}
+		frames = append(frames, TraceFrame{
+			Function: name,
+			IP:       ip,
+			Pos:      frame.cl.Fn.Lines.Lookup(ip),
+		})
+	}
+	top := frames[len(frames)-1]
+	module := vm.frames[vm.framesIndex-1].cl.Fn.Module
+	return &RuntimeError{Err: err, IP: top.IP, Pos: top.Pos, Module: module, Frames: frames}
+}
+
+// instructionStart: offset of the instruction containing the byte at offset,
+// since ip of a frame moves past operands as soon as they are read
+func instructionStart(ins my_code.Instructions, offset int) int {
+	start := 0
+	for pos := 0; pos <= offset && pos < len(ins); {
+		start = pos
+		def, err := my_code.Lookup(ins[pos])
+		if err != nil {
+			break
+		}
+		pos += 1 + def.OperandsWidth()
+	}
+	return start
+}
@@ -0,0 +1,22 @@
+package my_vm
+
+import (
+	"monkey/my_code"
+	"monkey/my_object"
+)
+
+type Frame struct {
+	cl *my_object.Closure
+	ip int // ip: points to the instruction being executed in this frame
+	// basePointer: stack pointer before the function is executed,
+	// locals start from here and the stack is restored to here after return
+	basePointer int
+}
+
+func NewFrame(cl *my_object.Closure, basePointer int) *Frame {
+	return &Frame{cl: cl, ip: -1, basePointer: basePointer}
+}
+
+func (f *Frame) Instructions() my_code.Instructions {
+	return f.cl.Fn.Instructions
+}
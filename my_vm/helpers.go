@@ -3,9 +3,9 @@ package my_vm
 import "monkey/my_object"
 
 var (
-	NULL  = &my_object.Null{}
-	TRUE  = &my_object.Boolean{Value: true}
-	FALSE = &my_object.Boolean{Value: false}
+	NULL  = my_object.NULL
+	TRUE  = my_object.TRUE
+	FALSE = my_object.FALSE
 )
 
 func booleanToInt(in bool) (out int64) {
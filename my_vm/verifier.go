@@ -0,0 +1,215 @@
+package my_vm
+
+import (
+	"errors"
+	"fmt"
+	"monkey/my_code"
+	"monkey/my_compiler"
+	"monkey/my_object"
+)
+
+var ErrInvalidByteCode = errors.New("invalid bytecode")
+
+// Verify: check bytecode before running it, so that malformed bytecode loaded from files
+// is reported as an error instead of panicking the vm;
+// main and every function reachable from main via OpClosure or OpImport are checked for
+// valid op codes, operands in bounds, jump targets on instruction boundaries and balanced stack depth
+func Verify(byteCode *my_compiler.ByteCode) error {
+	v := &verifier{
+		constants: byteCode.Constants,
+		numFree:   map[int]int{},
+		verified:  map[int]bool{},
+	}
+	return v.verify(&functionInfo{name: "main", ins: byteCode.Instructions, isMain: true})
+}
+
+type verifier struct {
+	constants []my_object.Object
+	// numFree: number of free variables of compiled functions by their constant indices
+	numFree  map[int]int
+	verified map[int]bool
+}
+
+// functionInfo: what instructions of a function can access
+type functionInfo struct {
+	name      string
+	ins       my_code.Instructions
+	numLocals int
+	numFree   int
+	isMain    bool
+}
+
+func (v *verifier) verify(fn *functionInfo) error {
+	closures, err := v.checkInstructions(fn)
+	if err != nil {
+		return err
+	}
+	if err := v.checkStackDepth(fn); err != nil {
+		return err
+	}
+	for _, closure := range closures {
+		if v.verified[closure.constIdx] {
+			continue
+		}
+		v.verified[closure.constIdx] = true
+		compiled := v.constants[closure.constIdx].(*my_object.CompiledFunction)
+		err := v.verify(&functionInfo{
+			name:      my_compiler.FunctionName(closure.constIdx),
+			ins:       compiled.Instructions,
+			numLocals: compiled.NumLocals,
+			numFree:   closure.numFree,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type closureRef struct {
+	constIdx int
+	numFree  int
+}
+
+// checkInstructions: check op codes and operands one by one,
+// returning functions referred to by OpClosure and modules referred to by OpImport
+func (v *verifier) checkInstructions(fn *functionInfo) ([]closureRef, error) {
+	boundaries := map[int]bool{}
+	closures := []closureRef{}
+	jumps := map[int]int{}
+	for pos := 0; pos < len(fn.ins); {
+		boundaries[pos] = true
+		def, err := my_code.Lookup(fn.ins[pos])
+		if err != nil {
+			return nil, v.errorf(fn, pos, "%s", err)
+		}
+		if pos+1+def.OperandsWidth() > len(fn.ins) {
+			return nil, v.errorf(fn, pos, "operands of %s truncated", def.Name)
+		}
+		operands, bytesRead := my_code.ReadOperands(def, fn.ins[pos+1:])
+		op := my_code.Opcode(fn.ins[pos])
+		switch op {
+		case my_code.OpConstant:
+			if operands[0] >= len(v.constants) {
+				return nil, v.errorf(fn, pos, "constant index %d out of range", operands[0])
+			}
+		case my_code.OpClosure:
+			if operands[0] >= len(v.constants) {
+				return nil, v.errorf(fn, pos, "constant index %d out of range", operands[0])
+			}
+			if _, ok := v.constants[operands[0]].(*my_object.CompiledFunction); !ok {
+				return nil, v.errorf(fn, pos, "constant %d is not a function", operands[0])
+			}
+			if numFree, ok := v.numFree[operands[0]]; ok && numFree != operands[1] {
+				return nil, v.errorf(fn, pos, "%s captures %d free variables, but %d elsewhere",
+					my_compiler.FunctionName(operands[0]), operands[1], numFree)
+			}
+			v.numFree[operands[0]] = operands[1]
+			closures = append(closures, closureRef{constIdx: operands[0], numFree: operands[1]})
+		case my_code.OpImport:
+			if operands[1] >= len(v.constants) {
+				return nil, v.errorf(fn, pos, "constant index %d out of range", operands[1])
+			}
+			module, ok := v.constants[operands[1]].(*my_object.CompiledFunction)
+			if !ok || module.NumParameters != 0 {
+				return nil, v.errorf(fn, pos, "constant %d is not a module", operands[1])
+			}
+			if numFree := v.numFree[operands[1]]; numFree != 0 {
+				return nil, v.errorf(fn, pos, "%s captures %d free variables, but none as a module",
+					my_compiler.FunctionName(operands[1]), numFree)
+			}
+			v.numFree[operands[1]] = 0
+			closures = append(closures, closureRef{constIdx: operands[1]})
+		case my_code.OpGetLocal, my_code.OpSetLocal, my_code.OpCaptureLocal:
+			if operands[0] >= fn.numLocals {
+				return nil, v.errorf(fn, pos, "local index %d out of range", operands[0])
+			}
+		case my_code.OpGetFree, my_code.OpSetFree, my_code.OpCaptureFree:
+			if operands[0] >= fn.numFree {
+				return nil, v.errorf(fn, pos, "free variable index %d out of range", operands[0])
+			}
+		case my_code.OpGetBuiltin:
+			if operands[0] >= len(my_object.Builtins) {
+				return nil, v.errorf(fn, pos, "builtin index %d out of range", operands[0])
+			}
+		case my_code.OpSlice:
+			if operands[0] >= my_code.SliceIsRange<<1 {
+				return nil, v.errorf(fn, pos, "unknown slice flags %b", operands[0])
+			}
+		case my_code.OpReturn:
+			if fn.isMain {
+				return nil, v.errorf(fn, pos, "OpReturn outside function")
+			}
+		case my_code.OpJump, my_code.OpJumpNotTruthy, my_code.OpSetupTry:
+			jumps[pos] = operands[0]
+		}
+		pos += 1 + bytesRead
+	}
+	for pos, target := range jumps {
+		// main may jump right to its end to finish the program
+		if !boundaries[target] && !(fn.isMain && target == len(fn.ins)) {
+			return nil, v.errorf(fn, pos, "jump target %04d is not an instruction", target)
+		}
+	}
+	return closures, nil
+}
+
+// checkStackDepth: follow every path of control flow, making sure the stack never underflows
+// or overflows, and that paths meeting at the same instruction have the same stack depth
+func (v *verifier) checkStackDepth(fn *functionInfo) error {
+	depths := map[int]int{0: 0}
+	pending := []int{0}
+	visit := func(from, pos, depth int) error {
+		if pos == len(fn.ins) && !fn.isMain {
+			return v.errorf(fn, from, "function ends without return")
+		}
+		if existing, ok := depths[pos]; ok {
+			if existing != depth {
+				return v.errorf(fn, pos, "inconsistent stack depth: %d and %d", existing, depth)
+			}
+			return nil
+		}
+		depths[pos] = depth
+		pending = append(pending, pos)
+		return nil
+	}
+	for len(pending) > 0 {
+		pos := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		if pos == len(fn.ins) {
+			continue
+		}
+		def, _ := my_code.Lookup(fn.ins[pos])
+		operands, bytesRead := my_code.ReadOperands(def, fn.ins[pos+1:])
+		op := my_code.Opcode(fn.ins[pos])
+		pops, pushes := my_code.StackEffect(op, operands)
+		depth := depths[pos]
+		if pops > depth {
+			return v.errorf(fn, pos, "stack underflow: %s pops %d values from %d", def.Name, pops, depth)
+		}
+		depth += pushes - pops
+		if fn.numLocals+depth > StackSize {
+			return v.errorf(fn, pos, "stack overflow")
+		}
+		if my_code.IsJump(op) {
+			targetDepth := depth
+			if op == my_code.OpSetupTry {
+				// the handler starts with the exception pushed
+				targetDepth++
+			}
+			if err := visit(pos, operands[0], targetDepth); err != nil {
+				return err
+			}
+		}
+		if !my_code.IsTerminator(op) {
+			if err := visit(pos, pos+1+bytesRead, depth); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (v *verifier) errorf(fn *functionInfo, pos int, format string, a ...any) error {
+	return fmt.Errorf("%w: %s: %04d: %s", ErrInvalidByteCode, fn.name, pos, fmt.Sprintf(format, a...))
+}
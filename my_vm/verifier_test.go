@@ -0,0 +1,159 @@
+package my_vm
+
+import (
+	"monkey/my_code"
+	"monkey/my_compiler"
+	"monkey/my_object"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifyCompiledByteCode(t *testing.T) {
+	inputs := []string{
+		"1 + 2",
+		"let a = [1, 2, 3]; a[0:2]",
+		"let f = fn(x) { let g = fn(y) { x + y }; g }; f(1)(2)",
+		"let a = 0; while(a < 10) { if (a == 5) { break; }; a = a + 1 }; a",
+		"let fib = fn(n) { if (n < 2) { return n; }; fib(n - 1) + fib(n - 2) }; fib(5)",
+		`len("abc")`,
+		"let f = fn(x) { try { 1 / x } catch (e) { throw e } finally { return 0 } }; try { f(0) } catch { 1 }",
+		"let a = 1; [a < 2 && a > 0 || false, a == 1 || a]",
+		"let a = 7; [a % 3, a // 2, 2 ** a, a & 1 | a ^ 2, ~a << 1 >> 1]",
+		"let a = [1, 2]; let f = fn(i) { a[i] += 1; i++; i }; a[0] -= f(0)",
+	}
+	for _, input := range inputs {
+		comp := my_compiler.New()
+		assert.NoError(t, comp.Compile(parse(input)))
+		assert.NoError(t, Verify(comp.ByteCode()), "input=%s", input)
+	}
+}
+
+func TestVerifyInvalidByteCode(t *testing.T) {
+	fn := func(numLocals int, ins ...[]byte) *my_object.CompiledFunction {
+		return &my_object.CompiledFunction{Instructions: concat(ins...), NumLocals: numLocals}
+	}
+	tests := []struct {
+		byteCode *my_compiler.ByteCode
+		expected string
+	}{
+		{
+			&my_compiler.ByteCode{Instructions: my_code.Instructions{255}},
+			"invalid bytecode: main: 0000: opcode 255 undefined",
+		},
+		{
+			&my_compiler.ByteCode{Instructions: my_code.Instructions{byte(my_code.OpConstant), 0}},
+			"invalid bytecode: main: 0000: operands of OpConstant truncated",
+		},
+		{
+			&my_compiler.ByteCode{Instructions: my_code.Make(my_code.OpConstant, 1)},
+			"invalid bytecode: main: 0000: constant index 1 out of range",
+		},
+		{
+			&my_compiler.ByteCode{
+				Instructions: concat(my_code.Make(my_code.OpClosure, 0, 0), my_code.Make(my_code.OpPop)),
+				Constants:    []my_object.Object{&my_object.Integer{Value: 1}},
+			},
+			"invalid bytecode: main: 0000: constant 0 is not a function",
+		},
+		{
+			&my_compiler.ByteCode{
+				Instructions: concat(my_code.Make(my_code.OpImport, 0, 0), my_code.Make(my_code.OpPop)),
+				Constants:    []my_object.Object{&my_object.CompiledFunction{NumParameters: 1}},
+			},
+			"invalid bytecode: main: 0000: constant 0 is not a module",
+		},
+		{
+			&my_compiler.ByteCode{Instructions: concat(my_code.Make(my_code.OpGetLocal, 0), my_code.Make(my_code.OpPop))},
+			"invalid bytecode: main: 0000: local index 0 out of range",
+		},
+		{
+			&my_compiler.ByteCode{Instructions: concat(my_code.Make(my_code.OpGetBuiltin, 255), my_code.Make(my_code.OpPop))},
+			"invalid bytecode: main: 0000: builtin index 255 out of range",
+		},
+		{
+			&my_compiler.ByteCode{Instructions: my_code.Make(my_code.OpReturn)},
+			"invalid bytecode: main: 0000: OpReturn outside function",
+		},
+		{
+			&my_compiler.ByteCode{Instructions: concat(my_code.Make(my_code.OpTrue), my_code.Make(my_code.OpJump, 2))},
+			"invalid bytecode: main: 0001: jump target 0002 is not an instruction",
+		},
+		{
+			&my_compiler.ByteCode{Instructions: my_code.Make(my_code.OpPop)},
+			"invalid bytecode: main: 0000: stack underflow: OpPop pops 1 values from 0",
+		},
+		{
+			// 0000 OpTrue
+			// 0001 OpJumpNotTruthy 7
+			// 0004 OpTrue
+			// 0005 OpTrue
+			// 0006 OpPop
+			// 0007 OpPop
+			&my_compiler.ByteCode{Instructions: concat(
+				my_code.Make(my_code.OpTrue),
+				my_code.Make(my_code.OpJumpNotTruthy, 7),
+				my_code.Make(my_code.OpTrue),
+				my_code.Make(my_code.OpTrue),
+				my_code.Make(my_code.OpPop),
+				my_code.Make(my_code.OpPop),
+			)},
+			"invalid bytecode: main: 0007: inconsistent stack depth: 0 and 1",
+		},
+		{
+			// the handler starts with the exception pushed
+			// 0000 OpSetupTry 4
+			// 0003 OpPopTry
+			// 0004 OpPop
+			&my_compiler.ByteCode{Instructions: concat(
+				my_code.Make(my_code.OpSetupTry, 4),
+				my_code.Make(my_code.OpPopTry),
+				my_code.Make(my_code.OpPop),
+			)},
+			"invalid bytecode: main: 0004: inconsistent stack depth: 1 and 0",
+		},
+		{
+			&my_compiler.ByteCode{
+				Instructions: concat(my_code.Make(my_code.OpClosure, 0, 0), my_code.Make(my_code.OpPop)),
+				Constants:    []my_object.Object{fn(0, my_code.Make(my_code.OpTrue))},
+			},
+			"invalid bytecode: fn#0: 0000: function ends without return",
+		},
+		{
+			&my_compiler.ByteCode{
+				Instructions: concat(my_code.Make(my_code.OpClosure, 0, 0), my_code.Make(my_code.OpPop)),
+				Constants:    []my_object.Object{fn(1, my_code.Make(my_code.OpGetFree, 0), my_code.Make(my_code.OpReturnValue))},
+			},
+			"invalid bytecode: fn#0: 0000: free variable index 0 out of range",
+		},
+		{
+			&my_compiler.ByteCode{
+				Instructions: concat(
+					my_code.Make(my_code.OpClosure, 0, 0),
+					my_code.Make(my_code.OpTrue),
+					my_code.Make(my_code.OpClosure, 0, 1),
+					my_code.Make(my_code.OpPop),
+					my_code.Make(my_code.OpPop),
+				),
+				Constants: []my_object.Object{fn(0, my_code.Make(my_code.OpReturn))},
+			},
+			"invalid bytecode: main: 0005: fn#0 captures 1 free variables, but 0 elsewhere",
+		},
+	}
+	for _, tt := range tests {
+		err := Verify(tt.byteCode)
+		if assert.ErrorIs(t, err, ErrInvalidByteCode) {
+			assert.Equal(t, tt.expected, err.Error())
+		}
+		vm := New(tt.byteCode)
+		assert.ErrorIs(t, vm.Run(), ErrInvalidByteCode)
+	}
+}
+
+func concat(ins ...[]byte) my_code.Instructions {
+	out := my_code.Instructions{}
+	for _, i := range ins {
+		out = append(out, i...)
+	}
+	return out
+}
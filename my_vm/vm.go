@@ -1,6 +1,8 @@
 package my_vm
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"math"
 	"monkey/my_code"
@@ -11,34 +13,58 @@ import (
 const (
 	StackSize  = 2048
 	GlobalSize = math.MaxUint16 + 1
+	MaxFrames  = 1024
 )
 
 type VM struct {
-	stack        []my_object.Object
-	sp           int // sp: points to the next value, top of stack is stack[sp-1]
-	instructions my_code.Instructions
-	constants    []my_object.Object
-	globals      []my_object.Object
+	stack     []my_object.Object
+	sp        int // sp: points to the next value, top of stack is stack[sp-1]
+	constants []my_object.Object
+	globals   []my_object.Object
+
+	frames      []*Frame
+	framesIndex int // framesIndex: points to the next frame, current frame is frames[framesIndex-1]
+
+	handlers []handler // handlers: exception handlers of try blocks being executed, the innermost being the last
+
+	verified bool // verified: bytecode is verified once before the first run
+
+	meter *my_object.Meter // meter: nil if unlimited
 }
 
 func New(byteCode *my_compiler.ByteCode) *VM {
-	return &VM{
-		sp:           0,
-		stack:        make([]my_object.Object, StackSize),
-		instructions: byteCode.Instructions,
-		constants:    byteCode.Constants,
-		globals:      NewGlobals(),
-	}
+	return NewWithState(byteCode, NewGlobals())
 }
 
 func NewWithState(byteCode *my_compiler.ByteCode, globals []my_object.Object) *VM {
+	mainFn := &my_object.CompiledFunction{Instructions: byteCode.Instructions}
+	if byteCode.Debug != nil {
+		mainFn.Lines = byteCode.Debug.Lines
+	}
+	mainClosure := &my_object.Closure{Fn: mainFn}
+	frames := make([]*Frame, MaxFrames)
+	frames[0] = NewFrame(mainClosure, 0)
 	return &VM{
-		sp:           0,
-		stack:        make([]my_object.Object, StackSize),
-		instructions: byteCode.Instructions,
-		constants:    byteCode.Constants,
-		globals:      globals,
+		sp:          0,
+		stack:       make([]my_object.Object, StackSize),
+		constants:   byteCode.Constants,
+		globals:     globals,
+		frames:      frames,
+		framesIndex: 1,
+	}
+}
+
+// SetLimits: enforce limits on the following run, the timeout counts from now on
+func (vm *VM) SetLimits(limits my_object.Limits) {
+	vm.meter = my_object.NewMeter(limits)
+}
+
+// SetContext: stop the following run once ctx is done
+func (vm *VM) SetContext(ctx context.Context) {
+	if vm.meter == nil {
+		vm.meter = my_object.NewMeter(my_object.Limits{})
 	}
+	vm.meter.SetContext(ctx)
 }
 
 func NewGlobals() []my_object.Object {
@@ -46,26 +72,82 @@ func NewGlobals() []my_object.Object {
 }
 
 func (vm *VM) Run() error {
+	if !vm.verified {
+		err := Verify(&my_compiler.ByteCode{
+			Instructions: vm.frames[0].Instructions(),
+			Constants:    vm.constants,
+		})
+		if err != nil {
+			return err
+		}
+		vm.verified = true
+	}
+	for {
+		err := vm.run()
+		if err == nil {
+			return nil
+		}
+		if !vm.handle(err) {
+			return vm.runtimeError(err)
+		}
+	}
+}
+
+func (vm *VM) run() error {
+	var ip int
+	var ins my_code.Instructions
 	// fetch-decode-execute
-	for ip := 0; ip < len(vm.instructions); ip++ {
-		op := my_code.Opcode(vm.instructions[ip])
+	for vm.currentFrame().ip < len(vm.currentFrame().Instructions())-1 {
+		if err := vm.meter.Step(); err != nil {
+			return err
+		}
+		vm.currentFrame().ip++
+		ip = vm.currentFrame().ip
+		ins = vm.currentFrame().Instructions()
+		op := my_code.Opcode(ins[ip])
 		switch op {
 		// variable-related
 		case my_code.OpConstant:
-			constIndex := my_code.ReadUint16(vm.instructions[ip+1:])
-			ip += 2
+			constIndex := my_code.ReadUint16(ins[ip+1:])
+			vm.currentFrame().ip += 2
 			err := vm.push(vm.constants[constIndex])
 			if err != nil {
 				return err
 			}
 		case my_code.OpSetGlobal:
-			globalIdx := my_code.ReadUint16(vm.instructions[ip+1:])
-			ip += 2
+			globalIdx := my_code.ReadUint16(ins[ip+1:])
+			vm.currentFrame().ip += 2
 			vm.globals[globalIdx] = vm.pop()
 		case my_code.OpGetGlobal:
-			globalIdx := my_code.ReadUint16(vm.instructions[ip+1:])
-			ip += 2
-			err := vm.push(vm.globals[globalIdx])
+			globalIdx := my_code.ReadUint16(ins[ip+1:])
+			vm.currentFrame().ip += 2
+			global := vm.globals[globalIdx]
+			if global == nil {
+				return fmt.Errorf("undefined global slot %d", globalIdx)
+			}
+			err := vm.push(global)
+			if err != nil {
+				return err
+			}
+		case my_code.OpSetLocal:
+			localIdx := my_code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip += 1
+			vm.setLocal(int(localIdx), vm.pop())
+		case my_code.OpGetLocal:
+			localIdx := my_code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip += 1
+			local := vm.getLocal(int(localIdx))
+			if local == nil {
+				return fmt.Errorf("undefined local slot %d", localIdx)
+			}
+			err := vm.push(local)
+			if err != nil {
+				return err
+			}
+		case my_code.OpCaptureLocal:
+			localIdx := my_code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip += 1
+			err := vm.push(vm.localCell(int(localIdx)))
 			if err != nil {
 				return err
 			}
@@ -78,7 +160,14 @@ func (vm *VM) Run() error {
 			if err != nil {
 				return err
 			}
-		case my_code.OpAdd, my_code.OpSub, my_code.OpDiv, my_code.OpMul:
+		case my_code.OpBitNot:
+			err := vm.executeOpBitNot()
+			if err != nil {
+				return err
+			}
+		case my_code.OpAdd, my_code.OpSub, my_code.OpDiv, my_code.OpMul,
+			my_code.OpMod, my_code.OpPow, my_code.OpFloorDiv,
+			my_code.OpBitAnd, my_code.OpBitOr, my_code.OpBitXor, my_code.OpShiftLeft, my_code.OpShiftRight:
 			err := vm.executeBinaryOperation(op)
 			if err != nil {
 				return err
@@ -90,15 +179,153 @@ func (vm *VM) Run() error {
 			}
 		// functional
 		case my_code.OpJump:
-			jumpToPos := my_code.ReadUint16(vm.instructions[ip+1:])
-			ip = int(jumpToPos) - 1 // ip has ++ after each loop
+			jumpToPos := my_code.ReadUint16(ins[ip+1:])
+			vm.currentFrame().ip = int(jumpToPos) - 1 // ip has ++ after each loop
 		case my_code.OpJumpNotTruthy:
 			// if condition is true
 			if !isTruthy(vm.pop()) {
-				jumpToPos := my_code.ReadUint16(vm.instructions[ip+1:])
-				ip = int(jumpToPos) - 1
+				jumpToPos := my_code.ReadUint16(ins[ip+1:])
+				vm.currentFrame().ip = int(jumpToPos) - 1
 			} else {
-				ip += 2 // ip has ++ after each loop, so +2 jumps over the OpJumpNotTruthy
+				vm.currentFrame().ip += 2 // ip has ++ after each loop, so +2 jumps over the OpJumpNotTruthy
+			}
+		case my_code.OpCall:
+			numArgs := my_code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip += 1
+			err := vm.executeCall(int(numArgs))
+			if err != nil {
+				return err
+			}
+		case my_code.OpClosure:
+			constIndex := my_code.ReadUint16(ins[ip+1:])
+			numFree := my_code.ReadUint8(ins[ip+3:])
+			vm.currentFrame().ip += 3
+			err := vm.pushClosure(int(constIndex), int(numFree))
+			if err != nil {
+				return err
+			}
+		case my_code.OpGetFree:
+			freeIdx := my_code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip += 1
+			free := deref(vm.currentFrame().cl.Free[freeIdx])
+			if free == nil {
+				return fmt.Errorf("undefined free slot %d", freeIdx)
+			}
+			err := vm.push(free)
+			if err != nil {
+				return err
+			}
+		case my_code.OpSetFree:
+			freeIdx := my_code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip += 1
+			setThrough(&vm.currentFrame().cl.Free[freeIdx], vm.pop())
+		case my_code.OpCaptureFree:
+			freeIdx := my_code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip += 1
+			err := vm.push(vm.currentFrame().cl.Free[freeIdx])
+			if err != nil {
+				return err
+			}
+		case my_code.OpGetBuiltin:
+			builtinIdx := my_code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip += 1
+			err := vm.push(my_object.Builtins[builtinIdx].Builtin)
+			if err != nil {
+				return err
+			}
+		case my_code.OpCurrentClosure:
+			err := vm.push(vm.currentFrame().cl)
+			if err != nil {
+				return err
+			}
+		case my_code.OpImport:
+			globalIdx := my_code.ReadUint16(ins[ip+1:])
+			constIndex := my_code.ReadUint16(ins[ip+3:])
+			vm.currentFrame().ip += 4
+			err := vm.importModule(int(globalIdx), int(constIndex))
+			if err != nil {
+				return err
+			}
+		case my_code.OpReturnValue:
+			returnValue := vm.pop()
+			// returning from main frame terminates the program
+			if vm.framesIndex == 1 {
+				return nil
+			}
+			frame := vm.popFrame()
+			vm.meter.Leave()
+			vm.dropHandlers()
+			vm.sp = frame.basePointer - 1 // -1 to pop the called function as well
+			err := vm.push(returnValue)
+			if err != nil {
+				return err
+			}
+		case my_code.OpReturn:
+			frame := vm.popFrame()
+			vm.meter.Leave()
+			vm.dropHandlers()
+			vm.sp = frame.basePointer - 1
+			err := vm.push(NULL)
+			if err != nil {
+				return err
+			}
+		// exceptions
+		case my_code.OpSetupTry:
+			handlerPos := my_code.ReadUint16(ins[ip+1:])
+			vm.currentFrame().ip += 2
+			vm.setupTry(int(handlerPos))
+		case my_code.OpPopTry:
+			err := vm.popTry()
+			if err != nil {
+				return err
+			}
+		case my_code.OpThrow:
+			return &exception{value: vm.pop()}
+		// data structures
+		case my_code.OpArray:
+			numElements := int(my_code.ReadUint16(ins[ip+1:]))
+			vm.currentFrame().ip += 2
+			array := vm.buildArray(vm.sp-numElements, vm.sp)
+			vm.sp -= numElements
+			err := vm.push(array)
+			if err != nil {
+				return err
+			}
+		case my_code.OpHash:
+			numElements := int(my_code.ReadUint16(ins[ip+1:]))
+			vm.currentFrame().ip += 2
+			hash, err := vm.buildHash(vm.sp-numElements, vm.sp)
+			if err != nil {
+				return err
+			}
+			vm.sp -= numElements
+			err = vm.push(hash)
+			if err != nil {
+				return err
+			}
+		case my_code.OpIndex:
+			err := vm.executeIndexExpression()
+			if err != nil {
+				return err
+			}
+		case my_code.OpDupPair:
+			pair := vm.stack[vm.sp-2 : vm.sp]
+			for _, obj := range []my_object.Object{pair[0], pair[1]} {
+				if err := vm.push(obj); err != nil {
+					return err
+				}
+			}
+		case my_code.OpSetIndex:
+			err := vm.executeSetIndex()
+			if err != nil {
+				return err
+			}
+		case my_code.OpSlice:
+			flags := my_code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip += 1
+			err := vm.executeSliceExpression(int(flags))
+			if err != nil {
+				return err
 			}
 		case my_code.OpPop:
 			vm.pop()
@@ -119,10 +346,38 @@ func (vm *VM) Run() error {
 				return err
 			}
 		}
+		if vm.meter != nil && allocatingOps[op] {
+			if err := vm.meter.Alloc(vm.StackTop()); err != nil {
+				return err
+			}
+		}
 	}
 	return nil
 }
 
+// allocatingOps: instructions pushing newly allocated objects, counted by the meter;
+// results of builtin functions are counted in callBuiltin
+var allocatingOps = map[my_code.Opcode]bool{
+	my_code.OpAdd:        true,
+	my_code.OpSub:        true,
+	my_code.OpMul:        true,
+	my_code.OpDiv:        true,
+	my_code.OpMinus:      true,
+	my_code.OpMod:        true,
+	my_code.OpPow:        true,
+	my_code.OpFloorDiv:   true,
+	my_code.OpBitAnd:     true,
+	my_code.OpBitOr:      true,
+	my_code.OpBitXor:     true,
+	my_code.OpShiftLeft:  true,
+	my_code.OpShiftRight: true,
+	my_code.OpBitNot:     true,
+	my_code.OpArray:      true,
+	my_code.OpHash:       true,
+	my_code.OpClosure:    true,
+	my_code.OpSlice:      true,
+}
+
 func (vm *VM) StackTop() my_object.Object {
 	if vm.sp == 0 {
 		return NULL
@@ -154,3 +409,102 @@ func (vm *VM) pop() my_object.Object {
 	vm.sp--
 	return obj
 }
+
+func (vm *VM) currentFrame() *Frame {
+	return vm.frames[vm.framesIndex-1]
+}
+
+func (vm *VM) pushFrame(f *Frame) error {
+	if vm.framesIndex >= MaxFrames {
+		return fmt.Errorf("stack overflow")
+	}
+	vm.frames[vm.framesIndex] = f
+	vm.framesIndex++
+	return nil
+}
+
+func (vm *VM) popFrame() *Frame {
+	vm.framesIndex--
+	return vm.frames[vm.framesIndex]
+}
+
+// executeCall: the function to call sits below its arguments on the stack
+func (vm *VM) executeCall(numArgs int) error {
+	switch callee := vm.stack[vm.sp-1-numArgs].(type) {
+	case *my_object.Closure:
+		return vm.callClosure(callee, numArgs)
+	case *my_object.Builtin:
+		return vm.callBuiltin(callee, numArgs)
+	default:
+		return fmt.Errorf("not a function: %s", callee.Type())
+	}
+}
+
+func (vm *VM) callBuiltin(builtin *my_object.Builtin, numArgs int) error {
+	args := vm.stack[vm.sp-numArgs : vm.sp]
+	result := builtin.Fn(args...)
+	vm.sp = vm.sp - numArgs - 1
+	if errObj, ok := result.(*my_object.Error); ok {
+		return errors.New(errObj.Message)
+	}
+	if err := vm.meter.Alloc(result); err != nil {
+		return err
+	}
+	if result == nil {
+		return vm.push(NULL)
+	}
+	return vm.push(result)
+}
+
+func (vm *VM) callClosure(cl *my_object.Closure, numArgs int) error {
+	if numArgs != cl.Fn.NumParameters {
+		return fmt.Errorf("wrong number of arguments: want=%d, got=%d", cl.Fn.NumParameters, numArgs)
+	}
+	frame := NewFrame(cl, vm.sp-numArgs)
+	// reserve slots for local bindings on the stack
+	if frame.basePointer+cl.Fn.NumLocals >= StackSize {
+		return fmt.Errorf("stack overflow")
+	}
+	if err := vm.meter.Enter(); err != nil {
+		return err
+	}
+	err := vm.pushFrame(frame)
+	if err != nil {
+		return err
+	}
+	// clear locals left by earlier frames, which may hold cells still shared with their closures
+	for i := frame.basePointer + numArgs; i < frame.basePointer+cl.Fn.NumLocals; i++ {
+		vm.stack[i] = nil
+	}
+	vm.sp = frame.basePointer + cl.Fn.NumLocals
+	return nil
+}
+
+// importModule: push the namespace of a module imported before,
+// otherwise call the function running the module, which returns its namespace
+func (vm *VM) importModule(globalIdx, constIdx int) error {
+	if namespace := vm.globals[globalIdx]; namespace != nil {
+		return vm.push(namespace)
+	}
+	fn, ok := vm.constants[constIdx].(*my_object.CompiledFunction)
+	if !ok {
+		return fmt.Errorf("not a function: %s", vm.constants[constIdx].Type())
+	}
+	module := &my_object.Closure{Fn: fn}
+	if err := vm.push(module); err != nil {
+		return err
+	}
+	return vm.callClosure(module, 0)
+}
+
+// pushClosure: collect free variables sitting on top of the stack into a new closure
+func (vm *VM) pushClosure(constIndex, numFree int) error {
+	fn, ok := vm.constants[constIndex].(*my_object.CompiledFunction)
+	if !ok {
+		return fmt.Errorf("not a function: %s", vm.constants[constIndex].Type())
+	}
+	free := make([]my_object.Object, numFree)
+	copy(free, vm.stack[vm.sp-numFree:vm.sp])
+	vm.sp -= numFree
+	return vm.push(&my_object.Closure{Fn: fn, Free: free})
+}
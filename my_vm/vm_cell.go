@@ -0,0 +1,43 @@
+package my_vm
+
+import "monkey/my_object"
+
+// Locals captured by closures move into cells shared by the frame and the closures,
+// so that assigning to them in any of these is seen by the others, like environments of my_evaluator.
+// Cells are created once a closure captures the local, other locals hold their values directly.
+
+func (vm *VM) getLocal(idx int) my_object.Object {
+	return deref(vm.stack[vm.currentFrame().basePointer+idx])
+}
+
+func (vm *VM) setLocal(idx int, value my_object.Object) {
+	setThrough(&vm.stack[vm.currentFrame().basePointer+idx], value)
+}
+
+// localCell: the cell of a local, moving its value into a new cell if not captured before
+func (vm *VM) localCell(idx int) *my_object.Cell {
+	slot := &vm.stack[vm.currentFrame().basePointer+idx]
+	if cell, ok := (*slot).(*my_object.Cell); ok {
+		return cell
+	}
+	cell := &my_object.Cell{Value: *slot}
+	*slot = cell
+	return cell
+}
+
+// deref: the value of a binding, read through its cell if captured
+func deref(obj my_object.Object) my_object.Object {
+	if cell, ok := obj.(*my_object.Cell); ok {
+		return cell.Value
+	}
+	return obj
+}
+
+// setThrough: assign to a binding, through its cell if captured
+func setThrough(slot *my_object.Object, value my_object.Object) {
+	if cell, ok := (*slot).(*my_object.Cell); ok {
+		cell.Value = value
+		return
+	}
+	*slot = value
+}
@@ -0,0 +1,198 @@
+package my_vm
+
+import (
+	"fmt"
+	"monkey/my_code"
+	"monkey/my_object"
+	"strings"
+)
+
+// below are almost the same as my_evaluator/eval_index.go and my_evaluator/eval_hash.go
+
+func (vm *VM) buildArray(startIdx, endIdx int) my_object.Object {
+	elements := make([]my_object.Object, endIdx-startIdx)
+	copy(elements, vm.stack[startIdx:endIdx])
+	return &my_object.Array{Elements: elements}
+}
+
+func (vm *VM) buildHash(startIdx, endIdx int) (my_object.Object, error) {
+	pairs := make(map[my_object.HashKey]my_object.HashPair)
+	for i := startIdx; i < endIdx; i += 2 {
+		key := vm.stack[i]
+		value := vm.stack[i+1]
+		hashableKey, ok := key.(my_object.HashableObject)
+		if !ok {
+			return nil, fmt.Errorf("key type not hashable: %s", key.Type())
+		}
+		pairs[hashableKey.HashKey()] = my_object.HashPair{Key: key, Value: value}
+	}
+	return &my_object.Hash{Pairs: pairs}, nil
+}
+
+// sliceIndices: values popped for OpSlice, nil if not specified
+type sliceIndices struct {
+	start  my_object.Object
+	end    my_object.Object
+	stride my_object.Object
+	// isSet: false only for empty indexing like a[]
+	isSet   bool
+	isRange bool
+}
+
+func (vm *VM) executeIndexExpression() error {
+	index := vm.pop()
+	left := vm.pop()
+	return vm.executeSlice(left, &sliceIndices{start: index, isSet: true})
+}
+
+func (vm *VM) executeSetIndex() error {
+	value := vm.pop()
+	index := vm.pop()
+	container := vm.pop()
+	if err := my_object.SetElement(container, index, value); err != nil {
+		return err
+	}
+	return vm.push(value)
+}
+
+func (vm *VM) executeSliceExpression(flags int) error {
+	indices := &sliceIndices{
+		isSet:   flags&(my_code.SliceHasStart|my_code.SliceIsRange) != 0,
+		isRange: flags&my_code.SliceIsRange != 0,
+	}
+	if flags&my_code.SliceHasStride != 0 {
+		indices.stride = vm.pop()
+	}
+	if flags&my_code.SliceHasEnd != 0 {
+		indices.end = vm.pop()
+	}
+	if flags&my_code.SliceHasStart != 0 {
+		indices.start = vm.pop()
+	}
+	left := vm.pop()
+	return vm.executeSlice(left, indices)
+}
+
+func (vm *VM) executeSlice(left my_object.Object, indices *sliceIndices) error {
+	switch left := left.(type) {
+	case *my_object.String:
+		elements := []my_object.Object{}
+		for _, char := range left.Value {
+			elements = append(elements, &my_object.String{Value: string(char)})
+		}
+		sliced, err := sliceArray(&my_object.Array{Elements: elements}, indices)
+		if err != nil {
+			return err
+		}
+		if sliced, ok := sliced.(*my_object.String); ok {
+			return vm.push(sliced)
+		}
+		sb := &strings.Builder{}
+		for _, el := range sliced.(*my_object.Array).Elements {
+			sb.WriteString(el.(*my_object.String).Value)
+		}
+		return vm.push(&my_object.String{Value: sb.String()})
+	case *my_object.Array:
+		sliced, err := sliceArray(left, indices)
+		if err != nil {
+			return err
+		}
+		return vm.push(sliced)
+	case *my_object.Hash:
+		if indices.start == nil {
+			return fmt.Errorf("hash indexing with empty expression")
+		}
+		key, ok := indices.start.(my_object.HashableObject)
+		if !ok {
+			return fmt.Errorf("key type not hashable: %s", indices.start.Type())
+		}
+		pair, ok := left.Pairs[key.HashKey()]
+		if !ok {
+			return vm.push(NULL)
+		}
+		return vm.push(pair.Value)
+	default:
+		return fmt.Errorf("index operator not supported: %s", left.Type())
+	}
+}
+
+func sliceArray(array *my_object.Array, indices *sliceIndices) (my_object.Object, error) {
+	length := int64(len(array.Elements))
+	if !indices.isSet {
+		return nil, fmt.Errorf("array-like indexing with empty expression")
+	}
+	// shortcut: a single index, counting from the end if negative
+	if !indices.isRange {
+		idx, err := my_object.ElementIndex(indices.start, len(array.Elements))
+		if err != nil {
+			return nil, err
+		}
+		return array.Elements[idx], nil
+	}
+	// parse start index
+	startIdx := int64(0)
+	if indices.start != nil {
+		start, ok := indices.start.(*my_object.Integer)
+		if !ok {
+			return nil, fmt.Errorf("array-like indexing expecting INT, but got %s", indices.start.Type())
+		}
+		startIdx = start.Value
+	}
+	if startIdx >= length {
+		return nil, fmt.Errorf("index %d out of array with length %d", startIdx, length)
+	}
+	if startIdx < 0 {
+		if -startIdx > length {
+			return nil, fmt.Errorf("index %d out of array with length %d", startIdx, length)
+		}
+		startIdx = length + startIdx
+	}
+	// parse end index
+	endIdx := length
+	if indices.end != nil {
+		end, ok := indices.end.(*my_object.Integer)
+		if !ok {
+			return nil, fmt.Errorf("array-like indexing expecting INT, but got %s", indices.end.Type())
+		}
+		endIdx = end.Value
+	}
+	if endIdx >= length {
+		endIdx = length
+	}
+	if endIdx < 0 {
+		// return empty if end index out of boundary without error
+		if (-endIdx) >= length {
+			return &my_object.Array{Elements: []my_object.Object{}}, nil
+		}
+		endIdx = length + endIdx
+	}
+	// parse stride
+	stride := int64(1)
+	if indices.stride != nil {
+		strideObj, ok := indices.stride.(*my_object.Integer)
+		if !ok {
+			return nil, fmt.Errorf("array-like indexing expecting INT, but got %s", indices.stride.Type())
+		}
+		stride = strideObj.Value
+	}
+	if stride == 0 {
+		return nil, fmt.Errorf("array-like indexing expecting non-zero stride")
+	}
+	results := &my_object.Array{Elements: []my_object.Object{}}
+	if stride > 0 {
+		for idx := startIdx; idx < endIdx; idx += stride {
+			results.Elements = append(results.Elements, array.Elements[idx])
+		}
+		return results, nil
+	}
+	if indices.start == nil {
+		startIdx = length - 1
+	}
+	if indices.end == nil {
+		endIdx = -1
+	}
+	for idx := startIdx; idx > endIdx; idx += stride {
+		results.Elements = append(results.Elements, array.Elements[idx])
+	}
+	return results, nil
+}
@@ -2,12 +2,34 @@ package my_vm
 
 import (
 	"fmt"
+	"math"
 	"monkey/my_code"
 	"monkey/my_object"
 
 	"golang.org/x/exp/constraints"
 )
 
+// opOperators: operators of the binary opcodes as written in code, for error messages like my_evaluator's;
+// a < b and a <= b compile to b > a and b >= a
+var opOperators = map[my_code.Opcode]string{
+	my_code.OpAdd:        "+",
+	my_code.OpSub:        "-",
+	my_code.OpMul:        "*",
+	my_code.OpDiv:        "/",
+	my_code.OpMod:        "%",
+	my_code.OpPow:        "**",
+	my_code.OpFloorDiv:   "//",
+	my_code.OpBitAnd:     "&",
+	my_code.OpBitOr:      "|",
+	my_code.OpBitXor:     "^",
+	my_code.OpShiftLeft:  "<<",
+	my_code.OpShiftRight: ">>",
+	my_code.OpGT:         ">",
+	my_code.OpGTE:        ">=",
+	my_code.OpEqual:      "==",
+	my_code.OpNotEqual:   "!=",
+}
+
 type arithmeticFuncs struct {
 	intFunc   func(a, b int64) int64
 	floatFunc func(a, b float64) float64
@@ -22,6 +44,12 @@ func sub[T numberConstraint](a, b T) T { return a - b }
 func mul[T numberConstraint](a, b T) T { return a * b }
 func div[T numberConstraint](a, b T) T { return a / b }
 
+func bitAnd(a, b int64) int64     { return a & b }
+func bitOr(a, b int64) int64      { return a | b }
+func bitXor(a, b int64) int64     { return a ^ b }
+func shiftLeft(a, b int64) int64  { return a << b }
+func shiftRight(a, b int64) int64 { return a >> b }
+
 var opToArithFuncs = map[my_code.Opcode]arithmeticFuncs{
 	my_code.OpAdd: {
 		intFunc:   add[int64],
@@ -39,12 +67,77 @@ var opToArithFuncs = map[my_code.Opcode]arithmeticFuncs{
 		intFunc:   mul[int64],
 		floatFunc: mul[float64],
 	},
+	my_code.OpMod: {
+		intFunc:   my_object.FloorMod,
+		floatFunc: my_object.FloatFloorMod,
+	},
+	my_code.OpPow: {
+		intFunc:   my_object.IntPow,
+		floatFunc: math.Pow,
+	},
+	my_code.OpFloorDiv: {
+		intFunc:   my_object.FloorDiv,
+		floatFunc: my_object.FloatFloorDiv,
+	},
+	// bitwise operators have no floatFunc: floats are not converted to integers implicitly
+	my_code.OpBitAnd:     {intFunc: bitAnd},
+	my_code.OpBitOr:      {intFunc: bitOr},
+	my_code.OpBitXor:     {intFunc: bitXor},
+	my_code.OpShiftLeft:  {intFunc: shiftLeft},
+	my_code.OpShiftRight: {intFunc: shiftRight},
+}
+
+// checkIntegerOperands: errors of op on integers the same as my_evaluator/eval_infix.go,
+// like dividing by zero or negative exponents, booleans counting as integers;
+// floats divide by zero to infinities instead
+func checkIntegerOperands(op my_code.Opcode, left, right my_object.Object) error {
+	switch left.(type) {
+	case *my_object.Integer, *my_object.Boolean:
+	default:
+		return nil
+	}
+	var value int64
+	switch right := right.(type) {
+	case *my_object.Integer:
+		value = right.Value
+	case *my_object.Boolean:
+		value = booleanToInt(right.Value)
+	default:
+		return nil
+	}
+	switch op {
+	case my_code.OpDiv, my_code.OpMod, my_code.OpFloorDiv:
+		if value == 0 {
+			return fmt.Errorf("division by zero")
+		}
+	case my_code.OpPow:
+		if value < 0 {
+			return fmt.Errorf("negative exponent: %d", value)
+		}
+	case my_code.OpShiftLeft, my_code.OpShiftRight:
+		if value < 0 {
+			return fmt.Errorf("negative shift count: %d", value)
+		}
+	}
+	return nil
+}
+
+func isFloat(obj my_object.Object) bool {
+	_, ok := obj.(*my_object.Float)
+	return ok
 }
 
 // executeBinaryOperation now accepts arithmetic ops like + - * /
 func (vm *VM) executeBinaryOperation(op my_code.Opcode) error {
 	rightObj := vm.pop()
 	leftObj := vm.pop()
+	if err := checkIntegerOperands(op, leftObj, rightObj); err != nil {
+		return err
+	}
+	if opToArithFuncs[op].floatFunc == nil && (isFloat(leftObj) || isFloat(rightObj)) {
+		// both operands are converted to floats first like in my_evaluator
+		return fmt.Errorf("unknown operator: %s%s%s", my_object.FLOAT_OBJ, opOperators[op], my_object.FLOAT_OBJ)
+	}
 	// below are almost the same as my_evaluator/eval_infix.go
 	switch leftObj := leftObj.(type) {
 	case *my_object.Integer:
@@ -55,10 +148,19 @@ func (vm *VM) executeBinaryOperation(op my_code.Opcode) error {
 			vm.push(&my_object.Integer{Value: opToArithFuncs[op].intFunc(leftObj.Value, booleanToInt(rightObj.Value))})
 		case *my_object.Float:
 			vm.push(&my_object.Float{Value: opToArithFuncs[op].floatFunc(float64(leftObj.Value), rightObj.Value)})
+		case *my_object.String:
+			if op != my_code.OpMul {
+				return fmt.Errorf("unknown operator: %s%s%s", leftObj.Type(), opOperators[op], rightObj.Type())
+			}
+			repeated, err := my_object.RepeatString(rightObj, leftObj, vm.meter)
+			if err != nil {
+				return err
+			}
+			vm.push(repeated)
 		case *my_object.Null:
-			return fmt.Errorf("unknown operator: %s%d%s", leftObj.Type(), op, rightObj.Type())
+			return fmt.Errorf("unknown operator: %s%s%s", leftObj.Type(), opOperators[op], rightObj.Type())
 		default:
-			return fmt.Errorf("unknown operator: %s%d%s", leftObj.Type(), op, rightObj.Type())
+			return fmt.Errorf("unknown operator: %s%s%s", leftObj.Type(), opOperators[op], rightObj.Type())
 		}
 	case *my_object.Boolean:
 		switch rightObj := rightObj.(type) {
@@ -69,9 +171,9 @@ func (vm *VM) executeBinaryOperation(op my_code.Opcode) error {
 		case *my_object.Float:
 			vm.push(&my_object.Float{Value: opToArithFuncs[op].floatFunc(float64(booleanToInt(leftObj.Value)), rightObj.Value)})
 		case *my_object.Null:
-			return fmt.Errorf("unknown operator: %s%d%s", leftObj.Type(), op, rightObj.Type())
+			return fmt.Errorf("unknown operator: %s%s%s", leftObj.Type(), opOperators[op], rightObj.Type())
 		default:
-			return fmt.Errorf("unknown operator: %s%d%s", leftObj.Type(), op, rightObj.Type())
+			return fmt.Errorf("unknown operator: %s%s%s", leftObj.Type(), opOperators[op], rightObj.Type())
 		}
 	case *my_object.Float:
 		switch rightObj := rightObj.(type) {
@@ -82,27 +184,41 @@ func (vm *VM) executeBinaryOperation(op my_code.Opcode) error {
 		case *my_object.Float:
 			vm.push(&my_object.Float{Value: opToArithFuncs[op].floatFunc(leftObj.Value, rightObj.Value)})
 		case *my_object.Null:
-			return fmt.Errorf("unknown operator: %s%d%s", leftObj.Type(), op, rightObj.Type())
+			return fmt.Errorf("unknown operator: %s%s%s", leftObj.Type(), opOperators[op], rightObj.Type())
 		default:
-			return fmt.Errorf("unknown operator: %s%d%s", leftObj.Type(), op, rightObj.Type())
+			return fmt.Errorf("unknown operator: %s%s%s", leftObj.Type(), opOperators[op], rightObj.Type())
 		}
 	case *my_object.String:
-		if rightObj, ok := rightObj.(*my_object.String); ok && op == my_code.OpAdd {
+		switch rightObj := rightObj.(type) {
+		case *my_object.String:
+			if op != my_code.OpAdd {
+				return fmt.Errorf("unknown operator: %s%s%s", leftObj.Type(), opOperators[op], rightObj.Type())
+			}
 			vm.push(&my_object.String{Value: leftObj.Value + rightObj.Value})
-		} else {
-			return fmt.Errorf("unknown operator: %s%d%s", leftObj.Type(), op, rightObj.Type())
+		case *my_object.Integer:
+			if op != my_code.OpMul {
+				return fmt.Errorf("unknown operator: %s%s%s", leftObj.Type(), opOperators[op], rightObj.Type())
+			}
+			repeated, err := my_object.RepeatString(leftObj, rightObj, vm.meter)
+			if err != nil {
+				return err
+			}
+			vm.push(repeated)
+		default:
+			return fmt.Errorf("unknown operator: %s%s%s", leftObj.Type(), opOperators[op], rightObj.Type())
 		}
 	case *my_object.Null:
-		return fmt.Errorf("unknown operator: %s%d%s", leftObj.Type(), op, rightObj.Type())
+		return fmt.Errorf("unknown operator: %s%s%s", leftObj.Type(), opOperators[op], rightObj.Type())
 	default:
-		return fmt.Errorf("unknown operator: %s%d%s", leftObj.Type(), op, rightObj.Type())
+		return fmt.Errorf("unknown operator: %s%s%s", leftObj.Type(), opOperators[op], rightObj.Type())
 	}
 	return nil
 }
 
 type compFuncs struct {
-	intFunc   func(a, b int64) bool
-	floatFunc func(a, b float64) bool
+	intFunc    func(a, b int64) bool
+	floatFunc  func(a, b float64) bool
+	stringFunc func(a, b string) bool
 }
 
 func equal[T comparable](a, b T) bool                     { return a == b }
@@ -111,10 +227,10 @@ func greaterThan[T constraints.Ordered](a, b T) bool      { return a > b }
 func greaterThanEqual[T constraints.Ordered](a, b T) bool { return a >= b }
 
 var opToCompFuncs = map[my_code.Opcode]compFuncs{
-	my_code.OpGT:       {intFunc: greaterThan[int64], floatFunc: greaterThan[float64]},
-	my_code.OpGTE:      {intFunc: greaterThanEqual[int64], floatFunc: greaterThanEqual[float64]},
-	my_code.OpEqual:    {intFunc: equal[int64], floatFunc: equal[float64]},
-	my_code.OpNotEqual: {intFunc: notEqual[int64], floatFunc: notEqual[float64]},
+	my_code.OpGT:       {intFunc: greaterThan[int64], floatFunc: greaterThan[float64], stringFunc: greaterThan[string]},
+	my_code.OpGTE:      {intFunc: greaterThanEqual[int64], floatFunc: greaterThanEqual[float64], stringFunc: greaterThanEqual[string]},
+	my_code.OpEqual:    {intFunc: equal[int64], floatFunc: equal[float64], stringFunc: equal[string]},
+	my_code.OpNotEqual: {intFunc: notEqual[int64], floatFunc: notEqual[float64], stringFunc: notEqual[string]},
 }
 
 func (vm *VM) executeComparison(op my_code.Opcode) error {
@@ -130,9 +246,9 @@ func (vm *VM) executeComparison(op my_code.Opcode) error {
 		case *my_object.Float:
 			vm.push(booleanToConstObj(opToCompFuncs[op].floatFunc(float64(leftObj.Value), rightObj.Value)))
 		case *my_object.Null:
-			return fmt.Errorf("unknown operator: %s%d%s", leftObj.Type(), op, rightObj.Type())
+			return fmt.Errorf("unknown operator: %s%s%s", leftObj.Type(), opOperators[op], rightObj.Type())
 		default:
-			return fmt.Errorf("unknown operator: %s%d%s", leftObj.Type(), op, rightObj.Type())
+			return fmt.Errorf("unknown operator: %s%s%s", leftObj.Type(), opOperators[op], rightObj.Type())
 		}
 	case *my_object.Boolean:
 		switch rightObj := rightObj.(type) {
@@ -143,9 +259,9 @@ func (vm *VM) executeComparison(op my_code.Opcode) error {
 		case *my_object.Float:
 			vm.push(booleanToConstObj(opToCompFuncs[op].floatFunc(float64(booleanToInt(leftObj.Value)), rightObj.Value)))
 		case *my_object.Null:
-			return fmt.Errorf("unknown operator: %s%d%s", leftObj.Type(), op, rightObj.Type())
+			return fmt.Errorf("unknown operator: %s%s%s", leftObj.Type(), opOperators[op], rightObj.Type())
 		default:
-			return fmt.Errorf("unknown operator: %s%d%s", leftObj.Type(), op, rightObj.Type())
+			return fmt.Errorf("unknown operator: %s%s%s", leftObj.Type(), opOperators[op], rightObj.Type())
 		}
 	case *my_object.Float:
 		switch rightObj := rightObj.(type) {
@@ -156,12 +272,17 @@ func (vm *VM) executeComparison(op my_code.Opcode) error {
 		case *my_object.Float:
 			vm.push(booleanToConstObj(opToCompFuncs[op].floatFunc(leftObj.Value, rightObj.Value)))
 		case *my_object.Null:
-			return fmt.Errorf("unknown operator: %s%d%s", leftObj.Type(), op, rightObj.Type())
+			return fmt.Errorf("unknown operator: %s%s%s", leftObj.Type(), opOperators[op], rightObj.Type())
 		default:
-			return fmt.Errorf("unknown operator: %s%d%s", leftObj.Type(), op, rightObj.Type())
+			return fmt.Errorf("unknown operator: %s%s%s", leftObj.Type(), opOperators[op], rightObj.Type())
 		}
 	case *my_object.String:
-		return fmt.Errorf("unknown operator: %s%d%s", leftObj.Type(), op, rightObj.Type())
+		switch rightObj := rightObj.(type) {
+		case *my_object.String:
+			vm.push(booleanToConstObj(opToCompFuncs[op].stringFunc(leftObj.Value, rightObj.Value)))
+		default:
+			return fmt.Errorf("unknown operator: %s%s%s", leftObj.Type(), opOperators[op], rightObj.Type())
+		}
 	case *my_object.Null:
 		switch rightObj := rightObj.(type) {
 		case *my_object.Null:
@@ -171,13 +292,13 @@ func (vm *VM) executeComparison(op my_code.Opcode) error {
 			case my_code.OpGT, my_code.OpNotEqual:
 				vm.push(FALSE)
 			default:
-				return fmt.Errorf("unknown operator: %s%d%s", leftObj.Type(), op, rightObj.Type())
+				return fmt.Errorf("unknown operator: %s%s%s", leftObj.Type(), opOperators[op], rightObj.Type())
 			}
 		default:
-			return fmt.Errorf("unknown operator: %s%d%s", leftObj.Type(), op, rightObj.Type())
+			return fmt.Errorf("unknown operator: %s%s%s", leftObj.Type(), opOperators[op], rightObj.Type())
 		}
 	default:
-		return fmt.Errorf("unknown operator: %s%d%s", leftObj.Type(), op, rightObj.Type())
+		return fmt.Errorf("unknown operator: %s%s%s", leftObj.Type(), opOperators[op], rightObj.Type())
 	}
 	return nil
 }
@@ -20,12 +20,11 @@ func (vm *VM) executeOpBang() {
 
 func (vm *VM) executeOpMinus() error {
 	switch obj := vm.pop().(type) {
+	// NOTE: push new objects since operands may come from the constant pool
 	case *my_object.Float:
-		obj.Value = -obj.Value
-		vm.push(obj)
+		vm.push(&my_object.Float{Value: -obj.Value})
 	case *my_object.Integer:
-		obj.Value = -obj.Value
-		vm.push(obj)
+		vm.push(&my_object.Integer{Value: -obj.Value})
 	case *my_object.Boolean:
 		if obj == TRUE {
 			vm.push(FALSE)
@@ -37,3 +36,16 @@ func (vm *VM) executeOpMinus() error {
 	}
 	return nil
 }
+
+// executeOpBitNot: same as my_evaluator/eval_prefix.go, booleans taken as 0 or 1
+func (vm *VM) executeOpBitNot() error {
+	switch obj := vm.pop().(type) {
+	case *my_object.Integer:
+		vm.push(&my_object.Integer{Value: ^obj.Value})
+	case *my_object.Boolean:
+		vm.push(&my_object.Integer{Value: ^booleanToInt(obj.Value)})
+	default:
+		return fmt.Errorf("unknown operator: ~%s", obj.Type())
+	}
+	return nil
+}
@@ -1,9 +1,12 @@
 package my_vm
 
 import (
+	"bytes"
 	"fmt"
 	"monkey/my_ast"
+	"monkey/my_code"
 	"monkey/my_compiler"
+	"monkey/my_evaluator"
 	"monkey/my_lexer"
 	"monkey/my_object"
 	"monkey/my_parser"
@@ -57,6 +60,47 @@ func TestBooleanExpressions(t *testing.T) {
 	runVMTests(t, tests)
 }
 
+func TestLogicalExpressions(t *testing.T) {
+	tests := []*vmTestCase{
+		{"true && 1", true},
+		{"1 && null", false},
+		{"false || 0", true},
+		{"null || false", false},
+		{"1 < 2 && 2 < 3 || false", true},
+		{"let a = 0; false && (a = 1); true || (a = 2); a", 0},
+		{"let a = 0; true && (a = 1); false || (a = a + 2); a", 3},
+		{"let f = fn(x) { x > 0 && x < 10 }; [f(5), f(20)]", []any{true, false}},
+	}
+	runVMTests(t, tests)
+}
+
+func TestArithmeticBitwiseExpressions(t *testing.T) {
+	tests := []*vmTestCase{
+		{"7 % 3", 1},
+		{"-7 % 3", 2},
+		{"7 % -3", -2},
+		{"-7.5 % 2", 0.5},
+		{"-7 // 2", -4},
+		{"7.0 // 2", 3.0},
+		{"2 ** 3 ** 2", 512},
+		{"-2 ** 2", -4},
+		{"~2 * 3", -9},
+		{"true ** 2 + true % 2", 2},
+		{"6 & 3 | 8 ^ 1", 11},
+		{"1 << 4 >> 2", 4},
+		{"(-16) >> 2", -4},
+		{"~5", -6},
+		{"~true", -2},
+		{"1 % 0", fmt.Errorf("division by zero")},
+		{"1 // false", fmt.Errorf("division by zero")},
+		{"2 ** -1", fmt.Errorf("negative exponent: -1")},
+		{"1 << -1", fmt.Errorf("negative shift count: -1")},
+		{"1.5 & 1", fmt.Errorf("unknown operator: FLOAT&FLOAT")},
+		{"~1.5", fmt.Errorf("unknown operator: ~FLOAT")},
+	}
+	runVMTests(t, tests)
+}
+
 func TestNumberStringAddArithmetic(t *testing.T) {
 	tests := []*vmTestCase{
 		{"1.0+4", 5.0},
@@ -67,6 +111,26 @@ func TestNumberStringAddArithmetic(t *testing.T) {
 	runVMTests(t, tests)
 }
 
+func TestStringExpressions(t *testing.T) {
+	tests := []*vmTestCase{
+		{`"mon" + "key"`, "monkey"},
+		{`"ab" * 3`, "ababab"},
+		{`3 * "ab"`, "ababab"},
+		{`"ab" * 0`, ""},
+		{`"ab" * -1`, ""},
+		{`"a" == "a"`, true},
+		{`"a" != "a"`, false},
+		{`"a" == "b"`, false},
+		{`"a" < "b"`, true},
+		{`"b" <= "a"`, false},
+		{`"abc" > "abd"`, false},
+		{`"abc" >= "abc"`, true},
+		{`"a" - "b"`, fmt.Errorf("unknown operator: STRING-STRING")},
+		{`"a" == 1`, fmt.Errorf("unknown operator: STRING==INT")},
+	}
+	runVMTests(t, tests)
+}
+
 func TestConditionals(t *testing.T) {
 	tests := []*vmTestCase{
 		{"if (true) { 10 }", 10},
@@ -92,7 +156,7 @@ func TestNull(t *testing.T) {
 		{"null!=null;", false},
 		{"null>null", false},
 		{"null<=null", true},
-		{"null+null", fmt.Errorf("unknown operator: NULL1NULL")},
+		{"null+null", fmt.Errorf("unknown operator: NULL+NULL")},
 		{"!(if(false){5})", true},
 		{"!if(false){5}", true},
 	}
@@ -108,6 +172,379 @@ func TestGlobalLetStatements(t *testing.T) {
 	runVMTests(t, tests)
 }
 
+func TestCallingFunctions(t *testing.T) {
+	tests := []*vmTestCase{
+		{"let fivePlusTen = fn() { 5 + 10; }; fivePlusTen();", 15},
+		{"let one = fn() { 1; }; let two = fn() { 2; }; one() + two()", 3},
+		{"let a = fn() { 1 }; let b = fn() { a() + 1 }; let c = fn() { b() + 1 }; c();", 3},
+		{"fn() { 24 }()", 24},
+		{"let early = fn() { return 99; 100; }; early();", 99},
+		{"let early = fn() { if (true) { return 99; } 100; }; early();", 99},
+		{"let noReturn = fn() { }; noReturn();", nil},
+		{"let noReturn = fn() { }; let again = fn() { noReturn(); }; again();", nil},
+		{"let minusOne = fn() { -1 }; minusOne(); minusOne();", -1},
+		{"let returnsOne = fn() { 1; }; let returnsOneReturner = fn() { returnsOne; }; returnsOneReturner()();", 1},
+		{"fn() { 1 }(1)", fmt.Errorf("wrong number of arguments: want=0, got=1")},
+		{"1()", fmt.Errorf("not a function: INT")},
+	}
+	runVMTests(t, tests)
+}
+
+func TestCallingFunctionsWithBindings(t *testing.T) {
+	tests := []*vmTestCase{
+		{"let one = fn() { let one = 1; one }; one();", 1},
+		{"let oneAndTwo = fn() { let one = 1; let two = 2; one + two; }; oneAndTwo();", 3},
+		{`
+		let oneAndTwo = fn() { let one = 1; let two = 2; one + two; };
+		let threeAndFour = fn() { let three = 3; let four = 4; three + four; };
+		oneAndTwo() + threeAndFour();
+		`, 10},
+		{`
+		let firstFoobar = fn() { let foobar = 50; foobar; };
+		let secondFoobar = fn() { let foobar = 100; foobar; };
+		firstFoobar() + secondFoobar();
+		`, 150},
+		{`
+		let globalSeed = 50;
+		let minusOne = fn() { let num = 1; globalSeed - num; };
+		let minusTwo = fn() { let num = 2; globalSeed - num; };
+		minusOne() + minusTwo();
+		`, 97},
+		{"let a = 1; let f = fn() { let a = 2; a }; f() + a;", 3},
+	}
+	runVMTests(t, tests)
+}
+
+func TestCallingFunctionsWithArguments(t *testing.T) {
+	tests := []*vmTestCase{
+		{"let identity = fn(a) { a; }; identity(4);", 4},
+		{"let sum = fn(a, b) { a + b; }; sum(1, 2);", 3},
+		{"let sum = fn(a, b) { let c = a + b; c; }; sum(1, 2);", 3},
+		{"let sum = fn(a, b) { let c = a + b; c; }; sum(1, 2) + sum(3, 4);", 10},
+		{"let sum = fn(a, b) { let c = a + b; c; }; let outer = fn() { sum(1, 2) + sum(3, 4); }; outer();", 10},
+		{"let max = fn(a, b) { if (a > b) { return a; } b }; max(3, 5) + max(7, 2);", 12},
+		{"fn(a, b) { a + b }(1)", fmt.Errorf("wrong number of arguments: want=2, got=1")},
+	}
+	runVMTests(t, tests)
+}
+
+func TestBlockScopes(t *testing.T) {
+	tests := []*vmTestCase{
+		{"let a = 1; if (true) { let a = 2; a }", 2},
+		{"let a = 1; if (true) { let a = 2; }; a", 1},
+		{"let a = 1; if (false) { 0 } else { let a = 3; a + a }", 6},
+		{"let f = fn(a) { if (a > 0) { let a = 10; a } else { a } }; f(1) + f(-1)", 9},
+		{"let f = fn(a) { if (a > 0) { let b = 10; b } else { let c = 20; c } }; f(1) + f(-1) + f(1)", 40},
+	}
+	runVMTests(t, tests)
+}
+
+func TestClosures(t *testing.T) {
+	tests := []*vmTestCase{
+		{"let newClosure = fn(a) { fn() { a; }; }; let closure = newClosure(99); closure();", 99},
+		{"let newAdder = fn(a, b) { fn(c) { a + b + c }; }; let adder = newAdder(1, 2); adder(8);", 11},
+		{"let newAdder = fn(a, b) { let c = a + b; fn(d) { c + d }; }; let adder = newAdder(1, 2); adder(8);", 11},
+		{`
+		let add = fn(a) { fn(b) { fn(c) { a + b + c } } };
+		add(1)(2)(3);
+		`, 6},
+		{`
+		let newClosure = fn(a, b) {
+			let one = fn() { a; };
+			let two = fn() { b; };
+			fn() { one() + two(); };
+		};
+		let closure = newClosure(9, 90);
+		closure();
+		`, 99},
+		{`
+		let apply = fn(f, x) { f(x) };
+		let base = 10;
+		let makeCallback = fn(offset) { fn(x) { x + offset + base } };
+		apply(makeCallback(5), 1);
+		`, 16},
+		{`
+		let outer = fn(a) { if (a > 0) { let b = a * 2; fn() { a + b } } else { fn() { 0 } } };
+		outer(2)() + outer(-1)();
+		`, 6},
+	}
+	runVMTests(t, tests)
+}
+
+// TestClosuresShareAssignments: closures and their enclosing function see assignments made by one another,
+// the same as the evaluator
+func TestClosuresShareAssignments(t *testing.T) {
+	inputs := []string{
+		"let f = fn(){ let n = 0; let inc = fn(){ n = n + 1 }; inc(); inc(); n }; f()",
+		"let f = fn(){ let n = 0; let inc = fn(){ n += 1 }; inc(); inc(); n }; f()",
+		"let f = fn(){ let n = 0; let inc = fn(){ n = n + 1 }; let get = fn(){ n }; inc(); inc(); get() }; f()",
+		"let f = fn(a){ let set = fn(v){ a = v }; let get = fn(){ fn(){ a } }; set(5); get()() }; f(1)",
+		"let f = fn(){ let n = 1; let g = fn(){ n }; n = 2; g() }; f()",
+		"let f = fn(){ let fs = []; for (let i = 0; i < 3; i++) { fs = append(fs, fn(){ i }) }; fs[0]() }; f()",
+		// locals of a later call do not write to cells left by an earlier one
+		"let f = fn(){ let n = 0; fn(){ n } }; let get = f(); let g = fn(){ let m = 5; m }; g(); get()",
+	}
+	for _, input := range inputs {
+		comp := my_compiler.New()
+		assert.NoError(t, comp.Compile(parse(input)))
+		vm := New(comp.ByteCode())
+		assert.NoError(t, vm.Run(), "input=%s", input)
+		expected := my_evaluator.Eval(parse(input), my_object.NewEnvironment())
+		assert.Equal(t, expected.String(), vm.LastPoppedStackItem().String(), "input=%s", input)
+	}
+}
+
+func TestRecursiveFunctions(t *testing.T) {
+	tests := []*vmTestCase{
+		{`
+		let countDown = fn(x) { if (x == 0) { return 0; } else { countDown(x - 1); } };
+		countDown(1);
+		`, 0},
+		{`
+		let wrapper = fn() {
+			let countDown = fn(x) { if (x == 0) { return 0; } else { countDown(x - 1); } };
+			countDown(1);
+		};
+		wrapper();
+		`, 0},
+		{`
+		let fibonacci = fn(x) {
+			if (x == 0) { return 0; }
+			if (x == 1) { return 1; }
+			fibonacci(x - 1) + fibonacci(x - 2);
+		};
+		fibonacci(15);
+		`, 610},
+	}
+	runVMTests(t, tests)
+}
+
+func TestLoops(t *testing.T) {
+	tests := []*vmTestCase{
+		{"for(;false;){}", nil},
+		{"while(false){}", nil},
+		{"do{1}while(false)", 1},
+		{"for(;true;){1;break;}", nil},
+		{"while(true){ 1; 2; break; }", nil},
+		{"let a = 1;while(a==1){let a = 2; break;};a;", 1},
+		{"for(let a = 1;;){ if (a == 1) { break; } }; 5", 5},
+		{"let f = fn(n) { while(true) { if (n > 0) { return n * 2; } } }; f(4)", 8},
+		{"let f = fn() { while(true) { while(true) { break; }; return 7; } }; f()", 7},
+		{"let f = fn() { do { continue; } while(false) }; f()", nil},
+		{"let f = fn() { for(let i = 0; i < 1; ) { let j = 3; break; } }; f()", nil},
+		{"let f = fn(x) { while(true) { let y = x + 1; return y; } }; f(1) + f(2)", 5},
+		{"let a = 0; while(true) { a = 1 + if (true) { break; } else { 2 }; }; a", 0},
+		{"let a = 0; for(let i = 0; i < 3; i = i + 1) { a = a + [i, if (i == 1) { continue; }][0]; }; a", 2},
+	}
+	runVMTests(t, tests)
+}
+
+func TestArrayLiterals(t *testing.T) {
+	tests := []*vmTestCase{
+		{"[]", []any{}},
+		{"[1, 2, 3]", []any{1, 2, 3}},
+		{"[1 + 2, 3 * 4, 5 + 6]", []any{3, 12, 11}},
+		{"let f = fn(a) { [a, [a]] }; f(1)", []any{1, []any{1}}},
+	}
+	runVMTests(t, tests)
+}
+
+func TestHashLiterals(t *testing.T) {
+	tests := []*vmTestCase{
+		{"{}", map[my_object.HashKey]int64{}},
+		{"{1: 2, 2: 3}", map[my_object.HashKey]int64{
+			(&my_object.Integer{Value: 1}).HashKey(): 2,
+			(&my_object.Integer{Value: 2}).HashKey(): 3,
+		}},
+		{"let two = 'three'; {one: 10 - 9, two: 1 + 1, 'three': 3, true: 4, 5.5: 5}", map[my_object.HashKey]int64{
+			(&my_object.String{Value: "one"}).HashKey():   1,
+			(&my_object.String{Value: "two"}).HashKey():   2,
+			(&my_object.String{Value: "three"}).HashKey(): 3,
+			(&my_object.Boolean{Value: true}).HashKey():   4,
+			(&my_object.Float{Value: 5.5}).HashKey():      5,
+		}},
+		{"{[1]: 1}", fmt.Errorf("key type not hashable: ARRAY")},
+	}
+	runVMTests(t, tests)
+}
+
+func TestIndexExpressions(t *testing.T) {
+	tests := []*vmTestCase{
+		{"[1, 2, 3, 4][0]", 1},
+		{"[1, 2, 3, 4][-1]", 4},
+		{"[[1, 1, 1]][0][0]", 1},
+		{"let i = 1; [1, 2, 3][i + 1]", 3},
+		{"[1, 2, 3, 4][4]", fmt.Errorf("index 4 out of array with length 4")},
+		{"[1, 2, 3, 4][-4]", 1},
+		{"[1, 2, 3, 4][-5]", fmt.Errorf("index -5 out of array with length 4")},
+		{"[5][-1]", 5},
+		{"[1, 2, 3, 4][-4:]", []any{1, 2, 3, 4}},
+		{"[1, 2, 3, 4][-5:]", fmt.Errorf("index -5 out of array with length 4")},
+		{"[1, 2, 3, 4][1:2]", []any{2}},
+		{"[1, 2, 3, 4][1:3]", []any{2, 3}},
+		{"[1, 2, 3, 4][2:5]", []any{3, 4}},
+		{"[1, 2, 3, 4][2:1]", []any{}},
+		{"[1, 2, 3, 4][0:0]", []any{}},
+		{"[1, 2, 3, 4][1:-5]", []any{}},
+		{"[1, 2, 3, 4][:]", []any{1, 2, 3, 4}},
+		{"[1, 2, 3, 4][::]", []any{1, 2, 3, 4}},
+		{"[1, 2, 3, 4][]", fmt.Errorf("array-like indexing with empty expression")},
+		{"[1, 2, 3, 4][:5]", []any{1, 2, 3, 4}},
+		{"[1, 2, 3, 4][::1]", []any{1, 2, 3, 4}},
+		{"[1, 2, 3, 4][::2]", []any{1, 3}},
+		{"[1, 2, 3, 4][::5]", []any{1}},
+		{"[1, 2, 3, 4][::0]", fmt.Errorf("array-like indexing expecting non-zero stride")},
+		{"[1, 2, 3, 4][::-1]", []any{4, 3, 2, 1}},
+		{"[1, 2, 3, 4][::-3]", []any{4, 1}},
+		{"[1, 2, 3, 4][1::-3]", []any{2}},
+		{"[1, 2, 3, 4][true]", fmt.Errorf("array-like indexing expecting INT, but got BOOLEAN")},
+		{"'hello'[1]", "e"},
+		{"'hello'[-1]", "o"},
+		{"'hello'[1:3]", "el"},
+		{"'hello'[::-1]", "olleh"},
+		{"{foo: 5}['foo']", 5},
+		{"{foo: 5}['bar']", nil},
+		{"{}['bar']", nil},
+		{"{5: 5}[5]", 5},
+		{"{5.0: 5}[5.0]", 5},
+		{"{true: 5}[true]", 5},
+		{"{true: 5}[[]]", fmt.Errorf("key type not hashable: ARRAY")},
+		{"1[0]", fmt.Errorf("index operator not supported: INT")},
+	}
+	runVMTests(t, tests)
+}
+
+func TestReassignExpressions(t *testing.T) {
+	tests := []*vmTestCase{
+		{"let a = true; a = 2; a", 2},
+		{"let a = true; a = 2;", 2},
+		{"let a = true; a >= false", true},
+		{"let a = 1; do { let a = 2 } while (false); a = 3; a;", 3},
+		{"let f = fn(x) { x = x * 2; x }; f(4)", 8},
+		{"let a = 1; let f = fn() { a = a + 1 }; f(); f(); a", 3},
+		{"let a = 1; if (true) { a = 2 }; a", 2},
+		{"let a = 1; if (true) { let a = 5; a = 2 }; a", 1},
+		{`
+		let newCounter = fn() { let count = 0; fn() { count = count + 1 } };
+		let counter = newCounter();
+		counter(); counter(); counter();
+		`, 3},
+	}
+	runVMTests(t, tests)
+}
+
+func TestAssignExpressions(t *testing.T) {
+	tests := []*vmTestCase{
+		{"let a = 1; a += 2; a", 3},
+		{"let a = 7; a -= 2; a *= 3; a %= 4", 3},
+		{"let a = 7.0; a /= 2", 3.5},
+		{`let s = "a"; s += "b"; s`, "ab"},
+		{"let i = 0; while (i < 5) { i++; }; i", 5},
+		{"let i = 5; i--; i", 4},
+		// ++ and -- followed by an operand are two signs
+		{"1--1", 2},
+		{"let a = 3; a--1", 4},
+		{"let f = fn(x) { x *= 2; x }; f(4)", 8},
+		{"let a = 1; let f = fn() { a += 1 }; f(); f(); a", 3},
+		{"let a = 1; if (true) { a += 1 }; a", 2},
+		{"let a = [1, 2, 3]; a[0] += 10; a[-1]--; a", []any{11, 2, 2}},
+		{`let h = {"n": 1}; h["n"] *= 5; h["n"]`, 5},
+		{"let a = [1]; let b = a; a[0] += 1; b", []any{2}},
+		{"let n = 0; let a = [0]; let f = fn() { n++; a }; f()[0] += 1; [n, a[0]]", []any{1, 1}},
+		{"let f = fn() { let a = [0, 0]; for (let i = 0; i < 2; i++) { a[i] += i + 1 }; a }; f()", []any{1, 2}},
+		{`let s = "a"; s[0] += "b"`, fmt.Errorf("index assignment not supported: STRING")},
+		{"let a = [1]; a[1] += 1", fmt.Errorf("index 1 out of array with length 1")},
+		{"let a = [1]; a[0] /= 0", fmt.Errorf("division by zero")},
+	}
+	runVMTests(t, tests)
+}
+
+func TestLoopsWithReassignment(t *testing.T) {
+	tests := []*vmTestCase{
+		{"let b = 1; for(let a = 1; a < 3; a = a + 1) { let b = a; return b; }", 1},
+		{"let b = 1; for(let a = 1; a < 3; ) { a = a + 1; b = a; b }", 3},
+		{"let a = 1; while(a < 3) { a = a + 1; a; }", 3},
+		{"let a = 1; do { a = a + 1; a; } while(a < 3)", 3},
+		{"let a = 1; while(a < 3) { a = a + 1; break; }; a;", 2},
+		{"let a = 1; while(a < 3) { a = a + 1; continue; }", nil},
+		{"let a = 1; while(a < 3) { a = a + 1; continue; }; a;", 3},
+		{"let a = 1; do { a = a + 1; continue; } while(a < 3); a", 3},
+		{"let b = 0; for(let a = 0; a < 3; a = a + 1) { if (a == 1) { continue; } b = b + a; }; b", 2},
+		{"let f = fn() { for(let a = 1; a < 3; a = a + 1) { if (a == 2) { return a * 10; } } }; f()", 20},
+		{`
+		let sum = fn(n) {
+			let total = 0;
+			for (let i = 0; i < n; i = i + 1) {
+				for (let j = 0; j < n; j = j + 1) {
+					if (j > i) { break; }
+					total = total + j;
+				}
+			}
+			total
+		};
+		sum(4)
+		`, 10},
+	}
+	runVMTests(t, tests)
+}
+
+func TestBuiltinFunctions(t *testing.T) {
+	tests := []*vmTestCase{
+		{`len("")`, 0},
+		{`len("four")`, 4},
+		{`len("hello world")`, 11},
+		{`len(1)`, fmt.Errorf("argument to len not supported: got INT")},
+		{`len("one", "two")`, fmt.Errorf("wrong number of arguments: got=2, want=1")},
+		{`len([1, 2, 3])`, 3},
+		{`len([])`, 0},
+		{`append([], 1)`, []any{1}},
+		{`append([1, 2], 3)`, []any{1, 2, 3}},
+		{`append(1, 1)`, fmt.Errorf("first argument to `append` must be ARRAY: got=INT")},
+		{`let f = fn(arr) { len(arr) + 1 }; f([1, 2])`, 3},
+		{`let len = fn(x) { 42 }; len([])`, 42},
+	}
+	runVMTests(t, tests)
+}
+
+func TestRegisteredBuiltinFunctions(t *testing.T) {
+	my_object.RegisterBuiltin("double", func(args ...my_object.Object) my_object.Object {
+		return &my_object.Integer{Value: args[0].(*my_object.Integer).Value * 2}
+	})
+	tests := []*vmTestCase{
+		{`double(21)`, 42},
+		{`let f = fn(x) { double(x) }; f(2)`, 4},
+	}
+	runVMTests(t, tests)
+}
+
+func TestRunDecodedByteCode(t *testing.T) {
+	input := `
+	let fib = fn(n) { if (n < 2) { return n; }; fib(n - 1) + fib(n - 2) };
+	let greet = fn(name) { "hello " + name };
+	[fib(10), greet("monkey"), 1.5 * 2]
+	`
+	comp := my_compiler.New()
+	assert.NoError(t, comp.Compile(parse(input)))
+	buf := &bytes.Buffer{}
+	assert.NoError(t, comp.ByteCode().Encode(buf))
+	byteCode, err := my_compiler.Decode(buf)
+	assert.NoError(t, err)
+	vm := New(byteCode)
+	assert.NoError(t, vm.Run())
+	testExpectedObject(t, []any{55, "hello monkey", 3.0}, vm.LastPoppedStackItem(), "input=%s", input)
+}
+
+func TestReturnStatements(t *testing.T) {
+	tests := []*vmTestCase{
+		{"return 10", 10},
+		{"return 2*5; 9", 10},
+		{"9; return 2*5; 9;", 10},
+		{"if(true){if(10>1){return 10;} return 1;}", 10},
+		{"if(true){let a = 1}", nil},
+	}
+	runVMTests(t, tests)
+}
+
 type vmTestCase struct {
 	input    string
 	expected any
@@ -156,6 +593,22 @@ func testExpectedObject(t *testing.T, expected any, actual my_object.Object, msg
 	case nil:
 		_, ok := actual.(*my_object.Null)
 		assert.True(t, ok, msgAndArgs...)
+	case []any:
+		arrObj, ok := actual.(*my_object.Array)
+		assert.True(t, ok, "want array obj, got: %s", actual.Type())
+		assert.EqualValues(t, len(expected), len(arrObj.Elements), msgAndArgs...)
+		for idx, el := range expected {
+			testExpectedObject(t, el, arrObj.Elements[idx], msgAndArgs...)
+		}
+	case map[my_object.HashKey]int64:
+		hashObj, ok := actual.(*my_object.Hash)
+		assert.True(t, ok, "want hash obj, got: %s", actual.Type())
+		assert.EqualValues(t, len(expected), len(hashObj.Pairs), msgAndArgs...)
+		for key, value := range expected {
+			pair, ok := hashObj.Pairs[key]
+			assert.True(t, ok, msgAndArgs...)
+			testExpectedObject(t, int(value), pair.Value, msgAndArgs...)
+		}
 	}
 }
 
@@ -164,3 +617,66 @@ func parse(input string) *my_ast.Program {
 	p := my_parser.New(l)
 	return p.Parse()
 }
+
+func TestRuntimeErrorTraceback(t *testing.T) {
+	input := `let neg = fn(x) {
+	-x
+};
+let apply = fn(f, v) { f(v) };
+apply(neg, null);`
+	comp := my_compiler.New()
+	assert.NoError(t, comp.Compile(parse(input)))
+	err := New(comp.ByteCode()).Run()
+
+	var runtimeErr *RuntimeError
+	if !assert.ErrorAs(t, err, &runtimeErr) {
+		return
+	}
+	assert.Equal(t, "unsupported type for negation: NULL", runtimeErr.Error())
+	assert.Equal(t, "2:2", runtimeErr.Pos.String())
+	assert.Equal(t, 2, runtimeErr.IP)
+	assert.Equal(t, `traceback (most recent call last):
+  main at 5:1 (ip 0021)
+  apply at 4:24 (ip 0004)
+  neg at 2:2 (ip 0002)
+`, runtimeErr.Traceback())
+
+	// deep recursion is collapsed
+	comp = my_compiler.New()
+	assert.NoError(t, comp.Compile(parse("let f = fn(n) { if (n == 0) { -null } else { f(n - 1) } }; f(3)")))
+	err = New(comp.ByteCode()).Run()
+	assert.ErrorAs(t, err, &runtimeErr)
+	assert.Equal(t, `traceback (most recent call last):
+  main at 1:60 (ip 0013)
+  f at 1:46 (ip 0021)
+  [previous frame repeated 2 more times]
+  f at 1:31 (ip 0010)
+`, runtimeErr.Traceback())
+}
+
+func TestUndefinedSlots(t *testing.T) {
+	fn := &my_object.CompiledFunction{
+		Instructions: concat(my_code.Make(my_code.OpGetLocal, 0), my_code.Make(my_code.OpReturnValue)),
+		NumLocals:    1,
+	}
+	tests := []struct {
+		byteCode *my_compiler.ByteCode
+		expected string
+	}{
+		{
+			&my_compiler.ByteCode{Instructions: concat(my_code.Make(my_code.OpGetGlobal, 0), my_code.Make(my_code.OpPop))},
+			"undefined global slot 0",
+		},
+		{
+			&my_compiler.ByteCode{
+				Instructions: concat(my_code.Make(my_code.OpClosure, 0, 0), my_code.Make(my_code.OpCall, 0), my_code.Make(my_code.OpPop)),
+				Constants:    []my_object.Object{fn},
+			},
+			"undefined local slot 0",
+		},
+	}
+	for _, tt := range tests {
+		err := New(tt.byteCode).Run()
+		assert.EqualError(t, err, tt.expected)
+	}
+}
@@ -0,0 +1,64 @@
+package my_vm
+
+import (
+	"fmt"
+	"monkey/my_object"
+)
+
+// handler: an exception handler set up by OpSetupTry, where to resume once an exception is raised
+type handler struct {
+	framesIndex int // framesIndex: frames above are unwound
+	ip          int // ip: position of the handler in the function of the frame
+	sp          int // sp: stack pointer when the handler is set up, restored before pushing the exception
+}
+
+// exception: raised by OpThrow with any value, the message tells it is uncaught once it leaves Run
+type exception struct {
+	value my_object.Object
+}
+
+func (e *exception) Error() string {
+	return my_object.UncaughtMessage(e.value)
+}
+
+func (vm *VM) setupTry(ip int) {
+	vm.handlers = append(vm.handlers, handler{framesIndex: vm.framesIndex, ip: ip, sp: vm.sp})
+}
+
+func (vm *VM) popTry() error {
+	if len(vm.handlers) == 0 || vm.handlers[len(vm.handlers)-1].framesIndex != vm.framesIndex {
+		return fmt.Errorf("no exception handler to pop")
+	}
+	vm.handlers = vm.handlers[:len(vm.handlers)-1]
+	return nil
+}
+
+// dropHandlers: forget handlers of frames returned from
+func (vm *VM) dropHandlers() {
+	for len(vm.handlers) > 0 && vm.handlers[len(vm.handlers)-1].framesIndex > vm.framesIndex {
+		vm.handlers = vm.handlers[:len(vm.handlers)-1]
+	}
+}
+
+// handle: resume at the innermost handler with the exception err on the stack, unwinding frames in between;
+// false if there is no handler or err stops the program for good like exceeding limits
+func (vm *VM) handle(err error) bool {
+	if len(vm.handlers) == 0 || vm.meter.Err() != nil {
+		return false
+	}
+	var value my_object.Object
+	if thrown, ok := err.(*exception); ok {
+		value = thrown.value
+	} else {
+		value = my_object.NewErrorValue(err.Error(), vm.runtimeError(err).Pos)
+	}
+	h := vm.handlers[len(vm.handlers)-1]
+	vm.handlers = vm.handlers[:len(vm.handlers)-1]
+	for vm.framesIndex > h.framesIndex {
+		vm.popFrame()
+		vm.meter.Leave()
+	}
+	vm.sp = h.sp
+	vm.currentFrame().ip = h.ip - 1 // ip has ++ after each loop
+	return vm.push(value) == nil
+}